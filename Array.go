@@ -3,93 +3,167 @@ package main
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 )
 
 func NewArray(elements []*Value) *Value {
-	return &Value{
-		Array: &Array{
-			Elements: elements,
-		},
-	}
+	refs := int32(1)
+	return &Value{Kind: KIND_ARRAY, ref: &Array{Elements: elements, refs: &refs}}
 }
 
+// Copy returns an array that shares the elements until one of the arrays is changed.
 func (a *Array) Copy() *Value {
-	return NewArray(a.Elements).SetContext(a.Context).SetPos(a.PosStart(), a.PosEnd())
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.refs == nil {
+		refs := int32(1)
+		a.refs = &refs
+	}
+	atomic.AddInt32(a.refs, 1)
+	return &Value{Kind: KIND_ARRAY, ref: &Array{Elements: a.Elements, Frozen: a.Frozen, refs: a.refs}}
+}
+
+// DeepCopy returns an array with its own elements, nested arrays are copied recursively and share nothing.
+// The copy and its nested arrays are not frozen, they can be changed like any new array.
+func (a *Array) DeepCopy() *Value {
+	elements := make([]*Value, len(a.Elements))
+	for idx, element := range a.Elements {
+		elements[idx] = element.Copy()
+		if element.Kind == KIND_ARRAY {
+			elements[idx] = element.Array().DeepCopy()
+		}
+	}
+	return NewArray(elements)
+}
+
+// Append adds value to the end of the array.
+func (a *Array) Append(value *Value) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.own()
+	a.Elements = append(a.Elements, value.Share())
+}
+
+// Remove removes the element at idx and returns it, ok is false if idx is out of bounds.
+func (a *Array) Remove(idx int) (element *Value, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if idx < 0 || idx >= len(a.Elements) {
+		return nil, false
+	}
+	element = a.Elements[idx]
+	a.own()
+	a.Elements = append(a.Elements[:idx], a.Elements[idx+1:]...)
+	return element, true
 }
 
-func (a *Array) PosStart() *Position {
-	return a.PositionStart
+// own gives the array its own elements before it is changed, if they are still shared with a copy. The caller holds
+// the lock of the array.
+func (a *Array) own() {
+	if a.refs == nil || atomic.LoadInt32(a.refs) <= 1 {
+		return
+	}
+	elements := ownElements(a.Elements, len(a.Elements))
+	atomic.AddInt32(a.refs, -1)
+	refs := int32(1)
+	a.Elements, a.refs = elements, &refs
 }
 
-func (a *Array) PosEnd() *Position {
-	return a.PositionEnd
+// ownElements copies the elements into a new slice with the given capacity, nested arrays are copied on write.
+func ownElements(elements []*Value, capacity int) []*Value {
+	owned := make([]*Value, len(elements), capacity)
+	for idx, element := range elements {
+		owned[idx] = element.Share()
+	}
+	return owned
+}
+
+// CheckMutable returns an error if the array is frozen and must not be changed by the operation.
+func (a *Array) CheckMutable(operation string, posStart *Position, posEnd *Position, context *Context) *RuntimeError {
+	if a.Frozen {
+		return NewRTError(posStart, posEnd, fmt.Sprintf("Cannot %s a frozen array", operation), context)
+	}
+	return nil
 }
 
 // Add element to Array
 func (a *Array) AddedTo(other *Value) (*Value, *RuntimeError) {
-	newArray := a.Copy()
-	newArray.Array.Elements = append(newArray.Array.Elements, other)
+	elements := append(ownElements(a.Elements, len(a.Elements)+1), other.Share())
+	newArray := NewArray(elements)
+	newArray.Array().Frozen = a.Frozen
 	return newArray, nil
 }
 
-// Remove element by index from Array
+// Remove element by index from Array, errors are located at the operation by the caller.
 func (a *Array) SubtractedBy(other *Value) (*Value, *RuntimeError) {
-	newArray := a.Copy()
-	if other.Number.ValueField.(int) < 0 || other.Number.ValueField.(int) >= len(newArray.Array.Elements) {
-		return nil, NewRTError(other.Number.PosStart(), other.Number.PosEnd(), fmt.Sprintf("Element at index %v could not be removed from array, index is out of bounds", other.Value()), a.Context)
+	index := other.Number()
+	if !index.IsInt() || index.Int < 0 || index.Int >= len(a.Elements) {
+		return nil, NewRTError(nil, nil, fmt.Sprintf("Element at index %v could not be removed from array, index is out of bounds", other.Value()), nil)
 	}
-	newArray.Array.Elements = append(newArray.Array.Elements[:other.Number.ValueField.(int)], newArray.Array.Elements[other.Number.ValueField.(int)+1:]...)
+	elements := ownElements(a.Elements[:index.Int], len(a.Elements)-1)
+	elements = append(elements, ownElements(a.Elements[index.Int+1:], 0)...)
+	newArray := NewArray(elements)
+	newArray.Array().Frozen = a.Frozen
 	return newArray, nil
 }
 
 // Extend Array with another Array
 func (a *Array) MultipliedBy(other *Value) (*Value, *RuntimeError) {
-	newArray := a.Copy()
-	newArray.Array.Elements = append(newArray.Array.Elements, other.Array.Elements...)
+	elements := ownElements(a.Elements, len(a.Elements)+len(other.Array().Elements))
+	elements = append(elements, ownElements(other.Array().Elements, len(other.Array().Elements))...)
+	newArray := NewArray(elements)
+	newArray.Array().Frozen = a.Frozen
 	return newArray, nil
 }
 
-// Retrieve element by index from Array
+// Retrieve element by index from Array, errors are located at the index by the caller.
 func (a *Array) GetIndex(index *Number) (*Value, *RuntimeError) {
-	if index.ValueField.(int) < 0 || index.ValueField.(int) >= len(a.Elements) {
-		return nil, NewRTError(index.PosStart(), index.PosEnd(), fmt.Sprintf("Element at index %v could not be retrieved from array, index is out of bounds", index.ValueField.(int)), a.Context)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if index.Int < 0 || index.Int >= len(a.Elements) {
+		return nil, NewRTError(nil, nil, fmt.Sprintf("Element at index %v could not be retrieved from array, index is out of bounds", index.Int), nil)
 	}
 
-	return a.Elements[index.ValueField.(int)], nil
+	// nested arrays can be changed through the index, so they must not be shared with a copy of this array
+	if a.Elements[index.Int].Kind == KIND_ARRAY {
+		a.own()
+	}
+	return a.Elements[index.Int], nil
 }
 
-// Error for illegal operation
+// IllegalOperation returns an error without a position, it is located at the operation by the caller.
 func (a *Array) IllegalOperation(other *Array) *RuntimeError {
-	if other == nil {
-		other = a
-	}
-	return NewRTError(a.PosStart(), a.PosEnd(), "Illegal operation", a.Context)
+	return NewRTError(nil, nil, "Illegal operation", nil)
 }
 
 // Length returns the length of the byte array.
 func (a *Array) Length() *Value {
-	value := NewNumber(float64(len(a.Elements)))
-	value.SetContext(a.Context)
-	return value
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return NewInt(len(a.Elements))
 }
 
 // String representation of Array
 func (a *Array) String() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	elementStrings := make([]string, len(a.Elements))
 	for i, element := range a.Elements {
-		switch {
-		case element.Number != nil:
-			elementStrings[i] = fmt.Sprintf("%v", element.Number.ValueField)
-		case element.String != nil:
-			elementStrings[i] = fmt.Sprintf("%q", element.String.ValueField)
-		case element.Array != nil:
-			elementStrings[i] = element.Array.String() // Recursively call String for nested arrays
-		case element.Function != nil:
-			elementStrings[i] = element.Function.String()
-		case element.BuildInFunction != nil:
-			elementStrings[i] = element.BuildInFunction.Base.Name
-		case element.Boolean != nil:
-			elementStrings[i] = element.Boolean.String()
+		switch element.Kind {
+		case KIND_NUMBER:
+			elementStrings[i] = fmt.Sprintf("%v", element.Number().Value())
+		case KIND_STRING:
+			elementStrings[i] = fmt.Sprintf("%q", element.String().ValueField)
+		case KIND_ARRAY:
+			elementStrings[i] = element.Array().String() // Recursively call String for nested arrays
+		case KIND_FUNCTION:
+			elementStrings[i] = element.Function().String()
+		case KIND_BUILD_IN_FUNCTION:
+			elementStrings[i] = element.BuildInFunction().Base.Name
+		case KIND_BOOLEAN:
+			elementStrings[i] = element.Boolean().String()
+		case KIND_TASK, KIND_CHANNEL:
+			elementStrings[i] = fmt.Sprintf("<%s>", element.Type())
 		default:
 			elementStrings[i] = "<null>"
 		}
@@ -102,5 +176,5 @@ func NewVariadicArray(elements []*Value) *Value {
 	for e := range elements {
 		array = append(array, elements[e])
 	}
-	return &Value{VariadicArray: &VariadicArray{array, array[0].GetPosStart(), array[len(array)-1].GetPosEnd(), array[0].GetContext()}}
+	return &Value{Kind: KIND_VARIADIC_ARRAY, ref: &VariadicArray{array}}
 }
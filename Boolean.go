@@ -1,19 +1,20 @@
 package main
 
+// trueBoolean and falseBoolean are shared by all boolean values, booleans can not be changed.
+var (
+	trueBoolean  = &Boolean{Binary: One}
+	falseBoolean = &Boolean{Binary: Zero}
+)
+
 func NewBoolean(value Binary) *Value {
-	return &Value{Boolean: &Boolean{Binary: value}}
+	if value == One {
+		return &Value{Kind: KIND_BOOLEAN, ref: trueBoolean}
+	}
+	return &Value{Kind: KIND_BOOLEAN, ref: falseBoolean}
 }
 
 func (b *Boolean) Copy() *Value {
-	return NewBoolean(b.Binary).SetContext(b.Context).SetPos(b.PositionStart, b.PositionEnd)
-}
-
-func (b *Boolean) PosStart() *Position {
-	return b.PositionStart
-}
-
-func (b *Boolean) PosEnd() *Position {
-	return b.PositionEnd
+	return NewBoolean(b.Binary)
 }
 
 func (b *Boolean) IsTrue() bool {
@@ -30,35 +31,19 @@ func (b *Boolean) String() string {
 
 func (b *Boolean) GetComparisonEq(other *Boolean) (*Value, *RuntimeError) {
 	if other != nil {
-		var value *Value
-		if b.Binary == other.Binary {
-			value = NewBoolean(One)
-		} else {
-			value = NewBoolean(Zero)
-		}
-		value.SetContext(b.Context)
-		return value, nil
+		return NewBoolean(ConvertBoolToInt(b.Binary == other.Binary)), nil
 	}
 	return nil, b.IllegalOperation(other)
 }
 
 func (b *Boolean) GetComparisonNe(other *Boolean) (*Value, *RuntimeError) {
 	if other != nil {
-		var value *Value
-		if b.Binary != other.Binary {
-			value = NewBoolean(One)
-		} else {
-			value = NewBoolean(Zero)
-		}
-		value.SetContext(b.Context)
-		return value, nil
+		return NewBoolean(ConvertBoolToInt(b.Binary != other.Binary)), nil
 	}
 	return nil, b.IllegalOperation(other)
 }
 
+// IllegalOperation returns an error without a position, it is located at the operation by the caller.
 func (b *Boolean) IllegalOperation(other interface{}) *RuntimeError {
-	if other == nil {
-		other = b
-	}
-	return NewRTError(b.PosStart(), b.PosEnd(), "Illegal operation", b.Context)
+	return NewRTError(nil, nil, "Illegal operation", nil)
 }
@@ -4,7 +4,7 @@ import "strconv"
 
 // NewByteArray is the constructor for ByteArray
 func NewByteArray(value []byte) *Value {
-	return &Value{ByteArray: &ByteArray{ValueField: value}}
+	return &Value{Kind: KIND_BYTE_ARRAY, ref: &ByteArray{ValueField: value}}
 }
 
 func (b *ByteArray) Copy() *Value {
@@ -40,15 +40,15 @@ func (b *ByteArray) AddedTo(other *ByteArray) (*Value, *RuntimeError) {
 }
 
 func (b *ByteArray) MultipliedBy(other *Number) (*Value, *RuntimeError) {
-	if ValueField, ok := other.ValueField.(int); ok {
-		if ValueField <= 0 {
+	if other.IsInt() {
+		if other.Int <= 0 {
 			value := NewByteArray([]byte{})
 			value.SetContext(b.Context)
 			return value, nil
 		}
 
-		result := make([]byte, 0, len(b.ValueField)*ValueField)
-		for i := 0; i < ValueField; i++ {
+		result := make([]byte, 0, len(b.ValueField)*other.Int)
+		for i := 0; i < other.Int; i++ {
 			result = append(result, b.ValueField...)
 		}
 		value := NewByteArray(result)
@@ -59,9 +59,9 @@ func (b *ByteArray) MultipliedBy(other *Number) (*Value, *RuntimeError) {
 }
 
 func (b *ByteArray) GetComparisonEq(other *Value) (*Value, *RuntimeError) {
-	if other != nil && other.ByteArray != nil {
+	if other != nil && other.ByteArray() != nil {
 		bVal := b.ValueField
-		otherVal := other.ByteArray.ValueField
+		otherVal := other.ByteArray().ValueField
 		value := NewBoolean(ConvertBoolToInt(string(bVal) == string(otherVal)))
 		value.SetContext(b.Context)
 		return value, nil
@@ -71,25 +71,24 @@ func (b *ByteArray) GetComparisonEq(other *Value) (*Value, *RuntimeError) {
 
 // GetByte retrieves the byte at a specified index.
 func (b *ByteArray) GetByte(index *Number) (*Value, *RuntimeError) {
-	if idx, ok := index.ValueField.(int); ok {
+	if index.IsInt() {
+		idx := index.Int
 		if idx < 0 || idx >= len(b.ValueField) {
 			return nil, NewRTError(b.PosStart(), b.PosEnd(), "Index out of bounds", b.Context)
 		}
-		value := NewNumber(float64(b.ValueField[idx]))
-		value.SetContext(b.Context)
-		return value, nil
+		return NewInt(int(b.ValueField[idx])), nil
 	}
 	return nil, b.IllegalOperation(index)
 }
 
 // Slice retrieves a sub-array of the byte array.
 func (b *ByteArray) Slice(startIndex, endIndex *Number) (*Value, *RuntimeError) {
-	if start, ok := startIndex.ValueField.(int); ok {
-		if end, ok := endIndex.ValueField.(int); ok {
-			if start < 0 || end > len(b.ValueField) || start >= end {
+	if start := startIndex.Int; startIndex.IsInt() {
+		if end := endIndex.Int; endIndex.IsInt() {
+			if start < 0 || end > len(b.ValueField) || start > end {
 				return nil, NewRTError(b.PosStart(), b.PosEnd(), "Invalid slice indices", b.Context)
 			}
-			value := NewByteArray(b.ValueField[start:end])
+			value := NewByteArray(append([]byte{}, b.ValueField[start:end]...))
 			value.SetContext(b.Context)
 			return value, nil
 		}
@@ -99,7 +98,7 @@ func (b *ByteArray) Slice(startIndex, endIndex *Number) (*Value, *RuntimeError)
 
 // Length returns the length of the byte array.
 func (b *ByteArray) Length() *Value {
-	value := NewNumber(float64(len(b.ValueField)))
+	value := NewNumber(len(b.ValueField))
 	value.SetContext(b.Context)
 	return value
 }
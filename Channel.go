@@ -0,0 +1,63 @@
+package main
+
+import "fmt"
+
+func NewChannel(size int) *Value {
+	return &Value{Kind: KIND_CHANNEL, ref: &Channel{state: &channelState{ch: make(chan *Value, size)}}}
+}
+
+// Copy creates a copy of the channel handle, every copy sends to and receives from the same queue.
+func (c *Channel) Copy() *Value {
+	return (&Value{Kind: KIND_CHANNEL, ref: &Channel{state: c.state}}).SetContext(c.Context).SetPos(c.PositionStart, c.PositionEnd)
+}
+
+func (c *Channel) PosStart() *Position {
+	return c.PositionStart
+}
+
+func (c *Channel) PosEnd() *Position {
+	return c.PositionEnd
+}
+
+func (c *Channel) String() string {
+	return fmt.Sprintf("<channel %d/%d>", len(c.state.ch), cap(c.state.ch))
+}
+
+// Send blocks until the value was handed over to the channel.
+func (c *Channel) Send(value *Value) (err *RuntimeError) {
+	c.state.mu.Lock()
+	closed := c.state.closed
+	c.state.mu.Unlock()
+	if closed {
+		return NewRTError(c.PosStart(), c.PosEnd(), "Send on closed channel", c.Context)
+	}
+
+	// the channel may still be closed by another task while this one is blocked
+	defer func() {
+		if recover() != nil {
+			err = NewRTError(c.PosStart(), c.PosEnd(), "Send on closed channel", c.Context)
+		}
+	}()
+	c.state.ch <- value
+	return nil
+}
+
+// Receive blocks until a value is available, a closed and drained channel yields null.
+func (c *Channel) Receive() *Value {
+	value, ok := <-c.state.ch
+	if !ok {
+		return NewNull()
+	}
+	return value
+}
+
+func (c *Channel) Close() *RuntimeError {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if c.state.closed {
+		return NewRTError(c.PosStart(), c.PosEnd(), "Close of closed channel", c.Context)
+	}
+	c.state.closed = true
+	close(c.state.ch)
+	return nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	RoundHalfEven RoundingMode = "halfEven"
+	RoundHalfUp   RoundingMode = "halfUp"
+	RoundHalfDown RoundingMode = "halfDown"
+	RoundUp       RoundingMode = "up"
+	RoundDown     RoundingMode = "down"
+	RoundCeiling  RoundingMode = "ceiling"
+	RoundFloor    RoundingMode = "floor"
+)
+
+var RoundingModes = []RoundingMode{RoundHalfEven, RoundHalfUp, RoundHalfDown, RoundUp, RoundDown, RoundCeiling, RoundFloor}
+
+// decimalContext is used for every decimal division, quotients have 16 decimal places and are rounded half to even.
+var decimalContext = &DecimalContext{scale: 16, rounding: RoundHalfEven}
+
+func (c *DecimalContext) Get() (int, RoundingMode) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.scale, c.rounding
+}
+
+func (c *DecimalContext) SetScale(scale int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scale = scale
+}
+
+func (c *DecimalContext) SetRounding(rounding RoundingMode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rounding = rounding
+}
+
+func NewDecimal(unscaled *big.Int, scale int) *Decimal {
+	return &Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+// ParseDecimal parses a decimal like "-12.340", the number of digits after the point becomes the scale.
+func ParseDecimal(text string) (*Decimal, bool) {
+	text = strings.TrimSpace(text)
+	digits := strings.TrimLeft(text, "+-")
+	if len(text)-len(digits) > 1 {
+		return nil, false
+	}
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" {
+		return nil, false
+	}
+	for _, char := range intPart + fracPart {
+		if !isDigit(char) {
+			return nil, false
+		}
+	}
+
+	unscaled, _ := new(big.Int).SetString("0"+intPart+fracPart, 10)
+	if strings.HasPrefix(text, "-") {
+		unscaled.Neg(unscaled)
+	}
+	return NewDecimal(unscaled, len(fracPart)), true
+}
+
+// toDecimal converts any number to a decimal. Floats are converted from their shortest representation, so 0.1 becomes
+// exactly 0.1 instead of the binary approximation.
+func toDecimal(value interface{}) (*Decimal, bool) {
+	switch v := value.(type) {
+	case *Decimal:
+		return v, true
+	case int:
+		return NewDecimal(big.NewInt(int64(v)), 0), true
+	case *big.Int:
+		return NewDecimal(v, 0), true
+	case float64:
+		return ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return nil, false
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+// roundQuotient divides num by den and rounds the result to an integer with the given rounding mode.
+func roundQuotient(num, den *big.Int, rounding RoundingMode) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	sign := num.Sign() * den.Sign()
+	// compares the remainder to half of the divisor
+	half := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).CmpAbs(den)
+
+	away := false
+	switch rounding {
+	case RoundUp:
+		away = true
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	case RoundHalfUp:
+		away = half >= 0
+	case RoundHalfDown:
+		away = half > 0
+	case RoundHalfEven:
+		away = half > 0 || (half == 0 && quotient.Bit(0) == 1)
+	}
+	if away {
+		quotient.Add(quotient, big.NewInt(int64(sign)))
+	}
+	return quotient
+}
+
+// Rescale returns the decimal with the given number of decimal places, dropped digits are rounded.
+func (d *Decimal) Rescale(scale int, rounding RoundingMode) *Decimal {
+	if scale >= d.Scale {
+		return NewDecimal(new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale)), scale)
+	}
+	return NewDecimal(roundQuotient(d.Unscaled, pow10(d.Scale-scale), rounding), scale)
+}
+
+// trim removes trailing zeros, but keeps at least minScale decimal places.
+func (d *Decimal) trim(minScale int) *Decimal {
+	unscaled, scale := new(big.Int).Set(d.Unscaled), d.Scale
+	ten, remainder := big.NewInt(10), new(big.Int)
+	for scale > minScale {
+		quotient, _ := new(big.Int).QuoRem(unscaled, ten, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+		unscaled, scale = quotient, scale-1
+	}
+	return NewDecimal(unscaled, scale)
+}
+
+// align returns the unscaled values of both decimals with the larger scale of the two.
+func (d *Decimal) align(other *Decimal) (*big.Int, *big.Int, int) {
+	scale := max(d.Scale, other.Scale)
+	return d.Rescale(scale, RoundDown).Unscaled, other.Rescale(scale, RoundDown).Unscaled, scale
+}
+
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	left, right, scale := d.align(other)
+	return NewDecimal(left.Add(left, right), scale)
+}
+
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	left, right, scale := d.align(other)
+	return NewDecimal(left.Sub(left, right), scale)
+}
+
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return NewDecimal(new(big.Int).Mul(d.Unscaled, other.Unscaled), d.Scale+other.Scale)
+}
+
+// Quo divides by another decimal, the quotient is rounded to the places of the decimal context.
+// Quotients that are exact with fewer places are not padded beyond the scale of the operands.
+func (d *Decimal) Quo(other *Decimal) *Decimal {
+	scale, rounding := decimalContext.Get()
+	num := new(big.Int).Mul(d.Unscaled, pow10(other.Scale+scale))
+	den := new(big.Int).Mul(other.Unscaled, pow10(d.Scale))
+	return NewDecimal(roundQuotient(num, den, rounding), scale).trim(min(scale, max(d.Scale, other.Scale)))
+}
+
+// Pow raises the decimal to an integer power, negative exponents are divided with the decimal context.
+func (d *Decimal) Pow(exponent int) *Decimal {
+	if exponent < 0 {
+		return NewDecimal(big.NewInt(1), 0).Quo(d.Pow(-exponent))
+	}
+	return NewDecimal(new(big.Int).Exp(d.Unscaled, big.NewInt(int64(exponent)), nil), d.Scale*exponent)
+}
+
+func (d *Decimal) Cmp(other *Decimal) int {
+	left, right, _ := d.align(other)
+	return left.Cmp(right)
+}
+
+func (d *Decimal) Float64() float64 {
+	value, _ := strconv.ParseFloat(d.String(), 64)
+	return value
+}
+
+// String formats the decimal with all of its decimal places, 10.50 stays 10.50.
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	if d.Scale > 0 {
+		if len(digits) <= d.Scale {
+			digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.Scale] + "." + digits[len(digits)-d.Scale:]
+	}
+	if d.Unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func (b *BuildInFunction) ExecuteDecimal(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	var decimal *Decimal
+	ok := false
+	if value.String() != nil {
+		decimal, ok = ParseDecimal(value.String().ValueField)
+	} else if value.Number() != nil {
+		decimal, ok = toDecimal(value.Number().Value())
+	}
+	if !ok {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not convert %v to a decimal", value.Value()), execCtx))
+	}
+	return res.Success(NewNumber(decimal))
+}
+
+// ExecuteRound rounds a number to the given number of decimal places with the rounding mode of the decimal context.
+// Decimals stay decimals, floats are rounded by their decimal representation.
+func (b *BuildInFunction) ExecuteRound(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	places, _, _ := execCtx.SymbolTable.Get("places")
+
+	if value.Number() == nil || places.Number() == nil || !places.Number().IsInt() || places.Number().Int < 0 {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Expected a number and a positive int as the number of places", execCtx))
+	}
+	decimal, ok := toDecimal(value.Number().Value())
+	if !ok {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not round %v", value.Value()), execCtx))
+	}
+
+	_, rounding := decimalContext.Get()
+	rounded := decimal.Rescale(places.Number().Int, rounding)
+	switch value.Number().Value().(type) {
+	case *Decimal:
+		return res.Success(NewNumber(rounded))
+	case float64:
+		return res.Success(NewNumber(rounded.Float64()))
+	}
+	return res.Success(NewNumber(normalizeInt(rounded.Rescale(0, RoundDown).Unscaled)))
+}
+
+func (b *BuildInFunction) ExecuteSetDecimalScale(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	places, _, _ := execCtx.SymbolTable.Get("places")
+
+	if places.Number() == nil || !places.Number().IsInt() || places.Number().Int < 0 {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Number of decimal places must be a positive int", execCtx))
+	}
+	decimalContext.SetScale(places.Number().Int)
+	return res.Success(NewNull())
+}
+
+func (b *BuildInFunction) ExecuteSetDecimalRounding(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	mode, _, _ := execCtx.SymbolTable.Get("mode")
+
+	if mode.String() != nil {
+		for _, rounding := range RoundingModes {
+			if string(rounding) == mode.String().ValueField {
+				decimalContext.SetRounding(rounding)
+				return res.Success(NewNull())
+			}
+		}
+	}
+
+	names := make([]string, len(RoundingModes))
+	for idx, rounding := range RoundingModes {
+		names[idx] = fmt.Sprintf("'%s'", rounding)
+	}
+	return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Unknown rounding mode %v, expected one of %s", mode.Value(), strings.Join(names, ", ")), execCtx))
+}
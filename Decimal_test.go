@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestDecimals(t *testing.T) {
+	scale, rounding := decimalContext.Get()
+	t.Cleanup(func() {
+		decimalContext.SetScale(scale)
+		decimalContext.SetRounding(rounding)
+	})
+
+	runProgramTests(t, []programTest{
+		{
+			name:   "addition is exact",
+			source: "var out = [0.1d + 0.2d == 0.3d, str(0.1d + 0.2d)]",
+			want:   `[true, "0.3"]`,
+		},
+		{
+			name:   "the scale of the result follows the operands",
+			source: `var out = [str(1.10d * 3), str(decimal("12.345") - 2.345d), str(1.5d + 1)]`,
+			want:   `["3.30", "10.000", "2.5"]`,
+		},
+		{
+			name:   "floats are converted from their shortest representation",
+			source: "var out = str(decimal(0.1))",
+			want:   "0.1",
+		},
+		{
+			name:   "round uses the rounding mode",
+			source: "setDecimalRounding(\"halfEven\")\nvar out = [str(round(2.5d, 0)), str(round(1.005d, 2))]",
+			want:   `["2", "1.00"]`,
+		},
+		{
+			name:   "division uses the scale and rounding mode",
+			source: "setDecimalScale(2)\nsetDecimalRounding(\"up\")\nvar out = [str(1d / 3), str(round(2.5d, 0))]",
+			want:   `["0.34", "3"]`,
+		},
+		{
+			name:    "unknown rounding modes are rejected",
+			source:  `setDecimalRounding("nope")`,
+			wantErr: "Unknown rounding mode nope, expected one of 'halfEven'",
+		},
+		{
+			name:    "decimal rejects text that is not a number",
+			source:  `decimal("1.2.3")`,
+			wantErr: "Can not convert 1.2.3 to a decimal",
+		},
+	})
+}
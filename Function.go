@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -9,18 +10,74 @@ import (
 // NewFunction creates a new Function instance.
 func NewFunction(name *string, bodyNode *Node, argNames []string, Flag bool) *Value {
 	baseFunc := NewBaseFunction(name)
-	return &Value{Function: &Function{bodyNode, argNames, baseFunc, Flag}}
+	return &Value{Kind: KIND_FUNCTION, ref: &Function{bodyNode, argNames, baseFunc, Flag, nil, nil, nil}}
 }
 
-// Execute executes the function with the given arguments.
+// maxCallDepth is the number of nested calls after which a call fails, deeper recursion would overflow the Go stack.
+var maxCallDepth = 10000
+
+// Execute executes the function with the given arguments. Calls in tail position run one after another in a loop
+// here, they take the place of the call of f in the traceback and do not count towards the call depth.
 func (f *Function) Execute(args []*Value) *RTResult {
 	res := NewRTResult()
-	interpreter := NewInterpreter()
+	var replaced []*Function // functions that returned with a tail call, their return type is checked at the end
+
+	for {
+		res.Register(f.Base.CheckArgs(f.ArgNames, args, false))
+		if res.ShouldReturn() {
+			return res
+		}
+		if len(replaced) > 0 {
+			// the call is entered from where the first function was called, the step runs on a copy of the callee
+			// so that the function value itself keeps its positions and context for its other callers
+			first := replaced[0].Base
+			base := *f.Base
+			base.Context, base.PositionStart, base.PositionEnd = first.Context, first.PositionStart, first.PositionEnd
+			step := *f
+			step.Base = &base
+			f = &step
+		}
+
+		value := res.Register(f.run(args))
+		if call := res.TailCall; call != nil {
+			replaced = append(replaced, f)
+			if call.Callee.Function() != nil {
+				f, args = call.Callee.Function(), call.Args
+				continue
+			}
+			// build-in functions do not recurse into the interpreter, they are called as usual
+			interpreter := NewInterpreter()
+			value = res.Register(interpreter.callValue(call.Callee, call.Args))
+		}
+		if res.ShouldReturn() {
+			return res
+		}
+		for idx := len(replaced) - 1; idx >= 0; idx-- {
+			if err := replaced[idx].checkReturnType(value); err != nil {
+				return res.Failure(err)
+			}
+		}
+		return res.Success(value)
+	}
+}
+
+// run executes the body of the function once the arguments are checked, a tail call is returned to Execute.
+func (f *Function) run(args []*Value) *RTResult {
+	res := NewRTResult()
 	execCtx := f.Base.GenerateNewContext()
+	if execCtx.Depth > maxCallDepth {
+		return res.Failure(NewRTError(
+			f.PosStart(), f.PosEnd(),
+			fmt.Sprintf("Maximum call depth of %d exceeded", maxCallDepth),
+			f.Base.Context,
+		))
+	}
 
-	res.Register(f.Base.CheckAndPopulateArgs(f.ArgNames, args, execCtx, false))
-	if res.ShouldReturn() {
-		return res
+	// arguments are passed by value, changes inside the function do not leak to the caller,
+	// the parameters take the first slots of the frame
+	execCtx.Frame = NewFrame(f.Locals, f.Closure)
+	for idx, arg := range args {
+		execCtx.Frame.Slots[idx] = arg.Share().SetContext(execCtx)
 	}
 
 	if f.BodyNode == nil {
@@ -31,7 +88,15 @@ func (f *Function) Execute(args []*Value) *RTResult {
 		))
 	}
 
-	value := res.Register(interpreter.visit(*f.BodyNode, execCtx))
+	execCtx.Deferred = &[]DeferredCall{}
+	value := res.Register(runCompiled(*f.BodyNode, f.Compiled, execCtx))
+
+	// deferred expressions run on every exit, a runtime error of the body takes precedence over their errors
+	deferRes := f.runDeferred(execCtx)
+	if res.Error == nil && deferRes.Error != nil {
+		return res.Failure(deferRes.Error)
+	}
+
 	if res.ShouldReturn() && res.FuncReturnValue == nil {
 		return res
 	}
@@ -52,12 +117,56 @@ func (f *Function) Execute(args []*Value) *RTResult {
 			ReturnValue = NewNull()
 		}
 	}
+
+	if err := f.checkReturnType(ReturnValue); err != nil {
+		return res.Failure(err)
+	}
 	return res.Success(ReturnValue)
 }
 
+// checkReturnType reports a value that does not match the declared return type of the function.
+func (f *Function) checkReturnType(value *Value) *RuntimeError {
+	if f.Base.ReturnType != nil && !f.Base.ReturnType.Matches(value) {
+		return NewRTError(
+			f.PosStart(), f.PosEnd(),
+			fmt.Sprintf("Expected '%s' to return %s, but got %s", f.Base.Name, f.Base.ReturnType, value.Type()),
+			f.Base.Context,
+		)
+	}
+	return nil
+}
+
+// runDeferred runs the deferred calls of the execution context in reverse order.
+func (f *Function) runDeferred(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	deferred := *execCtx.Deferred
+
+	var firstErr *RuntimeError
+	for idx := len(deferred) - 1; idx >= 0; idx-- {
+		interpreter := NewInterpreter()
+		deferRes := interpreter.callValue(deferred[idx].Callee, deferred[idx].Args)
+		if deferRes.Error != nil && firstErr == nil {
+			firstErr = deferRes.Error
+		}
+	}
+	execCtx.Deferred = nil
+
+	if firstErr != nil {
+		return res.Failure(firstErr)
+	}
+	return res.Success(nil)
+}
+
 // Copy creates a copy of the function.
 func (f *Function) Copy() *Value {
-	return NewFunction(&f.Base.Name, f.BodyNode, f.ArgNames, f.Flag).SetContext(f.Base.Context).SetPos(f.PosStart(), f.PosEnd())
+	copied := NewFunction(&f.Base.Name, f.BodyNode, f.ArgNames, f.Flag)
+	copied.Function().Base.ArgTypes = f.Base.ArgTypes
+	copied.Function().Base.ReturnType = f.Base.ReturnType
+	copied.Function().Base.Definition = f.Base.Definition
+	copied.Function().Locals = f.Locals
+	copied.Function().Closure = f.Closure
+	copied.Function().Compiled = f.Compiled
+	return copied.SetContext(f.Base.Context).SetPos(f.PosStart(), f.PosEnd())
 }
 
 // String returns the string representation of the function.
@@ -74,9 +183,6 @@ func (f *Function) PosEnd() *Position {
 }
 
 func (f *Function) IllegalOperation(other *Value) *RuntimeError {
-	if other.Function == nil {
-		other.Function = f
-	}
 	return NewRTError(f.PosStart(), f.PosEnd(), "Illegal operation", f.Base.Context)
 }
 
@@ -87,7 +193,7 @@ func NewBaseFunction(name *string) *BaseFunction {
 		name = &a
 	}
 
-	return &BaseFunction{*name, nil, nil, nil}
+	return &BaseFunction{*name, nil, nil, nil, nil, nil, nil}
 }
 
 func (b *BaseFunction) PosStart() *Position {
@@ -127,6 +233,16 @@ func (b *BaseFunction) CheckArgs(argNames []string, args []*Value, variadic bool
 		))
 	}
 
+	for i, argType := range b.ArgTypes {
+		if argType != nil && !argType.Matches(args[i]) {
+			return res.Failure(NewRTError(
+				b.PosStart(), b.PosEnd(),
+				fmt.Sprintf("Expected argument %d of type %s, but got %s", i+1, argType, args[i].Type()),
+				b.Context,
+			))
+		}
+	}
+
 	return res.Success(nil)
 }
 
@@ -137,11 +253,11 @@ func (b *BaseFunction) PopulateArgs(argNames []string, args []*Value, execCtx *C
 	} else {
 		for i, argName := range argNames {
 			argValue := args[i]
-			/*if argValue.Function != nil {
-				argValue = argValue.Function.Execute(argValue.)
-			} else if argValue.BuildInFunction != nil {
+			/*if argValue.Function() != nil {
+				argValue = argValue.Function().Execute(argValue.)
+			} else if argValue.BuildInFunction() != nil {
 
-			} else if argValue.StdLibFunction != nil {
+			} else if argValue.StdLibFunction() != nil {
 
 			}*/
 			value := argValue.SetContext(execCtx)
@@ -176,14 +292,50 @@ func NewBuildInFunction(name string) *Value {
 	BuildInFn.Methods["pop"] = Method{ArgsNames: []string{"array", "index"}, Fn: BuildInFn.ExecutePop}
 	BuildInFn.Methods["str"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteStr}
 	BuildInFn.Methods["num"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteNum}
-
-	return &Value{BuildInFunction: BuildInFn}
+	BuildInFn.Methods["split"] = Method{ArgsNames: []string{"value", "separator"}, Fn: BuildInFn.ExecuteSplit}
+	BuildInFn.Methods["join"] = Method{ArgsNames: []string{"array", "separator"}, Fn: BuildInFn.ExecuteJoin}
+	BuildInFn.Methods["freeze"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteFreeze}
+	BuildInFn.Methods["isFrozen"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteIsFrozen}
+	BuildInFn.Methods["clone"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteClone}
+	BuildInFn.Methods["deepClone"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteDeepClone}
+	BuildInFn.Methods["memoize"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteMemoize}
+	BuildInFn.Methods["trace"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteTrace}
+	BuildInFn.Methods["deprecated"] = Method{ArgsNames: []string{"message"}, Fn: BuildInFn.ExecuteDeprecated}
+	BuildInFn.Methods["eval"] = Method{ArgsNames: []string{"code", "env"}, Fn: BuildInFn.ExecuteEval, Optional: 1}
+	BuildInFn.Methods["exec"] = Method{ArgsNames: []string{"code", "env"}, Fn: BuildInFn.ExecuteExec, Optional: 1}
+	BuildInFn.Methods["typeOf"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteTypeOf}
+	BuildInFn.Methods["int"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteInt}
+	BuildInFn.Methods["float"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteFloat}
+	BuildInFn.Methods["bool"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteBool}
+	BuildInFn.Methods["bytes"] = Method{ArgsNames: []string{"value", "encoding"}, Fn: BuildInFn.ExecuteBytes}
+	BuildInFn.Methods["chr"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteChr}
+	BuildInFn.Methods["ord"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteOrd}
+	BuildInFn.Methods["funcName"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteFuncName}
+	BuildInFn.Methods["funcParams"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteFuncParams}
+	BuildInFn.Methods["funcArity"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteFuncArity}
+	BuildInFn.Methods["funcFile"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteFuncFile}
+	BuildInFn.Methods["funcLine"] = Method{ArgsNames: []string{"function"}, Fn: BuildInFn.ExecuteFuncLine}
+	BuildInFn.Methods["slice"] = Method{ArgsNames: []string{"value", "start", "end"}, Fn: BuildInFn.ExecuteSlice}
+	BuildInFn.Methods["decimal"] = Method{ArgsNames: []string{"value"}, Fn: BuildInFn.ExecuteDecimal}
+	BuildInFn.Methods["round"] = Method{ArgsNames: []string{"value", "places"}, Fn: BuildInFn.ExecuteRound}
+	BuildInFn.Methods["setDecimalScale"] = Method{ArgsNames: []string{"places"}, Fn: BuildInFn.ExecuteSetDecimalScale}
+	BuildInFn.Methods["setDecimalRounding"] = Method{ArgsNames: []string{"mode"}, Fn: BuildInFn.ExecuteSetDecimalRounding}
+	BuildInFn.Methods["await"] = Method{ArgsNames: []string{"task"}, Fn: BuildInFn.ExecuteAwait}
+	BuildInFn.Methods["channel"] = Method{ArgsNames: []string{"size"}, Fn: BuildInFn.ExecuteChannel}
+	BuildInFn.Methods["send"] = Method{ArgsNames: []string{"channel", "value"}, Fn: BuildInFn.ExecuteSend}
+	BuildInFn.Methods["receive"] = Method{ArgsNames: []string{"channel"}, Fn: BuildInFn.ExecuteReceive}
+	BuildInFn.Methods["close"] = Method{ArgsNames: []string{"channel"}, Fn: BuildInFn.ExecuteClose}
+	BuildInFn.Methods["select"] = Method{ArgsNames: []string{"channels"}, Fn: BuildInFn.ExecuteSelect}
+
+	return &Value{Kind: KIND_BUILD_IN_FUNCTION, ref: BuildInFn}
 
 }
 
 func (b *BuildInFunction) Execute(args ...*Value) *RTResult {
 	res := NewRTResult()
 	execCtx := b.Base.GenerateNewContext()
+	// arguments of build-in functions must not leak into the scope of the caller, which may be shared with other tasks
+	execCtx.SymbolTable = NewIsolatedSymbolTable(execCtx.Parent.SymbolTable)
 	method, ok := b.Methods[b.Base.Name]
 	if !ok {
 		b.noVisitMethod()
@@ -192,6 +344,9 @@ func (b *BuildInFunction) Execute(args ...*Value) *RTResult {
 	if b.Base.Name == "print" || b.Base.Name == "println" {
 		res.Register(b.Base.CheckAndPopulateArgs(method.ArgsNames, args, execCtx, true))
 	} else {
+		for len(args) < len(method.ArgsNames) && len(method.ArgsNames)-len(args) <= method.Optional {
+			args = append(args, NewNull())
+		}
 		res.Register(b.Base.CheckAndPopulateArgs(method.ArgsNames, args, execCtx, false))
 		if res.Error != nil {
 			return res
@@ -200,6 +355,10 @@ func (b *BuildInFunction) Execute(args ...*Value) *RTResult {
 
 	returnValue := res.Register(method.Fn(execCtx))
 	if res.Error != nil {
+		// the methods are bound to the registered build-in without a position, locate their errors at this call
+		if res.Error.PosStart == nil {
+			res.Error.PosStart, res.Error.PosEnd, res.Error.Context = b.Base.PosStart(), b.Base.PosEnd(), execCtx
+		}
 		return res
 	}
 
@@ -216,13 +375,14 @@ func (b *BuildInFunction) String() string {
 }
 
 func (b *BuildInFunction) Copy() *Value {
-	return NewBuildInFunction(b.Base.Name).SetContext(b.Base.Context).SetPos(b.Base.PosStart(), b.Base.PosEnd())
+	base := *b.Base
+	return &Value{Kind: KIND_BUILD_IN_FUNCTION, ref: &BuildInFunction{Base: &base, Methods: b.Methods}}
 }
 
 func (b *BuildInFunction) executeIsNumber(execCtx *Context) *RTResult {
 	value, exists, _ := execCtx.SymbolTable.Get("value")
 
-	if exists && value.Number != nil {
+	if exists && value.Number() != nil {
 		return NewRTResult().Success(NewBoolean(One))
 	} else {
 		return NewRTResult().Success(NewBoolean(Zero))
@@ -232,7 +392,7 @@ func (b *BuildInFunction) executeIsNumber(execCtx *Context) *RTResult {
 func (b *BuildInFunction) executeIsFunction(execCtx *Context) *RTResult {
 	value, exists, _ := execCtx.SymbolTable.Get("value")
 
-	if exists && value.Function != nil {
+	if exists && value.Function() != nil {
 		return NewRTResult().Success(NewBoolean(One))
 	} else {
 		return NewRTResult().Success(NewBoolean(Zero))
@@ -242,7 +402,7 @@ func (b *BuildInFunction) executeIsFunction(execCtx *Context) *RTResult {
 func (b *BuildInFunction) ExecuteIsArray(execCtx *Context) *RTResult {
 	value, exists, _ := execCtx.SymbolTable.Get("value")
 
-	if exists && value.Array != nil {
+	if exists && value.Array() != nil {
 		return NewRTResult().Success(NewBoolean(One))
 	} else {
 		return NewRTResult().Success(NewBoolean(Zero))
@@ -254,11 +414,14 @@ func (b *BuildInFunction) ExecuteAppend(execCtx *Context) *RTResult {
 	array, exists, _ := execCtx.SymbolTable.Get("array")
 	value, _, _ := execCtx.SymbolTable.Get("value")
 
-	if exists && array.Array == nil {
+	if exists && array.Array() == nil {
 		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "First argument must be an array", execCtx))
 	}
+	if err := array.Array().CheckMutable("append to", b.Base.PosStart(), b.Base.PosEnd(), execCtx); err != nil {
+		return res.Failure(err)
+	}
 
-	array.Array.Elements = append(array.Array.Elements, value)
+	array.Array().Append(value)
 	return res.Success(NewNull())
 }
 
@@ -281,18 +444,18 @@ func (b *BuildInFunction) ExecutePop(execCtx *Context) *RTResult {
 	index, _, _ := execCtx.SymbolTable.Get("index")
 	array, exists, _ := execCtx.SymbolTable.Get("array")
 
-	if exists && array.Array != nil {
-		if index.Number != nil {
-			arr := array.Array.Elements
-			idx := index.Number.ValueField.(int)
-			if index.Number.ValueField.(int) < 0 || index.Number.ValueField.(int) >= len(arr) {
+	if exists && array.Array() != nil {
+		if index.Number() != nil {
+			if err := array.Array().CheckMutable("pop from", b.Base.PosStart(), b.Base.PosEnd(), execCtx); err != nil {
+				return NewRTResult().Failure(err)
+			}
+			if !index.Number().IsInt() {
+				return NewRTResult().Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Index out of bounds", execCtx))
+			}
+			element, ok := array.Array().Remove(index.Number().Int)
+			if !ok {
 				return NewRTResult().Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Index out of bounds", execCtx))
 			}
-
-			element := arr[idx]
-
-			array.Array.Elements = append(arr[:idx], arr[idx+1:]...)
-
 			return NewRTResult().Success(element)
 		} else {
 			return NewRTResult().Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Index must be an Number, got: %v", index.Type()), execCtx))
@@ -308,15 +471,19 @@ func (b *BuildInFunction) ExecuteStr(execCtx *Context) *RTResult {
 
 	if !exists {
 		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Missing an argument for a conversion to string", execCtx))
-	} else if value.Number != nil {
-		switch number := value.Number.ValueField.(type) {
+	} else if value.Number() != nil {
+		switch number := value.Number().Value().(type) {
 		case int:
 			return res.Success(NewString(strconv.Itoa(number)))
 		case float64:
 			return res.Success(NewString(strconv.Itoa(int(number))))
+		case *big.Int:
+			return res.Success(NewString(number.String()))
+		case *Decimal:
+			return res.Success(NewString(number.String()))
 		}
-	} else if value.ByteArray != nil {
-		return res.Success(value.ByteArray.ToString())
+	} else if value.ByteArray() != nil {
+		return res.Success(value.ByteArray().ToString())
 	}
 	return res.Success(NewNull())
 }
@@ -327,23 +494,209 @@ func (b *BuildInFunction) ExecuteNum(execCtx *Context) *RTResult {
 
 	if !exists {
 		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Missing an argument for a conversion to Number (integer)", execCtx))
-	} else if value.String != nil {
-		if strings.Contains(value.String.ValueField, ".") {
-			parsed, _ := strconv.ParseFloat(value.String.ValueField, 64)
+	} else if value.String() != nil {
+		if strings.Contains(value.String().ValueField, ".") {
+			parsed, _ := strconv.ParseFloat(value.String().ValueField, 64)
 			return res.Success(NewNumber(parsed))
 
 		} else {
-			parsed, _ := strconv.Atoi(value.String.ValueField)
+			parsed, ok := parseInteger(value.String().ValueField)
+			if !ok {
+				parsed = 0
+			}
 			return res.Success(NewNumber(parsed))
 		}
-	} else if value.ByteArray != nil {
-		result, err := value.ByteArray.ToNumber()
+	} else if value.ByteArray() != nil {
+		result, err := value.ByteArray().ToNumber()
 		if err != nil {
 			return res.Failure(err)
 		}
 		return res.Success(result)
-	} else if value.Number != nil {
+	} else if value.Number() != nil {
 		return res.Success(value)
 	}
 	return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not use given argument of type %s", value.Type()), b.Base.Context))
 }
+
+// ExecuteFreeze returns a deeply immutable copy of the value, the given value stays mutable.
+func (b *BuildInFunction) ExecuteFreeze(execCtx *Context) *RTResult {
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	return NewRTResult().Success(value.Freeze())
+}
+
+func (b *BuildInFunction) ExecuteIsFrozen(execCtx *Context) *RTResult {
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	return NewRTResult().Success(NewBoolean(ConvertBoolToInt(value.IsFrozen())))
+}
+
+// callableBase returns the base of a callable value and the names of its parameters.
+// Functions of the standard library do not declare parameter names, for them params is nil.
+func callableBase(value *Value) (base *BaseFunction, params []string, ok bool) {
+	switch {
+	case value.Function() != nil:
+		return value.Function().Base, value.Function().ArgNames, true
+	case value.BuildInFunction() != nil:
+		return value.BuildInFunction().Base, value.BuildInFunction().Methods[value.BuildInFunction().Base.Name].ArgsNames, true
+	case value.StdLibFunction() != nil:
+		return value.StdLibFunction().Base, nil, true
+	}
+	return nil, nil, false
+}
+
+// introspect looks up the function argument and reports an error if it can not be called.
+func (b *BuildInFunction) introspect(execCtx *Context) (*BaseFunction, []string, *Value, *RuntimeError) {
+	function, _, _ := execCtx.SymbolTable.Get("function")
+	base, params, ok := callableBase(function)
+	if !ok {
+		return nil, nil, function, NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Expected a function, but got %s", function.Type()), execCtx)
+	}
+	return base, params, function, nil
+}
+
+func (b *BuildInFunction) ExecuteFuncName(execCtx *Context) *RTResult {
+	base, _, _, err := b.introspect(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+	return NewRTResult().Success(NewString(base.Name))
+}
+
+// ExecuteFuncParams returns the parameter names of a function, or null if the function does not declare them.
+func (b *BuildInFunction) ExecuteFuncParams(execCtx *Context) *RTResult {
+	_, params, function, err := b.introspect(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+	if function.StdLibFunction() != nil {
+		return NewRTResult().Success(NewNull())
+	}
+	names := make([]*Value, len(params))
+	for idx, param := range params {
+		names[idx] = NewString(param)
+	}
+	return NewRTResult().Success(NewArray(names))
+}
+
+// ExecuteFuncArity returns the number of parameters of a function, or null if the function does not declare them.
+func (b *BuildInFunction) ExecuteFuncArity(execCtx *Context) *RTResult {
+	_, params, function, err := b.introspect(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+	if function.StdLibFunction() != nil {
+		return NewRTResult().Success(NewNull())
+	}
+	return NewRTResult().Success(NewNumber(len(params)))
+}
+
+// ExecuteFuncFile returns the file a function is defined in, build-in functions are not defined in a file and return null.
+func (b *BuildInFunction) ExecuteFuncFile(execCtx *Context) *RTResult {
+	base, _, _, err := b.introspect(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+	if base.Definition == nil {
+		return NewRTResult().Success(NewNull())
+	}
+	return NewRTResult().Success(NewString(base.Definition.Src.Fn))
+}
+
+// ExecuteFuncLine returns the line a function is defined on, counted from 1.
+func (b *BuildInFunction) ExecuteFuncLine(execCtx *Context) *RTResult {
+	base, _, _, err := b.introspect(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+	if base.Definition == nil {
+		return NewRTResult().Success(NewNull())
+	}
+	return NewRTResult().Success(NewNumber(base.Definition.Ln + 1))
+}
+
+// ExecuteSlice returns the part of a string, array or byte array from start up to end. Strings are sliced by
+// characters, byte arrays by bytes.
+func (b *BuildInFunction) ExecuteSlice(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	start, _, _ := execCtx.SymbolTable.Get("start")
+	end, _, _ := execCtx.SymbolTable.Get("end")
+
+	if start.Number() == nil || end.Number() == nil || !start.Number().IsInt() || !end.Number().IsInt() {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Start and end of a slice must be ints", execCtx))
+	}
+	startIdx, endIdx := start.Number().Int, end.Number().Int
+
+	var result *Value
+	var err *RuntimeError
+	switch {
+	case value.String() != nil:
+		result, err = value.String().Slice(startIdx, endIdx)
+	case value.ByteArray() != nil:
+		result, err = value.ByteArray().Slice(start.Number(), end.Number())
+	case value.Array() != nil:
+		if startIdx < 0 || endIdx > len(value.Array().Elements) || startIdx > endIdx {
+			err = NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Invalid slice indices %d and %d for an array of length %d", startIdx, endIdx, len(value.Array().Elements)), execCtx)
+		} else {
+			result = NewArray(ownElements(value.Array().Elements[startIdx:endIdx], endIdx-startIdx))
+			result.Array().Frozen = value.Array().Frozen
+		}
+	default:
+		err = NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not slice %s", value.Type()), execCtx)
+	}
+	if err != nil {
+		err.PosStart, err.PosEnd, err.Context = b.Base.PosStart(), b.Base.PosEnd(), execCtx
+		return res.Failure(err)
+	}
+	return res.Success(result)
+}
+
+func (b *BuildInFunction) ExecuteClone(execCtx *Context) *RTResult {
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	copied := value.Copy()
+	// a clone is made to be changed, only the copied array itself is thawed, nested arrays stay as they are
+	if copied.Array() != nil {
+		copied.Array().Frozen = false
+	}
+	return NewRTResult().Success(copied)
+}
+
+func (b *BuildInFunction) ExecuteDeepClone(execCtx *Context) *RTResult {
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	return NewRTResult().Success(value.DeepCopy())
+}
+
+func (b *BuildInFunction) ExecuteSplit(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	separator, _, _ := execCtx.SymbolTable.Get("separator")
+
+	if value.String() == nil || separator.String() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not split %s by %s, expected two strings", value.Type(), separator.Type()), execCtx))
+	}
+
+	var elements []*Value
+	for _, part := range strings.Split(value.String().ValueField, separator.String().ValueField) {
+		elements = append(elements, NewString(part))
+	}
+	return res.Success(NewArray(elements))
+}
+
+func (b *BuildInFunction) ExecuteJoin(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	array, _, _ := execCtx.SymbolTable.Get("array")
+	separator, _, _ := execCtx.SymbolTable.Get("separator")
+
+	if array.Array() == nil || separator.String() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not join %s with %s, expected an array and a string", array.Type(), separator.Type()), execCtx))
+	}
+
+	parts := make([]string, len(array.Array().Elements))
+	for i, element := range array.Array().Elements {
+		if element.String() != nil {
+			parts[i] = element.String().ValueField
+		} else {
+			parts[i] = fmt.Sprint(element.Value())
+		}
+	}
+	return res.Success(NewString(strings.Join(parts, separator.String().ValueField)))
+}
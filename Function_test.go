@@ -0,0 +1,143 @@
+package main
+
+import "testing"
+
+func TestDefer(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name: "arguments are evaluated at the defer statement",
+			source: `var out = ""
+func log(text) {
+	out = out + text
+}
+func loop() {
+	for i = 0 to 3 {
+		defer log("i=" + str(i) + ";")
+	}
+}
+loop()`,
+			want: "i=2;i=1;i=0;",
+		},
+		{
+			name: "reassigning a variable does not change a deferred call",
+			source: `var out = ""
+func closeHandle(handle) {
+	out = out + "closed " + handle
+}
+func use() {
+	var handle = "a"
+	defer closeHandle(handle)
+	handle = "b"
+}
+use()`,
+			want: "closed a",
+		},
+		{
+			name: "the function is evaluated at the defer statement",
+			source: `var out = ""
+func first() {
+	out = out + "first"
+}
+func second() {
+	out = out + "second"
+}
+func use() {
+	var action = first
+	defer action()
+	action = second
+}
+use()`,
+			want: "first",
+		},
+		{
+			name:    "only calls can be deferred",
+			source:  "func f() {\n\tdefer 1 + 2\n}",
+			wantErr: "Expected function call after 'defer'",
+		},
+	})
+}
+
+func TestClone(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "a clone of a frozen array can be changed",
+			source: "var frozen = freeze([1, 2])\nvar copy = clone(frozen)\nappend(copy, 3)\nvar out = [len(frozen), len(copy), isFrozen(frozen), isFrozen(copy)]",
+			want:   "[2, 3, true, false]",
+		},
+		{
+			name:   "clone keeps nested arrays frozen",
+			source: "var copy = clone(freeze([[1]]))\nvar out = [isFrozen(copy), isFrozen(copy[0])]",
+			want:   "[false, true]",
+		},
+		{
+			name:   "a deep clone of a frozen array can be changed at every level",
+			source: "var frozen = freeze([[1], 2])\nvar copy = deepClone(frozen)\nappend(copy[0], 3)\nvar out = [isFrozen(copy), isFrozen(copy[0]), len(copy[0]), len(frozen[0])]",
+			want:   "[false, false, 2, 1]",
+		},
+		{
+			name:    "the frozen array itself stays frozen",
+			source:  "var frozen = freeze([1])\nvar copy = clone(frozen)\nappend(frozen, 2)",
+			wantErr: "Cannot append to a frozen array",
+		},
+	})
+}
+
+func TestTailCalls(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name: "calls in tail position do not count towards the call depth",
+			source: `func loop(n, acc) {
+	if n == 0 {
+		return acc
+	}
+	return loop(n - 1, acc + 1)
+}
+var out = loop(20000, 0)`,
+			want: "20000",
+		},
+		{
+			name: "deferred calls of a frame run after its tail call returned",
+			source: `var out = ""
+func log(text) {
+	out = out + text
+}
+func countdown(n) {
+	defer log(str(n))
+	if n == 0 {
+		return 0
+	}
+	return countdown(n - 1)
+}
+countdown(3)`,
+			want: "0123",
+		},
+		{
+			name: "a frame with deferred calls does not stop the tail calls below it",
+			source: `var out = ""
+func log(text) {
+	out = out + text
+}
+func loop(n, acc) {
+	if n == 0 {
+		return acc
+	}
+	return loop(n - 1, acc + 1)
+}
+func outer(n) {
+	defer log("done ")
+	return loop(n, 0)
+}
+log(str(outer(20000)))`,
+			want: "done 20000",
+		},
+		{
+			name: "return types are checked for every replaced call",
+			source: `func inner() => "text"
+func outer() -> Number {
+	return inner()
+}
+var out = outer()`,
+			wantErr: "Expected 'outer' to return Number, but got String",
+		},
+	})
+}
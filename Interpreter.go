@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"reflect"
+	"sync"
 )
 
 func NewInterpreter() Interpreter {
@@ -40,17 +41,25 @@ func (i *Interpreter) visit(node Node, context *Context) *RTResult {
 	case *ReturnNode:
 		return i.visitReturnNode(*n, context)
 	case *ContinueNode:
-		return i.visitContinueNode()
+		return i.visitContinueNode(*n)
 	case *BreakNode:
-		return i.visitBreakNode()
+		return i.visitBreakNode(*n)
 	case *ImportNode:
 		return i.visitImportNode(*n, context)
+	case *MethodCallNode:
+		return i.visitMethodCallNode(*n, context)
 	case *PackageMethod:
 		return i.visitPackageMethodNode(*n, context)
 	case *ReferenceNode:
 		return i.visitReferenceNode(*n, context)
 	case *DereferenceNode:
 		return i.visitDereferenceNode(*n, context)
+	case *SpawnNode:
+		return i.visitSpawnNode(*n, context)
+	case *ComprehensionNode:
+		return i.visitComprehensionNode(*n, context)
+	case *DeferNode:
+		return i.visitDeferNode(*n, context)
 
 	default:
 		// Handle unknown node types
@@ -65,16 +74,12 @@ func (i *Interpreter) NoVisitMethod(node Node, context *Context) *RTResult {
 }
 
 func (i *Interpreter) visitNumberNode(node NumberNode, context *Context) *RTResult {
-	value := NewNumber(node.Value)
-	value.SetContext(context).SetPos(node.PosStart(), node.PosEnd())
-	return NewRTResult().Success(value)
+	return NewRTResult().Success(NewNumber(node.Value))
 }
 
 func (i *Interpreter) visitStringNode(node StringNode, context *Context) *RTResult {
 	if TokenValue, ok := node.Value.(string); ok {
-		value := NewString(TokenValue)
-		value.SetContext(context).SetPos(node.PosStart(), node.PosEnd())
-		return NewRTResult().Success(value)
+		return NewRTResult().Success(NewString(TokenValue))
 	}
 	return NewRTResult().Failure(NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("%s is not of type string", reflect.TypeOf(node.Value)), context))
 }
@@ -103,33 +108,9 @@ func (i *Interpreter) visitImportNode(node ImportNode, context *Context) *RTResu
 			return res.Success(NewEmptyValue())
 		}
 
-		packageContent, err := LoadPackage(packageName.Value.(string))
+		packageContext, err := importModule(packageName.Value.(string), node, context)
 		if err != nil {
-			return res.Failure(NewRTError(
-				node.PositionStart, node.PositionEnd,
-				fmt.Sprintf("Failed to load package, no package named '%s', ", packageName.Value),
-				context,
-			))
-		}
-
-		packageContext := NewContext(fmt.Sprintf("<package %v>", packageName.Value.(string)), context, node.PositionStart)
-		packageContext.SymbolTable = NewSymbolTable(nil)
-
-		tokens, Err := NewLexer(packageName.Value.(string)+".ecp", packageContent).MakeTokens()
-		if Err != nil {
-			return res.Failure(NewRTError(node.PositionStart, node.PositionEnd, fmt.Sprintf("Error tokenizing imported package %v", packageName), context))
-		}
-
-		parser := NewParser(tokens)
-		packageAst := parser.Parse()
-		if packageAst.Error != nil {
-			return res.Failure(NewRTError(packageAst.Error.PosStart, packageAst.Error.PosEnd, packageAst.Error.Details, packageContext))
-		}
-
-		interpreter := NewInterpreter()
-		res.Register(interpreter.visit(packageAst.Node, packageContext))
-		if res.Error != nil {
-			return res
+			return res.Failure(err)
 		}
 
 		// Retrieve the function from the package's symbol table
@@ -158,34 +139,9 @@ func (i *Interpreter) visitImportNode(node ImportNode, context *Context) *RTResu
 				return res.Success(NewEmptyValue())
 			}
 
-			packageName := pkg.Value.(string)
-			packageContent, err := LoadPackage(packageName)
+			packageContext, err := importModule(pkg.Value.(string), node, context)
 			if err != nil {
-				return res.Failure(NewRTError(
-					node.PositionStart, node.PositionEnd,
-					fmt.Sprintf("Failed to load package, no package named '%s', ", packageName),
-					context,
-				))
-			}
-
-			packageContext := NewContext(fmt.Sprintf("<package %v>", packageName), context, node.PositionStart)
-			packageContext.SymbolTable = NewSymbolTable(nil)
-
-			tokens, Err := NewLexer(packageName+".ecp", packageContent).MakeTokens()
-			if Err != nil {
-				return res.Failure(NewRTError(node.PositionStart, node.PositionEnd, fmt.Sprintf("Error tokenizing imported package %v", packageName), context))
-			}
-
-			parser := NewParser(tokens)
-			packageAst := parser.Parse()
-			if packageAst.Error != nil {
-				return res.Failure(NewRTError(packageAst.Error.PosStart, packageAst.Error.PosEnd, packageAst.Error.Details, packageContext))
-			}
-
-			interpreter := NewInterpreter()
-			res.Register(interpreter.visit(packageAst.Node, packageContext))
-			if res.Error != nil {
-				return res
+				return res.Failure(err)
 			}
 
 			// Export all functions and constants from packageContext to the global scope
@@ -212,21 +168,120 @@ func (i *Interpreter) visitArrayNode(node ArrayNode, context *Context) *RTResult
 		}
 		value := res.Register(result)
 		if !value.IsEmpty() {
-			elements = append(elements, value)
+			elements = append(elements, value.Share())
 		}
 		if res.ShouldReturn() {
 			return res
 		}
 	}
 
-	newArray := NewArray(elements).SetContext(context).SetPos(node.PosStart(), node.PosEnd())
+	newArray := NewArray(elements)
+	if node.Frozen {
+		newArray = newArray.Freeze()
+	}
 
 	return res.Success(newArray)
 }
 
+// visitNullishNode evaluates 'left ?? right', the right side is only evaluated when the left side is null.
+func (i *Interpreter) visitNullishNode(node BinOpNode, context *Context) *RTResult {
+	res := NewRTResult()
+
+	left := res.Register(i.visit(node.LeftNode, context))
+	if res.ShouldReturn() {
+		return res
+	}
+	if left.Null() == nil {
+		return res.Success(left)
+	}
+
+	right := res.Register(i.visit(node.RightNode, context))
+	if res.ShouldReturn() {
+		return res
+	}
+	return res.Success(right)
+}
+
+// visitComprehensionNode evaluates a list comprehension into a new array.
+func (i *Interpreter) visitComprehensionNode(node ComprehensionNode, context *Context) *RTResult {
+	res := NewRTResult()
+	elements := []*Value{}
+
+	// the loop variables live in a frame of the comprehension and are not visible after it
+	scope := *context
+	scope.Frame = NewFrame(node.Locals, context.Frame)
+
+	res.Register(i.runComprehensionClause(node, 0, &elements, &scope))
+	if res.ShouldReturn() {
+		return res
+	}
+
+	return res.Success(NewArray(elements))
+}
+
+// runComprehensionClause iterates the clause at index and either descends into the next clause or collects the element.
+func (i *Interpreter) runComprehensionClause(node ComprehensionNode, index int, elements *[]*Value, context *Context) *RTResult {
+	res := NewRTResult()
+
+	if index == len(node.Clauses) {
+		value := res.Register(i.visit(node.ElementNode, context))
+		if res.ShouldReturn() {
+			return res
+		}
+		*elements = append(*elements, value.Share())
+		return res.Success(NewNull())
+	}
+
+	clause := node.Clauses[index]
+	iterable := res.Register(i.visit(clause.IterableNode, context))
+	if res.ShouldReturn() {
+		return res
+	}
+
+	var items []*Value
+	switch {
+	case iterable.Array() != nil:
+		items = iterable.Array().Elements
+	case iterable.String() != nil:
+		for _, char := range iterable.String().ValueField {
+			items = append(items, NewString(string(char)))
+		}
+	default:
+		return res.Failure(NewRTError(clause.IterableNode.PosStart(), clause.IterableNode.PosEnd(), fmt.Sprintf("Can not iterate over type %s", iterable.Type()), context))
+	}
+
+	for _, item := range items {
+		setVariable(clause.VarNameTok.Value.(string), clause.VarBinding, item.Share(), context)
+
+		if clause.ConditionNode != nil {
+			condition := res.Register(i.visit(clause.ConditionNode, context))
+			if res.ShouldReturn() {
+				return res
+			}
+			if condition.Boolean() == nil {
+				return res.Failure(NewRTError(clause.ConditionNode.PosStart(), clause.ConditionNode.PosEnd(), fmt.Sprintf("Comprehension condition must be of type bool, got %s", condition.Type()), context))
+			}
+			if !condition.Boolean().IsTrue() {
+				continue
+			}
+		}
+
+		res.Register(i.runComprehensionClause(node, index+1, elements, context))
+		if res.ShouldReturn() {
+			return res
+		}
+	}
+
+	return res.Success(NewNull())
+}
+
 func (i *Interpreter) visitBinOpNode(node BinOpNode, context *Context) *RTResult {
 	res := NewRTResult()
 
+	if node.OpTok.Type == TT_NULLISH {
+		return i.visitNullishNode(node, context)
+	}
+
 	leftRTValue := i.visit(node.LeftNode, context)
 	res.Register(leftRTValue)
 	if res.ShouldReturn() {
@@ -239,104 +294,114 @@ func (i *Interpreter) visitBinOpNode(node BinOpNode, context *Context) *RTResult
 		return res
 	}
 
-	left := leftRTValue.Value
-	right := rightRTValue.Value
+	result, err := binaryOperation(&node, leftRTValue.Value, rightRTValue.Value, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	return res.Success(result)
+}
+
+// binaryOperation applies the operator of node to the evaluated operands.
+func binaryOperation(node *BinOpNode, left, right *Value, context *Context) (*Value, *RuntimeError) {
 
 	var result *Value
 	var err *RuntimeError
 
-	// get the operation type and use the left and the right.Number node from the operation symbol as values
+	// get the operation type and use the left and the right.Number() node from the operation symbol as values
 	switch node.OpTok.Type {
 	case TT_PLUS:
-		if left.String != nil && right.String != nil {
-			result, err = left.String.AddedTo(right.String)
-		} else if left.Number != nil && right.Number != nil {
-			result, err = left.Number.AddedTo(right.Number)
-		} else if left.Array != nil && right.Number != nil {
-			result, err = left.Array.AddedTo(right)
-		} else if right.Array != nil && left.Array != nil {
-			result, err = left.Array.AddedTo(right)
-		} else if right.ByteArray != nil && left.ByteArray != nil {
-			result, err = left.ByteArray.AddedTo(right.ByteArray)
+		if left.String() != nil && right.String() != nil {
+			result, err = left.String().AddedTo(right.String())
+		} else if left.Number() != nil && right.Number() != nil {
+			result, err = left.Number().AddedTo(right.Number())
+		} else if left.Array() != nil && right.Number() != nil {
+			result, err = left.Array().AddedTo(right)
+		} else if right.Array() != nil && left.Array() != nil {
+			result, err = left.Array().AddedTo(right)
+		} else if right.ByteArray() != nil && left.ByteArray() != nil {
+			result, err = left.ByteArray().AddedTo(right.ByteArray())
 		} else {
-			return res.Failure(NewRTError(left.GetPosStart(), right.GetPosEnd(), fmt.Sprintf("Cannot add values of types %s and %s together ", left.Type(), right.Type()), context))
+			return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot add values of types %s and %s together ", left.Type(), right.Type()), context)
 		}
 	case TT_MINUS:
-		if left.Array != nil && right.Number != nil {
-			result, err = left.Array.SubtractedBy(right)
+		if left.Array() != nil && right.Number() != nil {
+			result, err = left.Array().SubtractedBy(right)
 		} else {
-			result, err = left.Number.SubtractedBy(right.Number)
+			result, err = left.Number().SubtractedBy(right.Number())
 		}
 	case TT_STAR:
-		if left.String != nil && right.Number != nil {
-			result, err = left.String.MultipliedBy(right.Number)
-		} else if left.Number != nil && right.Number != nil {
-			result, err = left.Number.MultipliedBy(right.Number)
-		} else if left.Array != nil && right.Array != nil {
-			result, err = left.Array.MultipliedBy(right)
-		} else if right.Number != nil && left.ByteArray != nil {
-			result, err = left.ByteArray.MultipliedBy(right.Number)
+		if left.String() != nil && right.Number() != nil {
+			result, err = left.String().MultipliedBy(right.Number())
+		} else if left.Number() != nil && right.Number() != nil {
+			result, err = left.Number().MultipliedBy(right.Number())
+		} else if left.Array() != nil && right.Array() != nil {
+			result, err = left.Array().MultipliedBy(right)
+		} else if right.Number() != nil && left.ByteArray() != nil {
+			result, err = left.ByteArray().MultipliedBy(right.Number())
 		} else {
-			return res.Failure(NewRTError(left.GetPosStart(), right.GetPosEnd(), fmt.Sprintf("Cannot multiply values of types %s and %s", left.Type(), right.Type()), context))
+			return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot multiply values of types %s and %s", left.Type(), right.Type()), context)
 		}
 	case TT_DIV:
-		result, err = left.Number.DividedBy(right.Number)
+		result, err = left.Number().DividedBy(right.Number())
 	case TT_POW:
-		result, err = left.Number.PowedBy(right.Number)
+		result, err = left.Number().PowedBy(right.Number())
 	case TT_EE:
-		if left.Number != nil {
-			result, err = left.Number.GetComparisonEq(right)
-		} else if left.Null != nil {
-			result, err = left.Null.GetComparisonEq(right)
-		} else if right.Null != nil {
-			result, err = right.Null.GetComparisonEq(left)
-		} else if right.Boolean != nil && left.Boolean != nil {
-			result, err = left.Boolean.GetComparisonEq(right.Boolean)
-		} else if left.String != nil {
-			result, err = left.String.GetComparisonEq(left)
-		} else if right.ByteArray != nil && left.ByteArray != nil {
-			result, err = left.ByteArray.GetComparisonEq(right)
+		if left.Null() != nil {
+			result, err = left.Null().GetComparisonEq(right)
+		} else if right.Null() != nil {
+			result, err = right.Null().GetComparisonEq(left)
+		} else if left.Number() != nil {
+			result, err = left.Number().GetComparisonEq(right)
+		} else if right.Boolean() != nil && left.Boolean() != nil {
+			result, err = left.Boolean().GetComparisonEq(right.Boolean())
+		} else if left.String() != nil {
+			result, err = left.String().GetComparisonEq(right)
+		} else if right.ByteArray() != nil && left.ByteArray() != nil {
+			result, err = left.ByteArray().GetComparisonEq(right)
 		} else {
-			return res.Failure(NewRTError(left.GetPosStart(), right.GetPosEnd(), fmt.Sprintf("Cannot compaire values of types %s and %s ", left.Type(), right.Type()), context))
+			return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot compaire values of types %s and %s ", left.Type(), right.Type()), context)
 		}
 	case TT_NE:
-		if left.Number != nil && right.Number != nil {
-			result, err = left.Number.GetComparisonNe(right.Number)
-		} else if right.Boolean != nil && left.Boolean != nil {
-			result, err = left.Boolean.GetComparisonNe(right.Boolean)
-		} else if left.Null != nil {
-			result, err = left.Null.GetComparisonNe(right)
-		} else if right.Null != nil {
-			result, err = right.Null.GetComparisonNe(left)
+		if left.Null() != nil {
+			result, err = left.Null().GetComparisonNe(right)
+		} else if right.Null() != nil {
+			result, err = right.Null().GetComparisonNe(left)
+		} else if left.Number() != nil && right.Number() != nil {
+			result, err = left.Number().GetComparisonNe(right.Number())
+		} else if right.Boolean() != nil && left.Boolean() != nil {
+			result, err = left.Boolean().GetComparisonNe(right.Boolean())
+		} else {
+			return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot compaire values of types %s and %s ", left.Type(), right.Type()), context)
 		}
 	case TT_LT:
-		result, err = left.Number.GetComparisonLt(right.Number)
+		result, err = left.Number().GetComparisonLt(right.Number())
 	case TT_GT:
-		if left.Number != nil && right.Number != nil {
-			result, err = left.Number.GetComparisonGt(right.Number)
+		if left.Number() != nil && right.Number() != nil {
+			result, err = left.Number().GetComparisonGt(right.Number())
 		} else {
-			return res.Failure(NewRTError(left.GetPosStart(), right.GetPosEnd(), fmt.Sprintf("Cannot compaire values of types %s and %s ", left.Type(), right.Type()), context))
+			return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot compaire values of types %s and %s ", left.Type(), right.Type()), context)
 		}
 	case TT_LTE:
-		result, err = left.Number.GetComparisonLte(right.Number)
+		result, err = left.Number().GetComparisonLte(right.Number())
 	case TT_GTE:
-		result, err = left.Number.GetComparisonGte(right.Number)
+		result, err = left.Number().GetComparisonGte(right.Number())
 	case TT_KEYWORD:
 		if node.OpTok.Value == "AND" {
-			result, err = left.Number.AndedBy(right.Number)
+			result, err = left.Number().AndedBy(right.Number())
 		} else if node.OpTok.Value == "OR" {
-			result, err = left.Number.OredBy(right.Number)
+			result, err = left.Number().OredBy(right.Number())
 		}
 	default:
-		return res.Failure(NewRTError(node.OpTok.PosStart, node.OpTok.PosEnd, "Invalid operation", context))
+		return nil, NewRTError(node.OpTok.PosStart, node.OpTok.PosEnd, "Invalid operation", context)
 	}
 	if err != nil {
-		return res.Failure(err)
+		// values have no position, their errors are located at the operation
+		if err.PosStart == nil {
+			err.PosStart, err.PosEnd, err.Context = node.PosStart(), node.PosEnd(), context
+		}
+		return nil, err
 	}
-
-	result.SetContext(context).SetPos(node.PosStart(), node.PosEnd())
-
-	return res.Success(result)
+	return result, nil
 }
 
 func (i *Interpreter) visitUnaryOpNode(node UnaryOpNode, context *Context) *RTResult {
@@ -346,35 +411,37 @@ func (i *Interpreter) visitUnaryOpNode(node UnaryOpNode, context *Context) *RTRe
 		return res
 	}
 
+	result, err := unaryOperation(&node, numValue, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	return res.Success(result)
+}
+
+// unaryOperation applies the prefix operator of node to the evaluated operand.
+func unaryOperation(node *UnaryOpNode, numValue *Value, context *Context) (*Value, *RuntimeError) {
 	var result *Value
 	var err *RuntimeError
 
-	num := numValue.Number
+	num := numValue.Number()
 	if num == nil {
-		return res.Failure(NewRTError(node.Node.PosStart(), node.Node.PosEnd(), "Expected a number", context))
+		return nil, NewRTError(node.Node.PosStart(), node.Node.PosEnd(), "Expected a number", context)
 	}
 
 	// else if for some reason required, when not expressions like +1 won't work because the context is not set
 	if node.OpTok.Type == TT_MINUS {
-		result, err = num.MultipliedBy(NewNumber(-1).Number)
-		if err != nil {
-			return res.Failure(err)
-		}
+		result, err = num.MultipliedBy(NewNumber(-1).Number())
 	} else if node.OpTok.Type == TT_PLUS {
-		result, err = num.MultipliedBy(NewNumber(1).Number)
-		if err != nil {
-			return res.Failure(err)
-		}
+		result, err = num.MultipliedBy(NewNumber(1).Number())
 	} else if node.OpTok.Matches(TT_KEYWORD, "not") {
 		result, err = num.Notted()
 	}
 
 	if err != nil {
-		return res.Failure(err)
-	} else {
-		result.SetContext(context).SetPos(node.PosStart(), node.PosEnd())
-		return res.Success(result)
+		err.PosStart, err.PosEnd, err.Context = node.PosStart(), node.PosEnd(), context
+		return nil, err
 	}
+	return result, nil
 }
 
 func (i *Interpreter) visitPackageMethodNode(node PackageMethod, context *Context) *RTResult {
@@ -383,7 +450,7 @@ func (i *Interpreter) visitPackageMethodNode(node PackageMethod, context *Contex
 	packageMethod, exists := context.SymbolTable.GetPackageMethod(node.PackageName, node.MethodName)
 
 	if !exists {
-		if _, exists := context.SymbolTable.packages[node.PackageName]; exists {
+		if context.SymbolTable.HasPackage(node.PackageName) {
 			return res.Failure(NewRTError(
 				node.PosStart(), node.PosEnd(),
 				fmt.Sprintf("Unresolved function reference '%s' in '%s' package", node.MethodName, node.PackageName),
@@ -406,9 +473,9 @@ func (i *Interpreter) visitPackageMethodNode(node PackageMethod, context *Contex
 			args = append(args, arg)
 		}
 
-		result = res.Register(packageMethod.StdLibFunction.Function(args))
+		result = res.Register(packageMethod.StdLibFunction().Function(args))
 		if res.Error != nil {
-			res.Error.SetLocation(packageMethod.StdLibFunction.Base)
+			res.Error.SetLocation(packageMethod.StdLibFunction().Base)
 			return res
 		}
 	} else if _, ok := callNode.(*VarAccessNode); ok {
@@ -418,45 +485,134 @@ func (i *Interpreter) visitPackageMethodNode(node PackageMethod, context *Contex
 	return res.Success(result)
 }
 
+// visitMethodCallNode resolves 'target.name' as a package member or calls name with target as its first argument.
+func (i *Interpreter) visitMethodCallNode(node MethodCallNode, context *Context) *RTResult {
+	res := NewRTResult()
+
+	if packageMethod := packageSelector(&node, context); packageMethod != nil {
+		return i.visitPackageMethodNode(*packageMethod, context)
+	}
+
+	value := res.Register(i.visit(node.TargetNode, context))
+	if res.ShouldReturn() {
+		return res
+	}
+	if node.Optional && value.Null() != nil {
+		return res.Success(NewNull())
+	}
+
+	method, err := lookupMethod(&node, value, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+
+	args := []*Value{value}
+	for _, argNode := range node.ArgNodes {
+		args = append(args, res.Register(i.visit(argNode, context)))
+		if res.ShouldReturn() {
+			return res
+		}
+	}
+
+	valueToCall, err := positionCall(method, &node, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	returnValue := res.Register(i.callValue(valueToCall, args))
+	if res.ShouldReturn() {
+		return res
+	}
+	return res.Success(returnValue.Copy().SetPos(node.PosStart(), node.PosEnd()).SetContext(context))
+}
+
+// packageSelector returns the package member selected by node, or nil if the target of node is not an imported package.
+func packageSelector(node *MethodCallNode, context *Context) *PackageMethod {
+	target, ok := node.TargetNode.(*VarAccessNode)
+	if !ok || target.Binding != nil {
+		return nil
+	}
+	packageName := target.VarNameTok.Value.(string)
+	if _, isVariable, _ := context.SymbolTable.Get(packageName); isVariable || !context.SymbolTable.HasPackage(packageName) {
+		return nil
+	}
+	var member Node = NewVarAccessNode(node.MethodTok)
+	if node.IsCall {
+		member = NewCallNode(member, node.ArgNodes)
+	}
+	packageMethod := NewPackageMethod(target.VarNameTok, node.MethodTok.Value.(string), member)
+	packageMethod.PositionEnd = node.PosEnd()
+	return packageMethod
+}
+
+// lookupMethod resolves the function called by 'target.name(args)', value is the evaluated target.
+func lookupMethod(node *MethodCallNode, value *Value, context *Context) (*Value, *RuntimeError) {
+	if !node.IsCall {
+		return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Value of type %s has no member '%s'", value.Type(), node.MethodTok.Value), context)
+	}
+
+	if node.MethodBinding != nil {
+		if method := context.Frame.get(node.MethodBinding); method != nil {
+			return method, nil
+		}
+		return nil, NewRTError(node.MethodTok.PosStart, node.MethodTok.PosEnd, fmt.Sprintf("Unresolved reference '%s'", node.MethodTok.Value), context)
+	}
+
+	method, exists, _ := context.SymbolTable.Get(node.MethodTok.Value.(string))
+	if !exists {
+		return nil, NewRTError(node.MethodTok.PosStart, node.MethodTok.PosEnd, fmt.Sprintf("Unresolved reference '%s'", node.MethodTok.Value), context)
+	}
+	return method, nil
+}
+
 // visitVarAccessNode visits a VarAccessNode and retrieves its value from the symbol table.
 func (i *Interpreter) visitVarAccessNode(node VarAccessNode, context *Context) *RTResult {
 	res := NewRTResult()
+	value, err := lookupVariable(&node, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	return res.Success(value)
+}
+
+// lookupVariable retrieves the value of the variable named by node from the symbol table.
+func lookupVariable(node *VarAccessNode, context *Context) (*Value, *RuntimeError) {
 	varName := node.VarNameTok.Value
 
+	if node.Binding != nil {
+		// a local variable is unset until its declaration ran
+		if value := context.Frame.get(node.Binding); value != nil {
+			return value, nil
+		}
+		return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Unresolved reference '%s'", varName), context)
+	}
+
 	value, exists, _ := context.SymbolTable.Get(varName.(string))
 	if !exists {
-		if _, exists := context.SymbolTable.packages[varName.(string)]; exists {
+		if context.SymbolTable.HasPackage(varName.(string)) {
 			// TODO error for package with dot but no func -> parser917
-			return res.Failure(NewRTError(
+			return nil, NewRTError(
 				node.PosStart(), node.PosEnd(),
 				fmt.Sprintf("Use of the package '%s' without a selector", varName),
-				context))
+				context)
 		}
-		return res.Failure(NewRTError(
+		return nil, NewRTError(
 			node.PosStart(), node.PosEnd(),
 			fmt.Sprintf("Unresolved reference '%s'", varName),
-			context))
+			context)
 	}
 
-	// value.SetPos(value.GetPosStart(), value.GetPosEnd()).SetContext(context)
-	value.SetContext(context)
+	// the stored value is not updated with the current context, it may be read by other tasks at the same time
 
-	return res.Success(value)
+	return value, nil
 }
 
 // visitVarAssignNode visits a VarAssignNode and assigns a value to the variable in the symbol table.
 func (i *Interpreter) visitVarAssignNode(node VarAssignNode, context *Context) *RTResult {
 	res := NewRTResult()
-	varName := node.VarNameTok.Value
 	var value *Value
 
-	if context.SymbolTable.Contains(varName.(string)) && node.declaration && GlobalSymbolTable == context.SymbolTable {
-		return res.Failure(NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Variable '%s' redeclared in scope", varName), context))
-	} else if !node.declaration && !context.SymbolTable.Contains(varName.(string)) {
-		return res.Failure(NewRTError(
-			node.PosStart(), node.PosEnd(),
-			fmt.Sprintf("Unresolved reference '%s'", varName),
-			context))
+	if err := checkAssignment(&node, context); err != nil {
+		return res.Failure(err)
 	}
 	if node.ValueNode != nil {
 		value = res.Register(i.visit(node.ValueNode, context))
@@ -466,17 +622,86 @@ func (i *Interpreter) visitVarAssignNode(node VarAssignNode, context *Context) *
 	if res.ShouldReturn() {
 		return res
 	}
+	if err := assignVariable(&node, value, context); err != nil {
+		return res.Failure(err)
+	}
 
-	if val, exists, _ := context.SymbolTable.Get(varName.(string)); exists && val.Type() == "Pointer" {
-		assignToPointer(val.Pointer, value, memory)
-	} else {
-		err := context.SymbolTable.Set(varName.(string), value, node.isConst)
-		if err != nil {
-			return res.Failure(err)
+	return res.Success(NewEmptyValue())
+}
+
+// checkAssignment reports whether the variable of node may be assigned, it runs before the value is evaluated.
+func checkAssignment(node *VarAssignNode, context *Context) *RuntimeError {
+	varName := node.VarNameTok.Value
+
+	// constants and redeclarations of local variables are checked by the Resolver
+	if node.Binding != nil {
+		if !node.declaration && context.Frame.get(node.Binding) == nil {
+			return NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Unresolved reference '%s'", varName), context)
 		}
+		return nil
 	}
 
-	return res.Success(NewEmptyValue())
+	if context.SymbolTable.Contains(varName.(string)) && node.declaration && GlobalSymbolTable == context.SymbolTable {
+		return NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Variable '%s' redeclared in scope", varName), context)
+	} else if !node.declaration && !context.SymbolTable.Contains(varName.(string)) {
+		return NewRTError(
+			node.PosStart(), node.PosEnd(),
+			fmt.Sprintf("Unresolved reference '%s'", varName),
+			context)
+	}
+	if _, exists, isConst := context.SymbolTable.Get(varName.(string)); exists && isConst && !node.declaration {
+		return NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot reassign constant '%v'", varName), context)
+	}
+	return nil
+}
+
+// assignVariable stores the evaluated value in the variable of node.
+func assignVariable(node *VarAssignNode, value *Value, context *Context) *RuntimeError {
+	varName := node.VarNameTok.Value
+
+	// constants are deeply immutable, mutable values stay untouched for other variables referring to them
+	if node.isConst {
+		value = value.Freeze()
+	}
+	value = value.Share()
+	if node.Type != nil && node.ValueNode != nil && !node.Type.Matches(value) {
+		return NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot assign %s to variable '%s' of type %s", value.Type(), varName, node.Type), context)
+	}
+
+	if node.Binding != nil {
+		if val := context.Frame.get(node.Binding); val != nil && val.Type() == "Pointer" {
+			assignToPointer(val.Pointer(), value, memory)
+		} else {
+			context.Frame.set(node.Binding, value)
+		}
+		return nil
+	}
+	if val, exists, _ := context.SymbolTable.Get(varName.(string)); exists && val.Type() == "Pointer" {
+		assignToPointer(val.Pointer(), value, memory)
+		return nil
+	}
+	if err := context.SymbolTable.Set(varName.(string), value, node.isConst); err != nil {
+		err.PosStart, err.PosEnd, err.Context = node.PosStart(), node.PosEnd(), context
+		return err
+	}
+	return nil
+}
+
+// setVariable sets a loop variable or function name, either in the slot found by the Resolver or by name.
+func setVariable(name string, binding *Binding, value *Value, context *Context) {
+	if binding != nil {
+		context.Frame.set(binding, value)
+		return
+	}
+	context.SymbolTable.Set(name, value, false)
+}
+
+// isTrue returns the value of a condition, conditions that are not booleans are an error at the condition.
+func isTrue(condition *Value, node Node, context *Context) (bool, *RuntimeError) {
+	if condition.Boolean() == nil {
+		return false, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Condition must be a Boolean, got %s", condition.Type()), context)
+	}
+	return condition.Boolean().IsTrue(), nil
 }
 
 func (i *Interpreter) visitIfNode(node IfNode, context *Context) *RTResult {
@@ -489,8 +714,11 @@ func (i *Interpreter) visitIfNode(node IfNode, context *Context) *RTResult {
 			return res
 		}
 
-		conditionValue := value.Boolean
-		if conditionValue.IsTrue() {
+		conditionValue, err := isTrue(value, ifcase.Condition, context)
+		if err != nil {
+			return res.Failure(err)
+		}
+		if conditionValue {
 			exprValue := res.Register(i.visit(ifcase.Expr, context))
 			if res.ShouldReturn() {
 				return res
@@ -530,47 +758,34 @@ func (i *Interpreter) visitForNode(node ForNode, context *Context) *RTResult {
 		return res
 	}
 
-	var stepValue *Number
+	var step *Value
 	if node.StepValueNode != nil {
-		stepValue = res.Register(i.visit(node.StepValueNode, context)).Number
+		step = res.Register(i.visit(node.StepValueNode, context))
 		if res.ShouldReturn() {
 			return res
 		}
-	} else {
-		stepValue = NewNumber(1).Number
 	}
 
-	var iVal int
-	var endValue int
-
-	if IsInt(start.Number.ValueField) {
-		iVal = start.Number.ValueField.(int)
-	} else {
-		startNum := start.Number
-		return res.Failure(NewRTError(startNum.PosStart(), startNum.PosEnd(), fmt.Sprintf("Can not use type %s as type int", reflect.TypeOf(startNum.ValueField)), startNum.Context))
-	}
-	if IsInt(end.Number.ValueField) {
-		endValue = end.Number.ValueField.(int)
-	} else {
-		endNum := end.Number
-		return res.Failure(NewRTError(endNum.PosStart(), endNum.PosEnd(), fmt.Sprintf("Can not use type %s as type int", reflect.TypeOf(endNum.ValueField)), endNum.Context))
+	iVal, endValue, stepValue, err := forRange(&node, start, end, step, context)
+	if err != nil {
+		return res.Failure(err)
 	}
 
 	var condition func() bool
-	if stepValue.ValueField.(int) >= 0 {
+	if stepValue >= 0 {
 		condition = func() bool { return iVal < endValue }
 	} else {
 		condition = func() bool { return iVal > endValue }
 	}
 
 	for condition() {
-		context.SymbolTable.Set(node.VarNameTok.Value.(string), NewNumber(iVal), false)
+		setVariable(node.VarNameTok.Value.(string), node.VarBinding, NewInt(iVal), context)
 
-		iVal += stepValue.ValueField.(int)
+		iVal += stepValue
 
 		value := res.Register(i.visit(node.BodyNode, context))
 
-		if res.ShouldReturn() && res.LoopShouldContinue == false && res.LoopShouldBreak == false {
+		if res.ShouldReturn() && !res.ControlsLoop(labelName(node.LabelTok)) {
 			return res
 		}
 
@@ -581,13 +796,38 @@ func (i *Interpreter) visitForNode(node ForNode, context *Context) *RTResult {
 			break
 		}
 
-		elements = append(elements, value)
+		if !node.Flag {
+			elements = append(elements, value)
+		}
 	}
 
 	if node.Flag {
 		return res.Success(NewEmptyValue())
 	}
-	return res.Success(NewArray(elements).SetContext(context).SetPos(node.PosStart(), node.PosEnd()))
+	return res.Success(NewArray(elements))
+}
+
+// forRange returns the bounds and the step of a for loop, all of them have to be integers. step is nil if the loop
+// has none, it steps by one then.
+func forRange(node *ForNode, start, end, step *Value, context *Context) (int, int, int, *RuntimeError) {
+	values := [3]int{0, 0, 1}
+	for idx, value := range []*Value{start, end, step} {
+		if value == nil {
+			continue
+		}
+		if number := value.Number(); number != nil && number.IsInt() {
+			values[idx] = number.Int
+			continue
+		}
+
+		valueNode := []Node{node.StartValueNode, node.EndValueNode, node.StepValueNode}[idx]
+		typeName := value.Type()
+		if number := value.Number(); number != nil {
+			typeName = fmt.Sprintf("%T", number.Value())
+		}
+		return 0, 0, 0, NewRTError(valueNode.PosStart(), valueNode.PosEnd(), fmt.Sprintf("Can not use type %s as type int", typeName), context)
+	}
+	return values[0], values[1], values[2], nil
 }
 
 func (i *Interpreter) visitWhileNode(node WhileNode, context *Context) *RTResult {
@@ -600,13 +840,17 @@ func (i *Interpreter) visitWhileNode(node WhileNode, context *Context) *RTResult
 			return res
 		}
 
-		if !condition.Boolean.IsTrue() {
+		conditionValue, err := isTrue(condition, node.ConditionNode, context)
+		if err != nil {
+			return res.Failure(err)
+		}
+		if !conditionValue {
 			break
 		}
 
 		value := res.Register(i.visit(node.BodyNode, context))
 
-		if res.ShouldReturn() && res.LoopShouldContinue == false && res.LoopShouldBreak == false {
+		if res.ShouldReturn() && !res.ControlsLoop(labelName(node.LabelTok)) {
 			return res
 		}
 
@@ -617,13 +861,15 @@ func (i *Interpreter) visitWhileNode(node WhileNode, context *Context) *RTResult
 			break
 		}
 
-		elements = append(elements, value)
+		if !node.Flag {
+			elements = append(elements, value)
+		}
 	}
 
 	if node.Flag {
 		return res.Success(NewEmptyValue())
 	}
-	return res.Success(NewArray(elements).SetContext(context).SetPos(node.PosStart(), node.PosEnd()))
+	return res.Success(NewArray(elements))
 }
 
 func (i *Interpreter) visitFuncDefNode(node FuncDefNode, context *Context) *RTResult {
@@ -637,19 +883,54 @@ func (i *Interpreter) visitFuncDefNode(node FuncDefNode, context *Context) *RTRe
 		funcName = nil
 	}
 
+	value := makeFunction(&node, funcName, context)
+
+	// decorators replace the function before its name is bound, the one closest to 'func' is applied first
+	for idx := len(node.Decorators) - 1; idx >= 0; idx-- {
+		decoratorNode := node.Decorators[idx]
+		decorator := res.Register(i.visit(decoratorNode, context))
+		if res.ShouldReturn() {
+			return res
+		}
+		valueToCall, err := positionCall(decorator, decoratorNode, context)
+		if err != nil {
+			return res.Failure(err)
+		}
+		decorated := res.Register(i.callValue(valueToCall, []*Value{value}))
+		if res.ShouldReturn() {
+			return res
+		}
+		if _, _, ok := callableBase(decorated); !ok {
+			return res.Failure(NewRTError(decoratorNode.PosStart(), decoratorNode.PosEnd(), fmt.Sprintf("Decorator must return a function, but returned %s", decorated.Type()), context))
+		}
+		value = decorated
+	}
+
+	if node.VarNameTok != nil {
+		setVariable(*funcName, node.Binding, value, context)
+		return res.Success(NewEmptyValue())
+	}
+
+	// anonymous functions are values, e.g. 'var f = func() => 1'
+	return res.Success(value)
+}
+
+// makeFunction creates the function value declared by node in context.
+func makeFunction(node *FuncDefNode, funcName *string, context *Context) *Value {
 	argNames := make([]string, len(node.ArgNameToks))
 	for idx, argName := range node.ArgNameToks {
 		argNames[idx] = argName.Value.(string)
 	}
 
 	value := NewFunction(funcName, &node.BodyNode, argNames, node.Flag)
+	value.Function().Base.ArgTypes = node.ArgTypes
+	value.Function().Base.ReturnType = node.ReturnType
+	value.Function().Base.Definition = node.PosStart()
+	value.Function().Locals = node.Locals
+	value.Function().Closure = context.Frame
+	value.Function().Compiled = node.Compiled
 	value.SetContext(context).SetPos(node.PosStart(), node.PosEnd())
-
-	if node.VarNameTok != nil {
-		context.SymbolTable.Set(*funcName, value, false)
-	}
-
-	return res.Success(NewEmptyValue())
+	return value
 }
 
 func (i *Interpreter) visitCallNode(node CallNode, context *Context) *RTResult {
@@ -660,7 +941,9 @@ func (i *Interpreter) visitCallNode(node CallNode, context *Context) *RTResult {
 	if res.ShouldReturn() {
 		return res
 	}
-	valueToCall := Call.SetPos(node.PosStart(), node.PosEnd()).SetContext(context)
+	if node.Optional && Call.Null() != nil {
+		return res.Success(NewNull())
+	}
 	for _, argNode := range node.ArgNodes {
 		args = append(args, res.Register(i.visit(argNode, context)))
 		if res.Error != nil {
@@ -671,40 +954,149 @@ func (i *Interpreter) visitCallNode(node CallNode, context *Context) *RTResult {
 		}
 	}
 
+	valueToCall, err := positionCall(Call, &node, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	returnValue := res.Register(i.callValue(valueToCall, args))
+	if res.ShouldReturn() {
+		return res
+	}
+	returnValue = returnValue.Copy().SetPos(node.PosStart(), node.PosEnd()).SetContext(context)
+	return res.Success(returnValue)
+}
+
+// callValue executes a callable value with the given arguments.
+func (i *Interpreter) callValue(valueToCall *Value, args []*Value) *RTResult {
+	res := NewRTResult()
+
 	var returnValue *Value
-	if valueToCall.Function != nil {
-		returnValue = res.Register(valueToCall.Function.Execute(args))
-	} else if valueToCall.BuildInFunction != nil {
-		returnValue = res.Register(valueToCall.BuildInFunction.Execute(args...))
-	} else if valueToCall.StdLibFunction != nil {
-		returnValue = res.Register(valueToCall.StdLibFunction.Function(args))
+	if valueToCall.Function() != nil {
+		returnValue = res.Register(valueToCall.Function().Execute(args))
+	} else if valueToCall.BuildInFunction() != nil {
+		returnValue = res.Register(valueToCall.BuildInFunction().Execute(args...))
+	} else if valueToCall.StdLibFunction() != nil {
+		returnValue = res.Register(valueToCall.StdLibFunction().Function(args))
 		if res.Error != nil {
-			res.Error.SetLocation(valueToCall.StdLibFunction.Base)
+			res.Error.SetLocation(valueToCall.StdLibFunction().Base)
 		}
+	} else {
+		// the value has no position, the error is located at the call by the caller
+		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("Value of type %s is not callable", valueToCall.Type()), nil))
 	}
 	if res.ShouldReturn() {
 		return res
 	}
-	returnValue = returnValue.Copy().SetPos(node.PosStart(), node.PosEnd()).SetContext(context)
 	return res.Success(returnValue)
 }
 
-func (i *Interpreter) visitIndexNode(node IndexNode, context *Context) *RTResult {
+// positionCall returns a copy of callee positioned at the call node, the stored function may be called by other tasks
+// at the same time. Values that can not be called are reported at the call.
+func positionCall(callee *Value, node Node, context *Context) (*Value, *RuntimeError) {
+	if callee.base() == nil {
+		return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Value of type %s is not callable", callee.Type()), context)
+	}
+	return callee.Copy().SetPos(node.PosStart(), node.PosEnd()).SetContext(context), nil
+}
+
+// visitSpawnNode evaluates the function and its arguments and runs the call on its own goroutine.
+func (i *Interpreter) visitSpawnNode(node SpawnNode, context *Context) *RTResult {
 	res := NewRTResult()
-	array := i.visit(node.VarAccessNode, context)
-	index := i.visit(node.IndexNode, context)
 
-	if array.Value.Array != nil {
-		value, err := array.Value.Array.GetIndex(index.Value.Number)
+	callee := res.Register(i.visit(node.CallNode.NodeToCall, context))
+	if res.ShouldReturn() {
+		return res
+	}
 
-		res.Failure(err)
-		if err != nil {
+	var args []*Value
+	for _, argNode := range node.CallNode.ArgNodes {
+		arg := res.Register(i.visit(argNode, context))
+		if res.ShouldReturn() {
 			return res
 		}
-		return res.Success(value)
-	} else {
-		return res.Failure(NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Element at index %v could not be retrieved, index is out of bounds with length 0", node.IndexNode.Value), context))
+		args = append(args, arg.Copy())
 	}
+
+	// the task gets its own symbols so that concurrently running functions cannot overwrite each other's variables
+	taskContext := NewContext("<task>", context, node.PosStart())
+	taskContext.SymbolTable = NewIsolatedSymbolTable(context.SymbolTable)
+
+	valueToCall, err := positionCall(callee, &node, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	valueToCall.SetContext(taskContext)
+	task := NewTask(valueToCall.FunctionName())
+	task.SetContext(context).SetPos(node.PosStart(), node.PosEnd())
+
+	go func() {
+		interpreter := NewInterpreter()
+		task.Task().complete(interpreter.callValue(valueToCall, args))
+	}()
+
+	return res.Success(task)
+}
+
+func (i *Interpreter) visitIndexNode(node IndexNode, context *Context) *RTResult {
+	res := NewRTResult()
+	array := res.Register(i.visit(node.Target, context))
+	if res.ShouldReturn() {
+		return res
+	}
+	if node.Optional && array.Null() != nil {
+		return res.Success(NewNull())
+	}
+
+	index := res.Register(i.visit(node.Index, context))
+	if res.ShouldReturn() {
+		return res
+	}
+
+	value, err := indexValue(&node, array, index, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	return res.Success(value)
+}
+
+// indexValue retrieves the element at index from an array, string or byte array.
+func indexValue(node *IndexNode, array *Value, index *Value, context *Context) (*Value, *RuntimeError) {
+	number := index.Number()
+	if number == nil {
+		return nil, NewRTError(node.Index.PosStart(), node.Index.PosEnd(), fmt.Sprintf("Can not use type %s as an index", index.Type()), context)
+	}
+	// arithmetic results are floats, whole numbers can still be used as an index
+	if number.form == floatNumber {
+		if number.Float != float64(int(number.Float)) {
+			return nil, NewRTError(node.Index.PosStart(), node.Index.PosEnd(), fmt.Sprintf("Can not use %v as an index", number.Float), context)
+		}
+		number = NewInt(int(number.Float)).Number()
+	}
+	if !number.IsInt() {
+		return nil, NewRTError(node.Index.PosStart(), node.Index.PosEnd(), fmt.Sprintf("Can not use %v as an index", number.Value()), context)
+	}
+
+	var value *Value
+	var err *RuntimeError
+	switch array.Kind {
+	case KIND_ARRAY:
+		value, err = array.Array().GetIndex(number)
+	case KIND_STRING:
+		value, err = array.String().GetIndex(number)
+	case KIND_BYTE_ARRAY:
+		value, err = array.ByteArray().GetByte(number)
+	default:
+		return nil, NewRTError(node.PosStart(), node.PosEnd(), fmt.Sprintf("Element at index %v could not be retrieved, index is out of bounds with length 0", number.Int), context)
+	}
+	if err != nil {
+		// values have no position, their errors are located at the index
+		if err.PosStart == nil {
+			err.PosStart, err.PosEnd = node.Index.PosStart(), node.Index.PosEnd()
+		}
+		err.Context = context
+		return nil, err
+	}
+	return value, nil
 }
 
 func (i *Interpreter) visitReferenceNode(node ReferenceNode, context *Context) *RTResult {
@@ -721,16 +1113,36 @@ func (i *Interpreter) visitDereferenceNode(node DereferenceNode, context *Contex
 
 	variable := res.Register(i.visit(node.Target, context))
 
-	if variable.Pointer == nil {
+	if variable.Pointer() == nil {
 		return res.Failure(NewRTError(node.PosStart(), node.PosEnd(), "invalid memory address or null pointer dereference", context))
 	}
 
-	return res.Success(dereference(variable.Pointer, memory))
+	return res.Success(dereference(variable.Pointer(), memory))
 }
 
 func (i *Interpreter) visitReturnNode(node ReturnNode, context *Context) *RTResult {
 	res := NewRTResult()
 
+	if node.TailCall {
+		call := node.NodeToReturn.(*CallNode)
+		callee := res.Register(i.visit(call.NodeToCall, context))
+		if res.ShouldReturn() {
+			return res
+		}
+		args := make([]*Value, len(call.ArgNodes))
+		for idx, argNode := range call.ArgNodes {
+			args[idx] = res.Register(i.visit(argNode, context))
+			if res.ShouldReturn() {
+				return res
+			}
+		}
+		valueToCall, err := positionCall(callee, call, context)
+		if err != nil {
+			return res.Failure(err)
+		}
+		return returnCall(valueToCall, args, call, context)
+	}
+
 	var value *Value
 	if node.NodeToReturn != nil {
 		value = res.Register(i.visit(node.NodeToReturn, context))
@@ -743,12 +1155,92 @@ func (i *Interpreter) visitReturnNode(node ReturnNode, context *Context) *RTResu
 	return res.SuccessReturn(value)
 }
 
-func (i *Interpreter) visitContinueNode() *RTResult {
-	return NewRTResult().SuccessContinue()
+// returnCall returns the result of a call in tail position. The call only takes the place of the function if no
+// deferred calls are pending, those have to run after the callee returned, so the call is made as usual then.
+func returnCall(valueToCall *Value, args []*Value, call Node, context *Context) *RTResult {
+	res := NewRTResult()
+	if context.Deferred == nil || len(*context.Deferred) == 0 {
+		return res.SuccessTailCall(valueToCall, args)
+	}
+
+	interpreter := NewInterpreter()
+	value := res.Register(interpreter.callValue(valueToCall, args))
+	if res.ShouldReturn() {
+		return res
+	}
+	return res.SuccessReturn(value.Copy().SetPos(call.PosStart(), call.PosEnd()).SetContext(context))
 }
 
-func (i *Interpreter) visitBreakNode() *RTResult {
-	return NewRTResult().SuccessBreak()
+// visitDeferNode evaluates the function and the arguments of the deferred call, the call runs when the surrounding
+// function exits.
+func (i *Interpreter) visitDeferNode(node DeferNode, context *Context) *RTResult {
+	res := NewRTResult()
+
+	if context.Deferred == nil {
+		return res.Failure(NewRTError(node.PosStart(), node.PosEnd(), "'defer' is only allowed inside of functions", context))
+	}
+
+	var callee *Value
+	var args []*Value
+	var argNodes []Node
+	switch call := node.Expr.(type) {
+	case *CallNode:
+		callee = res.Register(i.visit(call.NodeToCall, context))
+		if res.ShouldReturn() {
+			return res
+		}
+		if call.Optional && callee.Null() != nil {
+			return res.Success(NewEmptyValue())
+		}
+		argNodes = call.ArgNodes
+	case *MethodCallNode:
+		if packageMethod := packageSelector(call, context); packageMethod != nil {
+			// only the member is looked up here, the package function is called when the function exits
+			var member Node = NewVarAccessNode(call.MethodTok)
+			packageMethod.CallNode = &member
+			callee = res.Register(i.visitPackageMethodNode(*packageMethod, context))
+		} else {
+			target := res.Register(i.visit(call.TargetNode, context))
+			if res.ShouldReturn() {
+				return res
+			}
+			if call.Optional && target.Null() != nil {
+				return res.Success(NewEmptyValue())
+			}
+			method, err := lookupMethod(call, target, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			callee, args = method, []*Value{target}
+		}
+		if res.ShouldReturn() {
+			return res
+		}
+		argNodes = call.ArgNodes
+	}
+
+	for _, argNode := range argNodes {
+		arg := res.Register(i.visit(argNode, context))
+		if res.ShouldReturn() {
+			return res
+		}
+		args = append(args, arg.Copy())
+	}
+
+	valueToCall, err := positionCall(callee, node.Expr, context)
+	if err != nil {
+		return res.Failure(err)
+	}
+	*context.Deferred = append(*context.Deferred, DeferredCall{valueToCall, args})
+	return res.Success(NewEmptyValue())
+}
+
+func (i *Interpreter) visitContinueNode(node ContinueNode) *RTResult {
+	return NewRTResult().SuccessContinue(labelName(node.LabelTok))
+}
+
+func (i *Interpreter) visitBreakNode(node BreakNode) *RTResult {
+	return NewRTResult().SuccessBreak(labelName(node.LabelTok))
 }
 
 // NewRTResult creates a new RTResult instance.
@@ -762,6 +1254,8 @@ func (r *RTResult) Register(res *RTResult) *Value {
 	r.FuncReturnValue = res.FuncReturnValue
 	r.LoopShouldContinue = res.LoopShouldContinue
 	r.LoopShouldBreak = res.LoopShouldBreak
+	r.LoopLabel = res.LoopLabel
+	r.TailCall = res.TailCall
 	return res.Value
 }
 
@@ -771,6 +1265,8 @@ func (r *RTResult) Reset() {
 	r.FuncReturnValue = nil
 	r.LoopShouldContinue = false
 	r.LoopShouldBreak = false
+	r.LoopLabel = ""
+	r.TailCall = nil
 }
 
 // Success indicates a successful runtime operation.
@@ -786,15 +1282,24 @@ func (r *RTResult) SuccessReturn(value *Value) *RTResult {
 	return r
 }
 
-func (r *RTResult) SuccessContinue() *RTResult {
+// SuccessTailCall returns from a function by calling callee in its place.
+func (r *RTResult) SuccessTailCall(callee *Value, args []*Value) *RTResult {
+	r.Reset()
+	r.TailCall = &TailCall{callee, args}
+	return r
+}
+
+func (r *RTResult) SuccessContinue(label string) *RTResult {
 	r.Reset()
 	r.LoopShouldContinue = true
+	r.LoopLabel = label
 	return r
 }
 
-func (r *RTResult) SuccessBreak() *RTResult {
+func (r *RTResult) SuccessBreak(label string) *RTResult {
 	r.Reset()
 	r.LoopShouldBreak = true
+	r.LoopLabel = label
 	return r
 }
 
@@ -806,17 +1311,55 @@ func (r *RTResult) Failure(error *RuntimeError) *RTResult {
 }
 
 func (r *RTResult) ShouldReturn() bool {
-	return r.Error != nil || r.FuncReturnValue != nil || r.LoopShouldContinue || r.LoopShouldBreak
+	return r.Error != nil || r.FuncReturnValue != nil || r.TailCall != nil || r.LoopShouldContinue || r.LoopShouldBreak
+}
+
+// ControlsLoop reports whether a break or continue targets the loop with the given label.
+func (r *RTResult) ControlsLoop(label string) bool {
+	if r.Error != nil || r.FuncReturnValue != nil || r.TailCall != nil {
+		return false
+	}
+	return (r.LoopShouldContinue || r.LoopShouldBreak) && (r.LoopLabel == "" || r.LoopLabel == label)
 }
 
 // NewContext creates a new context with the given display name, parent, and parent entry position.
 func NewContext(displayName string, parent *Context, parentEntryPos *Position) *Context {
-	return &Context{
+	context := &Context{
 		DisplayName:    displayName,
 		Parent:         parent,
 		ParentEntryPos: parentEntryPos,
-		SymbolTable:    &SymbolTable{},
+		SymbolTable:    NewSymbolTable(nil),
+	}
+	if parent != nil {
+		context.Depth = parent.Depth + 1
 	}
+	return context
+}
+
+// NewFrame creates the frame of a function call with a slot for each of the names.
+func NewFrame(names []string, parent *Frame) *Frame {
+	return &Frame{Slots: make([]*Value, len(names)), Names: names, Parent: parent}
+}
+
+// frame returns the frame that holds the variable of binding.
+func (f *Frame) frame(binding *Binding) *Frame {
+	for depth := binding.Depth; depth > 0 && f != nil; depth-- {
+		f = f.Parent
+	}
+	return f
+}
+
+// get returns the value in the slot of binding, nil if the variable was not set yet.
+func (f *Frame) get(binding *Binding) *Value {
+	frame := f.frame(binding)
+	if frame == nil || binding.Slot >= len(frame.Slots) {
+		return nil
+	}
+	return frame.Slots[binding.Slot]
+}
+
+func (f *Frame) set(binding *Binding, value *Value) {
+	f.frame(binding).Slots[binding.Slot] = value
 }
 
 // NewSymbolTable creates a new SymbolTable instance.
@@ -829,14 +1372,37 @@ func NewSymbolTable(symboltable *SymbolTable) *SymbolTable {
 			buildIn:   make(map[string]*Value),
 			packages:  make(map[string]*Package),
 			parent:    nil,
+			mu:        &sync.RWMutex{},
 		}
 	} else {
-		return &SymbolTable{parent: symboltable, symbols: symboltable.symbols}
+		return &SymbolTable{parent: symboltable, symbols: symboltable.symbols, mu: symboltable.mu}
 	}
 }
 
+// NewIsolatedSymbolTable creates a SymbolTable with its own storage that still resolves reads through the parent.
+// Tasks use it so that concurrently running functions do not overwrite each other's variables.
+func NewIsolatedSymbolTable(parent *SymbolTable) *SymbolTable {
+	symbolTable := NewSymbolTable(nil)
+	symbolTable.parent = parent
+	return symbolTable
+}
+
 // Get retrieves the value associated with the name from the symbol table. 1 arg Value, 2 exists, 3 isConstant
 func (st *SymbolTable) Get(name string) (*Value, bool, bool) {
+	st.mu.RLock()
+	value, exists, isConst := st.lookup(name)
+	st.mu.RUnlock()
+	if exists {
+		return value, exists, isConst
+	}
+	if st.parent != nil {
+		return st.parent.Get(name)
+	}
+	return nil, false, false
+}
+
+// lookup searches the name in this symbol table only, the caller must hold the lock.
+func (st *SymbolTable) lookup(name string) (*Value, bool, bool) {
 	if value, exists := st.symbols[name]; exists {
 		return value, exists, false
 	}
@@ -846,16 +1412,18 @@ func (st *SymbolTable) Get(name string) (*Value, bool, bool) {
 	if value, exists := st.buildIn[name]; exists {
 		return value, exists, false
 	}
-	if st.parent != nil {
-		return st.parent.Get(name)
-	}
 	return nil, false, false
 }
 
 // GetPackageMethod retrieves the package associated with the name from the symbol table.
 func (st *SymbolTable) GetPackageMethod(packageName string, methodName string) (*Value, bool) {
-	if value, exists := st.packages[packageName].Methods[methodName]; exists {
-		return value, exists
+	st.mu.RLock()
+	pkg := st.packages[packageName]
+	st.mu.RUnlock()
+	if pkg != nil {
+		if value, exists := pkg.Methods[methodName]; exists {
+			return value, exists
+		}
 	}
 	if st.parent != nil {
 		return st.parent.GetPackageMethod(packageName, methodName)
@@ -863,11 +1431,28 @@ func (st *SymbolTable) GetPackageMethod(packageName string, methodName string) (
 	return nil, false
 }
 
+// HasPackage reports whether a package with the given name was imported into the symbol table or its parents.
+func (st *SymbolTable) HasPackage(name string) bool {
+	st.mu.RLock()
+	_, exists := st.packages[name]
+	st.mu.RUnlock()
+	if exists {
+		return true
+	}
+	if st.parent != nil {
+		return st.parent.HasPackage(name)
+	}
+	return false
+}
+
 // Set sets the value associated with the name in the symbol table.
 func (st *SymbolTable) Set(name string, value *Value, isConst bool) *RuntimeError {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	if isConst {
 		if _, exists := st.constants[name]; exists {
-			return NewRTError(value.GetPosStart(), value.GetPosEnd(), fmt.Sprintf("Cannot reassign constant '%v'", name), value.GetContext())
+			// values have no position, the error is located at the assignment by the caller
+			return NewRTError(nil, nil, fmt.Sprintf("Cannot reassign constant '%v'", name), nil)
 		}
 		st.constants[name] = value
 	} else {
@@ -878,18 +1463,24 @@ func (st *SymbolTable) Set(name string, value *Value, isConst bool) *RuntimeErro
 
 // SetBuildIn sets a build-in function in the symbol table.
 func (st *SymbolTable) SetBuildIn(name string, value *Value) *RuntimeError {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	st.buildIn[name] = value
 	return nil
 }
 
 // SetPackage sets a package into the symbol table.
 func (st *SymbolTable) SetPackage(name string, value *Package) *RuntimeError {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	st.packages[name] = value
 	return nil
 }
 
 // Remove removes the entry associated with the name from the symbol table.
 func (st *SymbolTable) Remove(name string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	delete(st.symbols, name)
 }
 
@@ -899,15 +1490,6 @@ func (st *SymbolTable) Contains(name string) bool {
 	return exists
 }
 
-func IsInt(value interface{}) bool {
-	switch value.(type) {
-	case int:
-		return true
-	default:
-		return false
-	}
-}
-
 func NewEmptyValue() *Value {
 	return &Value{}
 }
@@ -0,0 +1,236 @@
+package main
+
+import "testing"
+
+func TestComprehensionScope(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:    "the loop variable is not visible after the comprehension",
+			source:  "var xs = [1, 2, 3]\nvar ys = [x * 2 for x in xs]\nvar out = x",
+			wantErr: "Unresolved reference 'x'",
+		},
+		{
+			name:    "the loop variable is not visible after a comprehension in a function",
+			source:  "func f(xs) {\n\tvar ys = [x * 2 for x in xs]\n\treturn x\n}\nvar out = f([1])",
+			wantErr: "Unresolved reference 'x'",
+		},
+		{
+			name:   "a variable of the same name outside is not changed",
+			source: "var x = 10\nvar ys = [x for x in [1, 2]]\nvar out = str(x) + \",\" + str(len(ys))",
+			want:   "10,2",
+		},
+		{
+			name:   "later clauses and the element see the loop variables",
+			source: "func pairs(n) => [str(a) + str(b) for a in [1, 2] for b in [a, n] if b > a]\nvar out = pairs(3)",
+			want:   `["13", "23"]`,
+		},
+	})
+}
+
+func TestLabeledLoops(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name: "break leaves the labeled loop",
+			source: `var out = ""
+outer: for i = 0 to 3 {
+	for j = 0 to 3 {
+		if j == 2 {
+			break outer
+		}
+		out = out + str(i) + str(j) + " "
+	}
+}`,
+			want: "00 01 ",
+		},
+		{
+			name: "continue goes on with the next iteration of the labeled loop",
+			source: `var out = ""
+outer: for i = 0 to 3 {
+	for j = 0 to 3 {
+		if j == 1 {
+			continue outer
+		}
+		out = out + str(i) + str(j) + " "
+	}
+}`,
+			want: "00 10 20 ",
+		},
+		{
+			name: "labels work on while loops",
+			source: `var out = 0
+var i = 0
+rows: while i < 10 {
+	i = i + 1
+	while true {
+		out = out + 1
+		if i == 3 {
+			break rows
+		}
+		continue rows
+	}
+}`,
+			want: "3",
+		},
+		{
+			name: "a break without a label leaves the innermost loop",
+			source: `var out = 0
+outer: for i = 0 to 3 {
+	for j = 0 to 3 {
+		break
+	}
+	out = out + 1
+}`,
+			want: "3",
+		},
+		{
+			name:    "unknown labels are rejected",
+			source:  "for i = 0 to 3 {\n\tbreak missing\n}",
+			wantErr: "Unknown label 'missing'",
+		},
+		{
+			name:    "labels of enclosing loops can not be reused",
+			source:  "outer: for i = 0 to 3 {\n\touter: for j = 0 to 3 {\n\t\tbreak outer\n\t}\n}",
+			wantErr: "Label 'outer' is already used by an enclosing loop",
+		},
+		{
+			name:    "a function does not see the loops around it",
+			source:  "outer: for i = 0 to 3 {\n\tfunc f() {\n\t\tbreak outer\n\t}\n}",
+			wantErr: "'break' outside of a loop",
+		},
+	})
+}
+
+func TestPipesAndMethodCalls(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "a pipe passes the value as the first argument",
+			source: "func sub(a, b) => a - b\nvar out = 10 |> sub(3)",
+			want:   "7",
+		},
+		{
+			name:   "a pipe into a function value calls it with the value",
+			source: "func double(x) => x * 2\nvar out = 4 |> double |> double",
+			want:   "16",
+		},
+		{
+			name:   "a value is the first argument of a method call",
+			source: `var out = "a,b,c".split(",").join("-")`,
+			want:   "a-b-c",
+		},
+		{
+			name:   "pipes and method calls chain",
+			source: "func add(a, b) => a + b\nvar out = \"1 2 3\".split(\" \") |> len() |> add(1)",
+			want:   "4",
+		},
+		{
+			name:   "functions of the program are methods as well",
+			source: "func twice(s) => s + s\nvar out = \"ab\".twice().len()",
+			want:   "4",
+		},
+		{
+			name:    "a missing method is reported",
+			source:  "var x = 1\nvar out = x.missing()",
+			wantErr: "Unresolved reference 'missing'",
+		},
+		{
+			name:    "split only splits strings",
+			source:  `var out = split(1, ",")`,
+			wantErr: "Can not split Number by String, expected two strings",
+		},
+	})
+}
+
+func TestOptionalChaining(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "optional accesses of null are null",
+			source: "var a = null\nvar out = [a?.len(), a?[0], a?.len().missing()]",
+			want:   "[<null>, <null>, <null>]",
+		},
+		{
+			name:   "optional accesses of values work as usual",
+			source: "var a = [[1, 2]]\nvar out = [a?.len(), a?[0]?[1]]",
+			want:   "[1, 2]",
+		},
+		{
+			name:   "an optional call of null is null",
+			source: "var f = null\nvar g = func() => 1\nvar out = [f?.(), g?.()]",
+			want:   "[<null>, 1]",
+		},
+		{
+			name: "the right side of ?? is only evaluated for null",
+			source: `var calls = 0
+func fallback() {
+	calls = calls + 1
+	return 0
+}
+var out = [null ?? fallback(), 5 ?? fallback(), calls]`,
+			want: "[0, 5, 1]",
+		},
+		{
+			name: "only the chosen branch of a conditional is evaluated",
+			source: `var calls = 0
+func count(x) {
+	calls = calls + 1
+	return x
+}
+var out = [1 < 2 ? count("a") : count("b"), 1 > 2 ? count("a") : count("b"), calls]`,
+			want: `["a", "b", 2]`,
+		},
+		{
+			name:    "conditions must be booleans",
+			source:  "var a = null\nvar out = a ? 1 : 2",
+			wantErr: "Condition must be a Boolean, got Null",
+		},
+		{
+			name:    "if conditions must be booleans",
+			source:  "if 1 {\n\tvar out = 1\n}",
+			wantErr: "Condition must be a Boolean, got Number",
+		},
+		{
+			name:    "while conditions must be booleans",
+			source:  "var s = \"a\"\nwhile s {\n\ts = \"\"\n}",
+			wantErr: "Condition must be a Boolean, got String",
+		},
+	})
+}
+
+func TestImmutability(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:    "constants can not be appended to",
+			source:  "const a = [1, 2]\nappend(a, 3)",
+			wantErr: "Cannot append to a frozen array",
+		},
+		{
+			name:    "nested arrays of constants are frozen as well",
+			source:  "const a = [[1], 2]\nappend(a[0], 3)",
+			wantErr: "Cannot append to a frozen array",
+		},
+		{
+			name:    "constants can not be reassigned",
+			source:  "const a = 1\na = 2",
+			wantErr: "Cannot reassign constant 'a'",
+		},
+		{
+			name:   "a constant does not freeze the array of another variable",
+			source: "var a = [1]\nconst b = a\nappend(a, 2)\nvar out = [len(a), len(b), isFrozen(a), isFrozen(b)]",
+			want:   "[2, 1, false, true]",
+		},
+		{
+			name:   "tuples are frozen arrays",
+			source: "var t = (1, [2])\nvar out = [isFrozen(t), isFrozen(t[1]), len(t)]",
+			want:   "[true, true, 2]",
+		},
+		{
+			name:    "elements of frozen arrays can not be removed",
+			source:  "var t = freeze([1, 2])\npop(t, 0)",
+			wantErr: "Cannot pop from a frozen array",
+		},
+		{
+			name:   "only arrays are mutable",
+			source: "var out = [isFrozen(1), isFrozen(\"a\"), isFrozen([1])]",
+			want:   "[true, true, false]",
+		},
+	})
+}
@@ -1,19 +1,14 @@
 package main
 
+// null is shared by all null values.
+var null = &Null{}
+
 func NewNull() *Value {
-	return &Value{Null: &Null{}}
+	return &Value{Kind: KIND_NULL, ref: null}
 }
 
 func (n *Null) Copy() *Value {
-	return NewNull().SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-}
-
-func (n *Null) PosStart() *Position {
-	return n.PositionStart
-}
-
-func (n *Null) PosEnd() *Position {
-	return n.PositionEnd
+	return NewNull()
 }
 
 func (n *Null) String() string {
@@ -21,24 +16,14 @@ func (n *Null) String() string {
 }
 
 func (n *Null) GetComparisonEq(other *Value) (*Value, *RuntimeError) {
-	if other.Null == n {
-		return NewBoolean(One), nil
-	} else {
-		return NewBoolean(Zero), nil
-	}
+	return NewBoolean(ConvertBoolToInt(other.Null() != nil)), nil
 }
 
 func (n *Null) GetComparisonNe(other *Value) (*Value, *RuntimeError) {
-	if other.Null != n {
-		return NewBoolean(One), nil
-	} else {
-		return NewBoolean(Zero), nil
-	}
+	return NewBoolean(ConvertBoolToInt(other.Null() == nil)), nil
 }
 
+// IllegalOperation returns an error without a position, it is located at the operation by the caller.
 func (n *Null) IllegalOperation(other interface{}) *RuntimeError {
-	if other == nil {
-		other = n
-	}
-	return NewRTError(n.PosStart(), n.PosEnd(), "Illegal operation", n.Context)
+	return NewRTError(nil, nil, "Illegal operation", nil)
 }
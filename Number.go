@@ -2,326 +2,345 @@ package main
 
 import (
 	"math"
-	"reflect"
+	"math/big"
+	"strconv"
 )
 
+const (
+	intNumber numberForm = iota
+	floatNumber
+	exactNumber
+)
+
+// NewNumber creates a number from an int, a float64, a *big.Int or a *Decimal.
 func NewNumber(value interface{}) *Value {
-	return &Value{Number: &Number{ValueField: value}}
+	switch v := value.(type) {
+	case int:
+		return NewInt(v)
+	case float64:
+		return NewFloat(v)
+	}
+	return &Value{Kind: KIND_NUMBER, num: Number{Exact: value, form: exactNumber}}
 }
 
-func (n *Number) IllegalOperation(other interface{}) *RuntimeError {
-	if other == nil {
-		other = n
-	}
-	return NewRTError(n.PosStart(), n.PosEnd(), "Illegal operation", n.Context)
+// NewInt creates an int without boxing it, the fast path of NewNumber.
+func NewInt(value int) *Value {
+	return &Value{Kind: KIND_NUMBER, num: Number{Int: value, form: intNumber}}
 }
 
-func (n *Number) Copy() *Value {
-	return NewNumber(n.ValueField).SetContext(n.Context).SetPos(n.PosStart(), n.PosEnd())
+// NewFloat creates a float without boxing it, the fast path of NewNumber.
+func NewFloat(value float64) *Value {
+	return &Value{Kind: KIND_NUMBER, num: Number{Float: value, form: floatNumber}}
 }
 
-func (n *Number) Type() reflect.Type {
-	return reflect.TypeOf(n)
+// IllegalOperation returns an error without a position, it is located at the operation by the caller.
+func (n *Number) IllegalOperation(other interface{}) *RuntimeError {
+	return NewRTError(nil, nil, "Illegal operation", nil)
 }
 
-func (n *Number) Value() interface{} {
-	return n.ValueField
+func (n *Number) Copy() *Value {
+	return &Value{Kind: KIND_NUMBER, num: *n}
 }
 
-func (n *Number) PosStart() *Position {
-	return n.PositionStart
+// Value returns the number as an int, float64, *big.Int or *Decimal.
+func (n *Number) Value() interface{} {
+	switch n.form {
+	case intNumber:
+		return n.Int
+	case floatNumber:
+		return n.Float
+	}
+	return n.Exact
 }
 
-func (n *Number) PosEnd() *Position {
-	return n.PositionEnd
+// IsInt reports whether the number is an int, big integers are not.
+func (n *Number) IsInt() bool {
+	return n.form == intNumber
 }
 
 // AddedTo performs addition with another number.
 func (n *Number) AddedTo(other *Number) (*Value, *RuntimeError) {
-	if n.ValueField != nil && other.ValueField != nil {
-		switch nVal := n.ValueField.(type) {
-		case int:
-			if otherIsInt, ok := other.ValueField.(int); ok {
-				value := NewNumber(float64(nVal) + float64(otherIsInt))
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			} else if otherIsFloat, ok := other.ValueField.(float64); ok {
-				value := NewNumber(float64(nVal) + otherIsFloat)
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			}
-		case float64:
-			if otherIsInt, ok := other.ValueField.(int); ok {
-				value := NewNumber(nVal + float64(otherIsInt))
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			} else if otherIsFloat, ok := other.ValueField.(float64); ok {
-				value := NewNumber(nVal + otherIsFloat)
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			}
-		}
-	}
-	return nil, n.IllegalOperation(other)
+	return n.arithmetic(other, addInt, (*big.Int).Add, (*Decimal).Add, func(a, b float64) float64 { return a + b })
 }
 
 // SubtractedBy performs subtraction with another number.
 func (n *Number) SubtractedBy(other *Number) (*Value, *RuntimeError) {
-	if n.ValueField != nil && other.ValueField != nil {
-		switch nVal := n.ValueField.(type) {
-		case int:
-			value := NewNumber(nVal - other.ValueField.(int))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewNumber(nVal - other.ValueField.(float64))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		}
-	}
-	return nil, n.IllegalOperation(other)
+	return n.arithmetic(other, subInt, (*big.Int).Sub, (*Decimal).Sub, func(a, b float64) float64 { return a - b })
 }
 
 // MultipliedBy performs multiplication with another number.
 func (n *Number) MultipliedBy(other *Number) (*Value, *RuntimeError) {
-	if n.ValueField != nil && other.ValueField != nil {
-		switch nVal := n.ValueField.(type) {
-		case int:
-			value := NewNumber(nVal * other.ValueField.(int))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewNumber(nVal * other.ValueField.(float64))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		}
-	}
-	return nil, n.IllegalOperation(other)
+	return n.arithmetic(other, mulInt, (*big.Int).Mul, (*Decimal).Mul, func(a, b float64) float64 { return a * b })
 }
 
-// DividedBy performs division with another number.
+// DividedBy performs division with another number, the division of two integers is truncated towards zero.
+// Decimal quotients are rounded to the places of the decimal context.
 func (n *Number) DividedBy(other *Number) (*Value, *RuntimeError) {
-	if n.ValueField != nil && other.ValueField != nil {
-		if other.ValueField == 0 {
-			return nil, NewRTError(other.PosStart(), other.PosEnd(), "Division by zero", other.Context)
-		}
-		switch nVal := n.ValueField.(type) {
-		case int:
-			value := NewNumber(nVal / other.ValueField.(int))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewNumber(nVal / other.ValueField.(float64))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		}
+	if other != nil && isZero(other.Value()) {
+		return nil, NewRTError(nil, nil, "Division by zero", nil)
 	}
-	return nil, n.IllegalOperation(other)
+	return n.arithmetic(other, divInt, (*big.Int).Quo, (*Decimal).Quo, func(a, b float64) float64 { return a / b })
 }
 
+// PowedBy raises the number to the power of another number. Integers raised to a non-negative integer stay exact.
 func (n *Number) PowedBy(other *Number) (*Value, *RuntimeError) {
-	if n.ValueField != nil && other.ValueField != nil {
-		var nVal, otherVal float64
-		switch val := n.ValueField.(type) {
-		case float64:
-			nVal = val
-		case int:
-			nVal = float64(val)
-		default:
-			// Handle unsupported types here
-			return nil, n.IllegalOperation(other)
+	if n == nil || other == nil || !isNumeric(n.Value()) || !isNumeric(other.Value()) {
+		return nil, n.IllegalOperation(other)
+	}
+
+	var result interface{}
+	base, baseIsInt := toBigInt(n.Value())
+	exponent, exponentIsInt := toBigInt(other.Value())
+	if decimal, isDecimal := n.Exact.(*Decimal); isDecimal && other.IsInt() {
+		result = decimal.Pow(other.Int)
+	} else if baseIsInt && exponentIsInt && exponent.Sign() >= 0 {
+		result = normalizeInt(new(big.Int).Exp(base, exponent, nil))
+	} else {
+		result = math.Pow(toFloat(n.Value()), toFloat(other.Value()))
+	}
+	return NewNumber(result), nil
+}
+
+// arithmetic applies an operation to both numbers. Integer results that do not fit into an int are promoted to
+// big integers and big results that fit are demoted again, so every integer has exactly one representation.
+// If one side is a decimal the operation is exact on decimals, otherwise as soon as one side is a float the operation
+// is done on floats.
+func (n *Number) arithmetic(other *Number, intOp func(a, b int) (int, bool), bigOp func(z, a, b *big.Int) *big.Int, decimalOp func(a, b *Decimal) *Decimal, floatOp func(a, b float64) float64) (*Value, *RuntimeError) {
+	if n == nil || other == nil {
+		return nil, n.IllegalOperation(other)
+	}
+	// ints and floats are computed without boxing them
+	if n.form == intNumber && other.form == intNumber {
+		if value, ok := intOp(n.Int, other.Int); ok {
+			return NewInt(value), nil
 		}
+	} else if n.form != exactNumber && other.form != exactNumber {
+		return NewFloat(floatOp(toFloat(n.Value()), toFloat(other.Value()))), nil
+	}
 
-		switch val := other.ValueField.(type) {
-		case float64:
-			otherVal = val
-		case int:
-			otherVal = float64(val)
-		default:
-			// Handle unsupported types here
+	if !isNumeric(n.Value()) || !isNumeric(other.Value()) {
+		return nil, n.IllegalOperation(other)
+	}
+	if isDecimal(n.Value()) || isDecimal(other.Value()) {
+		left, leftOk := toDecimal(n.Value())
+		right, rightOk := toDecimal(other.Value())
+		if !leftOk || !rightOk {
 			return nil, n.IllegalOperation(other)
 		}
-
-		value := NewNumber(math.Pow(nVal, otherVal))
-		NewNumber(math.Pow(nVal, otherVal)).SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-		return value, nil
+		return NewNumber(decimalOp(left, right)), nil
 	}
-	return nil, n.IllegalOperation(other)
+	leftBig, leftIsBig := toBigInt(n.Value())
+	rightBig, rightIsBig := toBigInt(other.Value())
+	if leftIsBig && rightIsBig {
+		return NewNumber(normalizeInt(bigOp(new(big.Int), leftBig, rightBig))), nil
+	}
+	return NewFloat(floatOp(toFloat(n.Value()), toFloat(other.Value()))), nil
 }
 
-func (n *Number) GetComparisonEq(other *Value) (*Value, *RuntimeError) {
-	if other != nil {
-		if other.Number != nil {
-			nVal := toInt(n.ValueField)
-			otherVal := toInt(other.Number.ValueField)
-			value := NewBoolean(ConvertBoolToInt(nVal == otherVal))
-			value.SetContext(n.Context)
-			return value, nil
-		} else if other.String != nil {
-			switch nVal := n.ValueField.(type) {
-			case int:
-				return NewBoolean(ConvertBoolToInt(string(rune(nVal)) == other.String.ValueField)), nil
-			case float64:
-				return NewBoolean(ConvertBoolToInt(string(rune(nVal)) == other.String.ValueField)), nil
-			}
-		}
-	}
-	return nil, n.IllegalOperation(other)
+// addInt, subInt, mulInt and divInt report false instead of returning a wrapped around result.
+func addInt(a, b int) (int, bool) {
+	result := a + b
+	return result, (result > a) == (b > 0)
 }
 
-func (n *Number) GetComparisonNe(other *Number) (*Value, *RuntimeError) {
-	if other != nil {
-		value := NewBoolean(ConvertBoolToInt(n.ValueField != other.ValueField))
-		value.SetContext(n.Context)
-		return value, nil
+func subInt(a, b int) (int, bool) {
+	result := a - b
+	return result, (result < a) == (b > 0)
+}
+
+func mulInt(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
 	}
-	return nil, n.IllegalOperation(other)
+	result := a * b
+	return result, result/b == a && !(a == -1 && b == math.MinInt) && !(b == -1 && a == math.MinInt)
 }
 
-func (n *Number) GetComparisonLt(other *Number) (*Value, *RuntimeError) {
-	if other != nil {
-		switch nVal := n.ValueField.(type) {
-		case int:
-			switch otherVal := other.ValueField.(type) {
-			case int:
-				value := NewBoolean(ConvertBoolToInt(n.ValueField.(int) < otherVal))
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			case float64:
-				value := NewBoolean(ConvertBoolToInt(n.ValueField.(float64) < otherVal))
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			}
-		case float64:
-			switch otherVal := other.ValueField.(type) {
-			case int:
-				value := NewBoolean(ConvertBoolToInt(int(nVal) < otherVal))
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			case float64:
-				value := NewBoolean(ConvertBoolToInt(nVal < otherVal))
-				value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-				return value, nil
-			}
-		}
+func divInt(a, b int) (int, bool) {
+	if a == math.MinInt && b == -1 {
+		return 0, false
 	}
+	return a / b, true
+}
 
-	return nil, n.IllegalOperation(other)
+func isNumeric(value interface{}) bool {
+	switch value.(type) {
+	case int, float64, *big.Int, *Decimal:
+		return true
+	}
+	return false
 }
 
-func (n *Number) GetComparisonGt(other *Number) (*Value, *RuntimeError) {
+func isDecimal(value interface{}) bool {
+	_, ok := value.(*Decimal)
+	return ok
+}
 
-	switch nVal := n.ValueField.(type) {
+func isZero(value interface{}) bool {
+	switch v := value.(type) {
 	case int:
-		switch otherVal := other.ValueField.(type) {
-		case int:
-			value := NewBoolean(ConvertBoolToInt(nVal > otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewBoolean(ConvertBoolToInt(float64(nVal) > otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		}
+		return v == 0
 	case float64:
-		switch otherVal := other.ValueField.(type) {
-		case int:
-			value := NewBoolean(ConvertBoolToInt(int(nVal) > otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewBoolean(ConvertBoolToInt(n.ValueField.(float64) > otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		}
+		return v == 0
+	case *Decimal:
+		return v.Unscaled.Sign() == 0
 	}
-	return nil, n.IllegalOperation(other)
+	// big integers are never zero, small values are always stored as int
+	return false
 }
 
-func (n *Number) GetComparisonLte(other *Number) (*Value, *RuntimeError) {
-	if other == nil {
-		return nil, n.IllegalOperation(other)
+// toBigInt returns integers as big integers, floats are not converted.
+func toBigInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case int:
+		return big.NewInt(int64(v)), true
+	case *big.Int:
+		return v, true
 	}
+	return nil, false
+}
 
-	switch nVal := n.ValueField.(type) {
+func toFloat(value interface{}) float64 {
+	switch v := value.(type) {
 	case int:
-		switch otherVal := other.ValueField.(type) {
-		case int:
-			value := NewBoolean(ConvertBoolToInt(nVal <= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewBoolean(ConvertBoolToInt(float64(nVal) <= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		}
+		return float64(v)
 	case float64:
-		switch otherVal := other.ValueField.(type) {
-		case int:
-			value := NewBoolean(ConvertBoolToInt(n.ValueField.(int) <= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewBoolean(ConvertBoolToInt(n.ValueField.(float64) <= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
+		return v
+	case *big.Int:
+		float, _ := new(big.Float).SetInt(v).Float64()
+		return float
+	case *Decimal:
+		return v.Float64()
+	}
+	return math.NaN()
+}
+
+// parseInteger parses a decimal integer, values that do not fit into an int become big integers.
+func parseInteger(text string) (interface{}, bool) {
+	if value, err := strconv.Atoi(text); err == nil {
+		return value, true
+	}
+	value, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, false
+	}
+	return normalizeInt(value), true
+}
+
+// normalizeInt returns an int if the value fits into one, otherwise the big integer itself.
+func normalizeInt(value *big.Int) interface{} {
+	if value.IsInt64() && value.Int64() >= math.MinInt && value.Int64() <= math.MaxInt {
+		return int(value.Int64())
+	}
+	return value
+}
+
+// compareNumbers returns -1, 0 or +1 depending on whether a is less than, equal to or greater than b.
+// Integers and decimals are compared exactly, even if one of them is a big integer.
+func compareNumbers(a, b interface{}) int {
+	if isDecimal(a) || isDecimal(b) {
+		left, leftOk := toDecimal(a)
+		right, rightOk := toDecimal(b)
+		if leftOk && rightOk {
+			return left.Cmp(right)
 		}
 	}
-	return nil, n.IllegalOperation(other)
+	if left, ok := a.(int); ok {
+		if right, ok := b.(int); ok {
+			switch {
+			case left < right:
+				return -1
+			case left > right:
+				return 1
+			}
+			return 0
+		}
+	}
+	leftBig, leftIsInt := toBigInt(a)
+	rightBig, rightIsInt := toBigInt(b)
+	if leftIsInt && rightIsInt {
+		return leftBig.Cmp(rightBig)
+	}
+	left, right := toFloat(a), toFloat(b)
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
 }
 
-func (n *Number) GetComparisonGte(other *Number) (*Value, *RuntimeError) {
-	if other == nil {
+// compare returns the result of a comparison with another number as a boolean value.
+func (n *Number) compare(other *Number, matches func(order int) bool) (*Value, *RuntimeError) {
+	if n == nil || other == nil {
 		return nil, n.IllegalOperation(other)
 	}
-
-	switch nVal := n.ValueField.(type) {
-	case int:
-		switch otherVal := other.ValueField.(type) {
-		case int:
-			value := NewBoolean(ConvertBoolToInt(n.ValueField.(int) >= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewBoolean(ConvertBoolToInt(float64(nVal) >= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
+	if n.form == intNumber && other.form == intNumber {
+		order := 0
+		if n.Int < other.Int {
+			order = -1
+		} else if n.Int > other.Int {
+			order = 1
 		}
-	case float64:
-		switch otherVal := other.ValueField.(type) {
-		case int:
-			value := NewBoolean(ConvertBoolToInt(n.ValueField.(int) >= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
-		case float64:
-			value := NewBoolean(ConvertBoolToInt(n.ValueField.(float64) >= otherVal))
-			value.SetContext(n.Context).SetPos(n.PositionStart, n.PositionEnd)
-			return value, nil
+		return NewBoolean(ConvertBoolToInt(matches(order))), nil
+	}
+	if !isNumeric(n.Value()) || !isNumeric(other.Value()) {
+		return nil, n.IllegalOperation(other)
+	}
+	return NewBoolean(ConvertBoolToInt(matches(compareNumbers(n.Value(), other.Value())))), nil
+}
+
+func (n *Number) GetComparisonEq(other *Value) (*Value, *RuntimeError) {
+	if other != nil {
+		if other.Number() != nil {
+			return n.compare(other.Number(), func(order int) bool { return order == 0 })
+		} else if other.String() != nil {
+			switch nVal := n.Value().(type) {
+			case int:
+				return NewBoolean(ConvertBoolToInt(string(rune(nVal)) == other.String().ValueField)), nil
+			case float64:
+				return NewBoolean(ConvertBoolToInt(string(rune(nVal)) == other.String().ValueField)), nil
+			}
 		}
 	}
 	return nil, n.IllegalOperation(other)
 }
 
+func (n *Number) GetComparisonNe(other *Number) (*Value, *RuntimeError) {
+	return n.compare(other, func(order int) bool { return order != 0 })
+}
+
+func (n *Number) GetComparisonLt(other *Number) (*Value, *RuntimeError) {
+	return n.compare(other, func(order int) bool { return order < 0 })
+}
+
+func (n *Number) GetComparisonGt(other *Number) (*Value, *RuntimeError) {
+	return n.compare(other, func(order int) bool { return order > 0 })
+}
+
+func (n *Number) GetComparisonLte(other *Number) (*Value, *RuntimeError) {
+	return n.compare(other, func(order int) bool { return order <= 0 })
+}
+
+func (n *Number) GetComparisonGte(other *Number) (*Value, *RuntimeError) {
+	return n.compare(other, func(order int) bool { return order >= 0 })
+}
+
 func (n *Number) AndedBy(other *Number) (*Value, *RuntimeError) {
 	if other != nil {
-		value := NewBoolean(ConvertBoolToInt(n.ValueField != 0 && other.ValueField != 0))
-		value.SetContext(n.Context)
-		return value, nil
+		return NewBoolean(ConvertBoolToInt(n.Value() != 0 && other.Value() != 0)), nil
 	}
 	return nil, n.IllegalOperation(other)
 }
 
 func (n *Number) OredBy(other *Number) (*Value, *RuntimeError) {
 	if other != nil {
-		value := NewBoolean(ConvertBoolToInt(n.ValueField != 0 || other.ValueField != 0))
-		value.SetContext(n.Context)
-		return value, nil
+		return NewBoolean(ConvertBoolToInt(n.Value() != 0 || other.Value() != 0)), nil
 	}
 	return nil, n.IllegalOperation(other)
 }
 
 func (n *Number) Notted() (*Value, *RuntimeError) {
-	value := NewBoolean(ConvertBoolToInt(n.ValueField != 0))
-	value.SetContext(n.Context)
-	return value, nil
+	return NewBoolean(ConvertBoolToInt(n.Value() != 0)), nil
 }
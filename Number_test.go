@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestBigIntegers(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "addition promotes on overflow",
+			source: "var max = 9223372036854775807\nvar out = str(max + 1)",
+			want:   "9223372036854775808",
+		},
+		{
+			name:   "subtraction promotes on overflow",
+			source: "var min = -9223372036854775807\nvar out = str(min - 2)",
+			want:   "-9223372036854775809",
+		},
+		{
+			name:   "multiplication promotes on overflow",
+			source: "var n = 3037000500\nvar out = str(n * n)",
+			want:   "9223372037000250000",
+		},
+		{
+			name:   "powers promote on overflow",
+			source: "var base = 2\nvar out = str(base ^ 100)",
+			want:   "1267650600228229401496703205376",
+		},
+		{
+			name:   "literals larger than int64 are kept exact",
+			source: "var out = str(99999999999999999999999)",
+			want:   "99999999999999999999999",
+		},
+		{
+			name:   "big results that fit again are ordinary integers",
+			source: "var max = 9223372036854775807\nvar big = max + 1\nvar out = [big - 1 == max, str(big - 1)]",
+			want:   `[true, "9223372036854775807"]`,
+		},
+		{
+			name:   "num parses big integers",
+			source: `var out = str(num("123456789012345678901234567890") + 1)`,
+			want:   "123456789012345678901234567891",
+		},
+	})
+}
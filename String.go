@@ -1,18 +1,21 @@
 package main
 
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
 func NewString(value string) *Value {
-	return &Value{String: &String{ValueField: value}}
+	return &Value{Kind: KIND_STRING, ref: &String{ValueField: value}}
 }
 
+// IllegalOperation returns an error without a position, it is located at the operation by the caller.
 func (s *String) IllegalOperation(other interface{}) *RuntimeError {
-	if other == nil {
-		other = s
-	}
-	return NewRTError(s.PosStart(), s.PosEnd(), "Illegal operation", s.Context)
+	return NewRTError(nil, nil, "Illegal operation", nil)
 }
 
 func (s *String) Copy() *Value {
-	return NewString(s.ValueField).SetContext(s.Context).SetPos(s.PositionStart, s.PositionEnd)
+	return &Value{Kind: KIND_STRING, ref: s}
 }
 
 func (s *String) IsTrue() bool {
@@ -23,69 +26,61 @@ func (s *String) Value() string {
 	return s.ValueField
 }
 
-func (s *String) PosStart() *Position {
-	return s.PositionStart
-}
-
-func (s *String) PosEnd() *Position {
-	return s.PositionEnd
-}
-
 // MultipliedBy performs multiplication with another number.
 func (s *String) MultipliedBy(other *Number) (*Value, *RuntimeError) {
-	if ValueField, ok := other.ValueField.(int); ok {
-		if ValueField <= 0 {
-			value := NewString("")
-			value.SetContext(s.Context)
-			return value, nil
+	if other.IsInt() {
+		if other.Int <= 0 {
+			return NewString(""), nil
 		}
 
 		var result string
-		for i := 0; i < ValueField; i++ {
+		for i := 0; i < other.Int; i++ {
 			result += s.ValueField
 		}
-		value := NewString(result)
-		value.SetContext(s.Context)
-		return value, nil
+		return NewString(result), nil
 	}
 	return nil, s.IllegalOperation(other)
 }
 
 // AddedTo performs addition with another number.
 func (s *String) AddedTo(other *String) (*Value, *RuntimeError) {
-	value := NewString(s.ValueField + other.ValueField)
-	value.SetContext(s.Context)
-	return value, nil
+	return NewString(s.ValueField + other.ValueField), nil
 }
 
 func (s *String) GetComparisonEq(other *Value) (*Value, *RuntimeError) {
 	if other != nil {
-		if other.String != nil {
-			sVal := s.ValueField
-			otherVal := other.String.ValueField
-			value := NewBoolean(ConvertBoolToInt(sVal == otherVal))
-			value.SetContext(s.Context)
-			return value, nil
-		} else if other.Number != nil {
-			sVal := s.ValueField
+		if other.String() != nil {
+			return NewBoolean(ConvertBoolToInt(s.ValueField == other.String().ValueField)), nil
+		} else if other.Number() != nil {
 			var otherVal string
-			switch other.Number.ValueField.(type) {
-			case int:
-				otherVal = string(rune(other.Number.ValueField.(int)))
-			case float64:
-				otherVal = string(rune(other.Number.ValueField.(int)))
+			if other.Number().IsInt() {
+				otherVal = string(rune(other.Number().Int))
 			}
-			value := NewBoolean(ConvertBoolToInt(sVal == otherVal))
-			value.SetContext(s.Context)
-			return value, nil
+			return NewBoolean(ConvertBoolToInt(s.ValueField == otherVal)), nil
 		}
 	}
 	return nil, s.IllegalOperation(other)
 }
 
-// Length returns the length of the byte array.
+// Length returns the number of characters (code points) of the string, len of a ByteArray counts bytes.
 func (s *String) Length() *Value {
-	value := NewNumber(float64(len(s.ValueField)))
-	value.SetContext(s.Context)
-	return value
+	return NewInt(utf8.RuneCountInString(s.ValueField))
+}
+
+// GetIndex returns the character at the index, counted in code points. Errors are located at the index by the caller.
+func (s *String) GetIndex(index *Number) (*Value, *RuntimeError) {
+	runes := []rune(s.ValueField)
+	if !index.IsInt() || index.Int < 0 || index.Int >= len(runes) {
+		return nil, NewRTError(nil, nil, fmt.Sprintf("Character at index %v could not be retrieved from string, index is out of bounds", index.Value()), nil)
+	}
+	return NewString(string(runes[index.Int])), nil
+}
+
+// Slice returns the characters from start up to, but not including, end counted in code points.
+func (s *String) Slice(start, end int) (*Value, *RuntimeError) {
+	runes := []rune(s.ValueField)
+	if start < 0 || end > len(runes) || start > end {
+		return nil, NewRTError(nil, nil, fmt.Sprintf("Invalid slice indices %d and %d for a string of length %d", start, end, len(runes)), nil)
+	}
+	return NewString(string(runes[start:end])), nil
 }
@@ -0,0 +1,42 @@
+package main
+
+func NewTask(name string) *Value {
+	return &Value{Kind: KIND_TASK, ref: &Task{Name: name, state: &taskState{done: make(chan struct{})}}}
+}
+
+// Copy creates a copy of the task handle, every copy waits for the same call.
+func (t *Task) Copy() *Value {
+	task := &Task{Name: t.Name, state: t.state}
+	return (&Value{Kind: KIND_TASK, ref: task}).SetContext(t.Context).SetPos(t.PositionStart, t.PositionEnd)
+}
+
+func (t *Task) PosStart() *Position {
+	return t.PositionStart
+}
+
+func (t *Task) PosEnd() *Position {
+	return t.PositionEnd
+}
+
+func (t *Task) String() string {
+	return "<task " + t.Name + ">"
+}
+
+// complete stores the result of the finished call and wakes up every waiting task.
+func (t *Task) complete(result *RTResult) {
+	t.state.result = result.Value
+	t.state.err = result.Error
+	close(t.state.done)
+}
+
+// Await blocks until the task has finished and returns its result.
+func (t *Task) Await() (*Value, *RuntimeError) {
+	<-t.state.done
+	if t.state.err != nil {
+		return nil, t.state.err
+	}
+	if t.state.result == nil || t.state.result.IsEmpty() {
+		return NewNull(), nil
+	}
+	return t.state.result.Copy(), nil
+}
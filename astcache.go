@@ -0,0 +1,611 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// astCacheFormat is written at the start of a cache file, it changes when the encoding of the nodes changes.
+const astCacheFormat = "ECPC2"
+
+// tags of the encoded nodes
+const (
+	astNil byte = iota
+	astNumber
+	astString
+	astBinOp
+	astUnaryOp
+	astVarAccess
+	astVarAssign
+	astIndex
+	astArray
+	astIf
+	astFor
+	astWhile
+	astFuncDef
+	astCall
+	astMethodCall
+	astReturn
+	astBreak
+	astContinue
+	astImport
+	astComprehension
+	astDefer
+	astSpawn
+	astReference
+	astDereference
+)
+
+// tags of the encoded token values
+const (
+	astValueNil byte = iota
+	astValueString
+	astValueInt
+	astValueFloat
+	astValueBigInt
+	astValueDecimal
+)
+
+// interpreterVersion identifies the build of the running interpreter, caches written by other builds are not read.
+var interpreterVersion = sync.OnceValue(func() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+})
+
+// astCachePath returns the cache file for the AST of source, it is keyed by the content and the interpreter version.
+// It returns false if there is no cache directory.
+func astCachePath(source string) (string, bool) {
+	version := interpreterVersion()
+	cacheDir, err := os.UserCacheDir()
+	if err != nil || version == "" {
+		return "", false
+	}
+	hash := sha256.Sum256([]byte(astCacheFormat + "\x00" + version + "\x00" + source))
+	return filepath.Join(cacheDir, "ektoplasma", hex.EncodeToString(hash[:])+".ecpc"), true
+}
+
+// readCachedAST returns the AST of source that was cached by an earlier run, or nil if there is none.
+func readCachedAST(fileName, source string) Node {
+	path, ok := astCachePath(source)
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	node, err := DecodeAST(data, fileName, source)
+	if err != nil {
+		return nil
+	}
+	return node
+}
+
+// writeCachedAST caches the AST of source for later runs, the AST must not be resolved yet. Failures are ignored,
+// the source is parsed again then.
+func writeCachedAST(source string, node Node) {
+	path, ok := astCachePath(source)
+	if !ok {
+		return
+	}
+	data, err := EncodeAST(node)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	// the file is renamed into place so that other runs never read a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// EncodeAST serializes a parsed AST. Positions are stored without their file, it is given again when decoding.
+func EncodeAST(node Node) ([]byte, error) {
+	e := &astEncoder{data: []byte(astCacheFormat)}
+	e.node(node)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.data, nil
+}
+
+// DecodeAST restores an AST encoded by EncodeAST, its positions point into source.
+func DecodeAST(data []byte, fileName, source string) (Node, error) {
+	if len(data) < len(astCacheFormat) || string(data[:len(astCacheFormat)]) != astCacheFormat {
+		return nil, errors.New("not an AST cache")
+	}
+	d := &astDecoder{data: data, offset: len(astCacheFormat), source: &Source{Fn: fileName, Text: source}}
+	node := d.node()
+	if d.err == nil && d.offset != len(d.data) {
+		d.fail()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+func (e *astEncoder) byte(value byte) {
+	e.data = append(e.data, value)
+}
+
+func (e *astEncoder) bool(value bool) {
+	if value {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+}
+
+func (e *astEncoder) int(value int) {
+	e.data = binary.AppendVarint(e.data, int64(value))
+}
+
+func (e *astEncoder) string(value string) {
+	e.int(len(value))
+	e.data = append(e.data, value...)
+}
+
+func (e *astEncoder) position(pos *Position) {
+	e.bool(pos != nil)
+	if pos != nil {
+		e.int(pos.Idx)
+		e.int(pos.Ln)
+		e.int(pos.Col)
+	}
+}
+
+// positions writes the start and the end of a node.
+func (e *astEncoder) positions(posStart, posEnd *Position) {
+	e.position(posStart)
+	e.position(posEnd)
+}
+
+func (e *astEncoder) value(value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		e.byte(astValueNil)
+	case string:
+		e.byte(astValueString)
+		e.string(v)
+	case int:
+		e.byte(astValueInt)
+		e.int(v)
+	case float64:
+		e.byte(astValueFloat)
+		e.data = binary.AppendUvarint(e.data, math.Float64bits(v))
+	case *big.Int:
+		e.byte(astValueBigInt)
+		e.string(v.String())
+	case *Decimal:
+		if v == nil {
+			e.err = errors.New("invalid decimal literal")
+			return
+		}
+		e.byte(astValueDecimal)
+		e.string(v.Unscaled.String())
+		e.int(v.Scale)
+	default:
+		e.err = fmt.Errorf("can not encode a token value of type %T", value)
+	}
+}
+
+func (e *astEncoder) token(tok *Token) {
+	e.bool(tok != nil)
+	if tok != nil {
+		e.string(string(tok.Type))
+		e.value(tok.Value)
+		e.position(tok.PosStart)
+		e.position(tok.PosEnd)
+	}
+}
+
+// tokens writes a list of tokens, a nil list stays nil, e.g. the names of an import that imports the whole package.
+func (e *astEncoder) tokens(toks []*Token) {
+	e.bool(toks != nil)
+	e.int(len(toks))
+	for _, tok := range toks {
+		e.token(tok)
+	}
+}
+
+func (e *astEncoder) typeAnnotation(annotation *TypeAnnotation) {
+	e.bool(annotation != nil)
+	if annotation != nil {
+		e.string(annotation.Name)
+		e.typeAnnotation(annotation.Element)
+		e.position(annotation.PositionStart)
+		e.position(annotation.PositionEnd)
+	}
+}
+
+func (e *astEncoder) nodes(nodes []Node) {
+	e.int(len(nodes))
+	for _, node := range nodes {
+		e.node(node)
+	}
+}
+
+func (e *astEncoder) node(node Node) {
+	switch n := node.(type) {
+	case nil:
+		e.byte(astNil)
+	case *NumberNode:
+		e.byte(astNumber)
+		e.token(n.Tok)
+		e.value(n.Value)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *StringNode:
+		e.byte(astString)
+		e.token(n.Tok)
+		e.value(n.Value)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *BinOpNode:
+		e.byte(astBinOp)
+		e.node(n.LeftNode)
+		e.token(n.OpTok)
+		e.node(n.RightNode)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *UnaryOpNode:
+		e.byte(astUnaryOp)
+		e.token(n.OpTok)
+		e.node(n.Node)
+		e.position(n.Position)
+	case *VarAccessNode:
+		e.byte(astVarAccess)
+		e.token(n.VarNameTok)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *VarAssignNode:
+		e.byte(astVarAssign)
+		e.token(n.VarNameTok)
+		e.node(n.ValueNode)
+		e.bool(n.isConst)
+		e.bool(n.declaration)
+		e.typeAnnotation(n.Type)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *IndexNode:
+		e.byte(astIndex)
+		e.node(n.Target)
+		e.node(n.Index)
+		e.bool(n.Optional)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ArrayNode:
+		e.byte(astArray)
+		e.nodes(n.ElementNodes)
+		e.bool(n.Frozen)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *IfNode:
+		e.byte(astIf)
+		e.int(len(n.Cases))
+		for _, ifCase := range n.Cases {
+			e.node(ifCase.Condition)
+			e.node(ifCase.Expr)
+			e.bool(ifCase.Flag)
+		}
+		e.bool(n.ElseCase != nil)
+		if n.ElseCase != nil {
+			e.node(n.ElseCase.Expr)
+			e.bool(n.ElseCase.Flag)
+		}
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ForNode:
+		e.byte(astFor)
+		e.token(n.LabelTok)
+		e.token(n.VarNameTok)
+		e.node(n.StartValueNode)
+		e.node(n.EndValueNode)
+		e.node(n.StepValueNode)
+		e.node(n.BodyNode)
+		e.bool(n.Flag)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *WhileNode:
+		e.byte(astWhile)
+		e.token(n.LabelTok)
+		e.node(n.ConditionNode)
+		e.node(n.BodyNode)
+		e.bool(n.Flag)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *FuncDefNode:
+		e.byte(astFuncDef)
+		e.token(n.VarNameTok)
+		e.tokens(n.ArgNameToks)
+		e.int(len(n.ArgTypes))
+		for _, argType := range n.ArgTypes {
+			e.typeAnnotation(argType)
+		}
+		e.typeAnnotation(n.ReturnType)
+		e.node(n.BodyNode)
+		e.bool(n.Flag)
+		e.nodes(n.Decorators)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *CallNode:
+		e.byte(astCall)
+		e.node(n.NodeToCall)
+		e.nodes(n.ArgNodes)
+		e.bool(n.Optional)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *MethodCallNode:
+		e.byte(astMethodCall)
+		e.node(n.TargetNode)
+		e.token(n.MethodTok)
+		e.nodes(n.ArgNodes)
+		e.bool(n.IsCall)
+		e.bool(n.Optional)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ReturnNode:
+		e.byte(astReturn)
+		e.node(n.NodeToReturn)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *BreakNode:
+		e.byte(astBreak)
+		e.token(n.LabelTok)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ContinueNode:
+		e.byte(astContinue)
+		e.token(n.LabelTok)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ImportNode:
+		e.byte(astImport)
+		e.tokens(n.ImportNames)
+		e.tokens(n.PackageNames)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ComprehensionNode:
+		e.byte(astComprehension)
+		e.node(n.ElementNode)
+		e.int(len(n.Clauses))
+		for _, clause := range n.Clauses {
+			e.token(clause.VarNameTok)
+			e.node(clause.IterableNode)
+			e.node(clause.ConditionNode)
+		}
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *DeferNode:
+		e.byte(astDefer)
+		e.node(n.Expr)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *SpawnNode:
+		e.byte(astSpawn)
+		e.node(n.CallNode)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *ReferenceNode:
+		e.byte(astReference)
+		e.node(n.Target)
+		e.positions(n.PositionStart, n.PositionEnd)
+	case *DereferenceNode:
+		e.byte(astDereference)
+		e.node(n.Target)
+		e.positions(n.PositionStart, n.PositionEnd)
+	default:
+		e.err = fmt.Errorf("can not encode a node of type %T", node)
+	}
+}
+
+// fail marks the data as invalid, the decoder returns zero values from then on.
+func (d *astDecoder) fail() {
+	if d.err == nil {
+		d.err = errors.New("invalid AST cache")
+	}
+	d.offset = len(d.data)
+}
+
+func (d *astDecoder) byte() byte {
+	if d.offset >= len(d.data) {
+		d.fail()
+		return 0
+	}
+	value := d.data[d.offset]
+	d.offset++
+	return value
+}
+
+func (d *astDecoder) bool() bool {
+	return d.byte() == 1
+}
+
+func (d *astDecoder) int() int {
+	value, n := binary.Varint(d.data[d.offset:])
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.offset += n
+	return int(value)
+}
+
+// length reads the length of a list or a string, it can not be longer than the rest of the data.
+func (d *astDecoder) length() int {
+	length := d.int()
+	if length < 0 || length > len(d.data)-d.offset {
+		d.fail()
+		return 0
+	}
+	return length
+}
+
+func (d *astDecoder) string() string {
+	length := d.length()
+	value := string(d.data[d.offset : d.offset+length])
+	d.offset += length
+	return value
+}
+
+func (d *astDecoder) position() *Position {
+	if !d.bool() {
+		return nil
+	}
+	return &Position{Idx: d.int(), Ln: d.int(), Col: d.int(), Src: d.source}
+}
+
+func (d *astDecoder) value() interface{} {
+	switch d.byte() {
+	case astValueNil:
+		return nil
+	case astValueString:
+		return d.string()
+	case astValueInt:
+		return d.int()
+	case astValueFloat:
+		bits, n := binary.Uvarint(d.data[d.offset:])
+		if n <= 0 {
+			d.fail()
+			return nil
+		}
+		d.offset += n
+		return math.Float64frombits(bits)
+	case astValueBigInt:
+		value, ok := new(big.Int).SetString(d.string(), 10)
+		if !ok {
+			d.fail()
+		}
+		return value
+	case astValueDecimal:
+		unscaled, ok := new(big.Int).SetString(d.string(), 10)
+		if !ok {
+			d.fail()
+		}
+		return &Decimal{Unscaled: unscaled, Scale: d.int()}
+	}
+	d.fail()
+	return nil
+}
+
+func (d *astDecoder) token() *Token {
+	if !d.bool() {
+		return nil
+	}
+	return &Token{Type: TokenTypes(d.string()), Value: d.value(), PosStart: d.position(), PosEnd: d.position()}
+}
+
+func (d *astDecoder) tokens() []*Token {
+	if !d.bool() {
+		d.length()
+		return nil
+	}
+	toks := make([]*Token, d.length())
+	for idx := range toks {
+		toks[idx] = d.token()
+	}
+	return toks
+}
+
+func (d *astDecoder) typeAnnotation() *TypeAnnotation {
+	if !d.bool() {
+		return nil
+	}
+	return &TypeAnnotation{Name: d.string(), Element: d.typeAnnotation(), PositionStart: d.position(), PositionEnd: d.position()}
+}
+
+func (d *astDecoder) nodes() []Node {
+	nodes := make([]Node, d.length())
+	for idx := range nodes {
+		nodes[idx] = d.node()
+	}
+	return nodes
+}
+
+// node reads a node, struct fields are evaluated in the order they are written.
+func (d *astDecoder) node() Node {
+	switch d.byte() {
+	case astNil:
+		return nil
+	case astNumber:
+		return &NumberNode{Tok: d.token(), Value: d.value(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astString:
+		return &StringNode{Tok: d.token(), Value: d.value(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astBinOp:
+		return &BinOpNode{LeftNode: d.node(), OpTok: d.token(), RightNode: d.node(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astUnaryOp:
+		return &UnaryOpNode{OpTok: d.token(), Node: d.node(), Position: d.position()}
+	case astVarAccess:
+		return &VarAccessNode{VarNameTok: d.token(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astVarAssign:
+		return &VarAssignNode{VarNameTok: d.token(), ValueNode: d.node(), isConst: d.bool(), declaration: d.bool(), Type: d.typeAnnotation(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astIndex:
+		return &IndexNode{Target: d.node(), Index: d.node(), Optional: d.bool(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astArray:
+		return &ArrayNode{ElementNodes: d.nodes(), Frozen: d.bool(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astIf:
+		node := &IfNode{Cases: make([]*IfCaseNode, d.length())}
+		for idx := range node.Cases {
+			node.Cases[idx] = &IfCaseNode{Condition: d.node(), Expr: d.node(), Flag: d.bool()}
+		}
+		if d.bool() {
+			node.ElseCase = &ElseCaseNode{Expr: d.node(), Flag: d.bool()}
+		}
+		node.PositionStart, node.PositionEnd = d.position(), d.position()
+		return node
+	case astFor:
+		return &ForNode{LabelTok: d.token(), VarNameTok: d.token(), StartValueNode: d.node(), EndValueNode: d.node(), StepValueNode: d.node(), BodyNode: d.node(), Flag: d.bool(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astWhile:
+		return &WhileNode{LabelTok: d.token(), ConditionNode: d.node(), BodyNode: d.node(), Flag: d.bool(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astFuncDef:
+		node := &FuncDefNode{VarNameTok: d.token(), ArgNameToks: d.tokens(), ArgTypes: make([]*TypeAnnotation, d.length()), Compiled: &CompiledBody{}}
+		for idx := range node.ArgTypes {
+			node.ArgTypes[idx] = d.typeAnnotation()
+		}
+		node.ReturnType = d.typeAnnotation()
+		node.BodyNode = d.node()
+		node.Flag = d.bool()
+		node.Decorators = d.nodes()
+		node.PositionStart, node.PositionEnd = d.position(), d.position()
+		return node
+	case astCall:
+		return &CallNode{NodeToCall: d.node(), ArgNodes: d.nodes(), Optional: d.bool(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astMethodCall:
+		return &MethodCallNode{TargetNode: d.node(), MethodTok: d.token(), ArgNodes: d.nodes(), IsCall: d.bool(), Optional: d.bool(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astReturn:
+		return &ReturnNode{NodeToReturn: d.node(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astBreak:
+		return &BreakNode{LabelTok: d.token(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astContinue:
+		return &ContinueNode{LabelTok: d.token(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astImport:
+		return &ImportNode{ImportNames: d.tokens(), PackageNames: d.tokens(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astComprehension:
+		node := &ComprehensionNode{ElementNode: d.node(), Clauses: make([]*ComprehensionClause, d.length())}
+		for idx := range node.Clauses {
+			node.Clauses[idx] = &ComprehensionClause{VarNameTok: d.token(), IterableNode: d.node(), ConditionNode: d.node()}
+		}
+		node.PositionStart, node.PositionEnd = d.position(), d.position()
+		return node
+	case astDefer:
+		return &DeferNode{Expr: d.node(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astSpawn:
+		call, ok := d.node().(*CallNode)
+		if !ok {
+			d.fail()
+		}
+		return &SpawnNode{CallNode: call, PositionStart: d.position(), PositionEnd: d.position()}
+	case astReference:
+		return &ReferenceNode{Target: d.node(), PositionStart: d.position(), PositionEnd: d.position()}
+	case astDereference:
+		return &DereferenceNode{Target: d.node(), PositionStart: d.position(), PositionEnd: d.position()}
+	}
+	d.fail()
+	return nil
+}
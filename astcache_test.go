@@ -0,0 +1,59 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+// addresses matches the pointers printed by the String methods of nodes.
+var addresses = regexp.MustCompile(`0x[0-9a-f]+`)
+
+func TestASTCacheRoundTrip(t *testing.T) {
+	sources := []string{
+		"var a: Array<Number> = [1, 2.5, 3d]",
+		"const name = \"text\"\nname = name + \"!\"",
+		"func add(a: Number, b) -> Number => a + b\nadd(1, 2) |> str",
+		"@memoize\nfunc f(n) {\n\tdefer println(n)\n\tif n < 2 {\n\t\treturn n\n\telif n == 2 {\n\t\treturn 1\n\telse\n\t\treturn f(n - 1) + f(n - 2)\n\t}\n}",
+		"outer: for i = 0 to 10 step 2 {\n\twhile true {\n\t\tbreak outer\n\t}\n\tcontinue\n}",
+		"var xs = [x * y for x in [1, 2] for y in [3] if x > 1]\nxs?[0]?.len() ?? -1",
+		"var t = (1, \"a\",)\nvar p = &t\n*p",
+		"import \"math\"\nimport sqrt, pow from \"math\"\nvar task = spawn sqrt(4)",
+		"var c = 1 == 1 ? not false : 2 ^ 3 ^ 2",
+	}
+
+	for _, source := range sources {
+		ast := NewLexerParser(NewLexer("<test>", source)).Parse()
+		if ast.Error != nil {
+			t.Fatalf("syntax error in %q: %s", source, ast.Error.Details)
+		}
+		data, err := EncodeAST(ast.Node)
+		if err != nil {
+			t.Fatalf("encoding %q: %v", source, err)
+		}
+		decoded, err := DecodeAST(data, "<test>", source)
+		if err != nil {
+			t.Fatalf("decoding %q: %v", source, err)
+		}
+		got, want := addresses.ReplaceAllString(decoded.String(), ""), addresses.ReplaceAllString(ast.Node.String(), "")
+		if got != want {
+			t.Errorf("decoded AST of %q is\n%s\nwant\n%s", source, got, want)
+		}
+		if got, want := *decoded.PosEnd(), *ast.Node.PosEnd(); got.Idx != want.Idx || got.Ln != want.Ln {
+			t.Errorf("decoded AST of %q ends at %d:%d, want %d:%d", source, got.Ln, got.Idx, want.Ln, want.Idx)
+		}
+
+		// imports of a whole package have no names, which the String output does not show
+		for idx, statement := range ast.Node.(*ArrayNode).ElementNodes {
+			if importNode, ok := statement.(*ImportNode); ok {
+				decodedImport := decoded.(*ArrayNode).ElementNodes[idx].(*ImportNode)
+				if (importNode.ImportNames == nil) != (decodedImport.ImportNames == nil) {
+					t.Errorf("decoded import %d of %q: nil names = %t, want %t", idx, source, decodedImport.ImportNames == nil, importNode.ImportNames == nil)
+				}
+			}
+		}
+
+		if _, err := DecodeAST(data[:len(data)-1], "<test>", source); err == nil {
+			t.Errorf("decoding a truncated AST of %q did not fail", source)
+		}
+	}
+}
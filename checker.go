@@ -0,0 +1,371 @@
+package main
+
+import "fmt"
+
+func (t *TypeAnnotation) PosStart() *Position {
+	return t.PositionStart
+}
+
+func (t *TypeAnnotation) PosEnd() *Position {
+	return t.PositionEnd
+}
+
+func (t *TypeAnnotation) String() string {
+	if t == nil {
+		return "<unknown>"
+	}
+	if t.Element != nil {
+		return fmt.Sprintf("%s<%s>", t.Name, t.Element)
+	}
+	return t.Name
+}
+
+// Matches reports whether a runtime value fits the annotated type, the elements of arrays are checked as well.
+func (t *TypeAnnotation) Matches(value *Value) bool {
+	switch t.Name {
+	case "Any":
+		return true
+	case "Function":
+		return value.Function() != nil || value.BuildInFunction() != nil || value.StdLibFunction() != nil
+	case "Array":
+		if value.Array() == nil {
+			return false
+		}
+		if t.Element != nil {
+			for _, element := range value.Array().Elements {
+				if !t.Element.Matches(element) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return value.Type() == t.Name
+}
+
+// accepts reports whether a statically known type can be used where t is expected, nil stands for an unknown type.
+func (t *TypeAnnotation) accepts(other *TypeAnnotation) bool {
+	if t == nil || other == nil || t.Name == "Any" || other.Name == "Any" {
+		return true
+	}
+	if t.Name != other.Name {
+		return false
+	}
+	return t.Element.accepts(other.Element)
+}
+
+func NewTypeChecker() *TypeChecker {
+	return &TypeChecker{scopes: []*checkerScope{newCheckerScope()}}
+}
+
+func newCheckerScope() *checkerScope {
+	return &checkerScope{vars: map[string]*TypeAnnotation{}, funcs: map[string]*FuncDefNode{}}
+}
+
+// Check walks the whole tree and returns the collected type errors.
+func (c *TypeChecker) Check(node Node) []*TypeError {
+	c.walk(node)
+	return c.Errors
+}
+
+func (c *TypeChecker) report(node Node, details string) {
+	c.Errors = append(c.Errors, NewTypeError(node.PosStart(), node.PosEnd(), details))
+}
+
+func (c *TypeChecker) pushScope() {
+	c.scopes = append(c.scopes, newCheckerScope())
+}
+
+func (c *TypeChecker) popScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// lookupVar returns the annotated type of a variable, untyped variables may hold any value and return nil.
+func (c *TypeChecker) lookupVar(name string) *TypeAnnotation {
+	for idx := len(c.scopes) - 1; idx >= 0; idx-- {
+		if varType, exists := c.scopes[idx].vars[name]; exists {
+			return varType
+		}
+	}
+	return nil
+}
+
+func (c *TypeChecker) lookupFunc(name string) *FuncDefNode {
+	for idx := len(c.scopes) - 1; idx >= 0; idx-- {
+		if _, shadowed := c.scopes[idx].vars[name]; shadowed {
+			return nil
+		}
+		if funcDef, exists := c.scopes[idx].funcs[name]; exists {
+			return funcDef
+		}
+	}
+	return nil
+}
+
+func (c *TypeChecker) walk(node Node) {
+	switch n := node.(type) {
+	case *ArrayNode:
+		for _, element := range n.ElementNodes {
+			c.walk(element)
+		}
+	case *VarAssignNode:
+		c.checkVarAssign(n)
+	case *FuncDefNode:
+		for _, decorator := range n.Decorators {
+			c.walk(decorator)
+		}
+		c.checkFuncDef(n)
+	case *ReturnNode:
+		if n.NodeToReturn != nil {
+			c.walk(n.NodeToReturn)
+		}
+		if len(c.returnTypes) > 0 {
+			c.checkReturn(c.returnTypes[len(c.returnTypes)-1], n.NodeToReturn, n)
+		}
+	case *CallNode:
+		c.checkCall(n)
+	case *BinOpNode:
+		c.walk(n.LeftNode)
+		c.walk(n.RightNode)
+	case *UnaryOpNode:
+		c.walk(n.Node)
+	case *IfNode:
+		for _, ifCase := range n.Cases {
+			c.walk(ifCase.Condition)
+			c.walk(ifCase.Expr)
+		}
+		if n.ElseCase != nil {
+			c.walk(n.ElseCase.Expr)
+		}
+	case *ForNode:
+		c.walk(n.StartValueNode)
+		c.walk(n.EndValueNode)
+		if n.StepValueNode != nil {
+			c.walk(n.StepValueNode)
+		}
+		c.walk(n.BodyNode)
+	case *WhileNode:
+		c.walk(n.ConditionNode)
+		c.walk(n.BodyNode)
+	case *IndexNode:
+		c.walk(n.Target)
+		c.walk(n.Index)
+	case *MethodCallNode:
+		c.walk(n.TargetNode)
+		for _, arg := range n.ArgNodes {
+			c.walk(arg)
+		}
+	case *ComprehensionNode:
+		for _, clause := range n.Clauses {
+			c.walk(clause.IterableNode)
+			if clause.ConditionNode != nil {
+				c.walk(clause.ConditionNode)
+			}
+		}
+		c.walk(n.ElementNode)
+	case *SpawnNode:
+		c.checkCall(n.CallNode)
+	case *DeferNode:
+		c.walk(n.Expr)
+	case *ReferenceNode:
+		c.walk(n.Target)
+	case *DereferenceNode:
+		c.walk(n.Target)
+	}
+}
+
+func (c *TypeChecker) checkVarAssign(node *VarAssignNode) {
+	name := node.VarNameTok.Value.(string)
+	if node.ValueNode != nil {
+		c.walk(node.ValueNode)
+	}
+
+	varType := node.Type
+	if !node.declaration {
+		varType = c.lookupVar(name)
+	}
+	if varType != nil && node.ValueNode != nil {
+		if valueType, ok := c.fits(varType, node.ValueNode); !ok {
+			c.report(node.ValueNode, fmt.Sprintf("Cannot assign %s to variable '%s' of type %s", valueType, name, varType))
+		}
+	}
+
+	if node.declaration {
+		scope := c.scopes[len(c.scopes)-1]
+		scope.vars[name] = node.Type
+		delete(scope.funcs, name)
+	}
+}
+
+func (c *TypeChecker) checkFuncDef(node *FuncDefNode) {
+	// named functions are declared before the body is checked so that recursive calls are known
+	if node.VarNameTok != nil {
+		scope := c.scopes[len(c.scopes)-1]
+		scope.funcs[node.VarNameTok.Value.(string)] = node
+		delete(scope.vars, node.VarNameTok.Value.(string))
+	}
+
+	c.pushScope()
+	defer c.popScope()
+	for idx, argName := range node.ArgNameToks {
+		c.scopes[len(c.scopes)-1].vars[argName.Value.(string)] = node.ArgTypes[idx]
+	}
+
+	c.returnTypes = append(c.returnTypes, node.ReturnType)
+	defer func() { c.returnTypes = c.returnTypes[:len(c.returnTypes)-1] }()
+
+	c.walk(node.BodyNode)
+	// the body of an arrow function is its return value
+	if node.Flag {
+		c.checkReturn(node.ReturnType, node.BodyNode, node.BodyNode)
+	}
+}
+
+func (c *TypeChecker) checkReturn(returnType *TypeAnnotation, valueNode Node, at Node) {
+	if returnType == nil {
+		return
+	}
+	if valueNode == nil {
+		if returnType.Name != "Null" && returnType.Name != "Any" {
+			c.report(at, fmt.Sprintf("Expected a return value of type %s", returnType))
+		}
+		return
+	}
+	if valueType, ok := c.fits(returnType, valueNode); !ok {
+		c.report(valueNode, fmt.Sprintf("Expected a return value of type %s, but got %s", returnType, valueType))
+	}
+}
+
+func (c *TypeChecker) checkCall(node *CallNode) {
+	c.walk(node.NodeToCall)
+	for _, arg := range node.ArgNodes {
+		c.walk(arg)
+	}
+
+	callee, ok := node.NodeToCall.(*VarAccessNode)
+	if !ok {
+		return
+	}
+	funcDef := c.lookupFunc(callee.VarNameTok.Value.(string))
+	if funcDef == nil {
+		return
+	}
+
+	if len(node.ArgNodes) != len(funcDef.ArgNameToks) {
+		c.report(node, fmt.Sprintf("Expected %d arguments, but got %d", len(funcDef.ArgNameToks), len(node.ArgNodes)))
+		return
+	}
+	for idx, arg := range node.ArgNodes {
+		if argType, ok := c.fits(funcDef.ArgTypes[idx], arg); !ok {
+			c.report(arg, fmt.Sprintf("Expected argument %d of type %s, but got %s", idx+1, funcDef.ArgTypes[idx], argType))
+		}
+	}
+}
+
+// fits checks an expression against an expected type and returns the inferred type of the expression.
+// Array literals are checked element by element, so that mixed literals do not pass as arrays of unknown elements.
+// Elements of different types are reported at the element by fits itself, the literal then counts as fitting.
+func (c *TypeChecker) fits(expected *TypeAnnotation, node Node) (*TypeAnnotation, bool) {
+	valueType := c.infer(node)
+	if array, ok := node.(*ArrayNode); ok && expected != nil && expected.Name == "Array" && expected.Element != nil {
+		if !c.sameElementTypes(array) {
+			return valueType, true
+		}
+		for _, element := range array.ElementNodes {
+			if elementType, ok := c.fits(expected.Element, element); !ok {
+				return &TypeAnnotation{Name: "Array", Element: elementType}, false
+			}
+		}
+		return valueType, true
+	}
+	return valueType, expected.accepts(valueType)
+}
+
+// sameElementTypes reports the first element of an array literal whose type differs from the elements before it.
+func (c *TypeChecker) sameElementTypes(array *ArrayNode) bool {
+	var first *TypeAnnotation
+	for _, element := range array.ElementNodes {
+		elementType := c.infer(element)
+		if elementType == nil || elementType.Name == "Any" {
+			continue
+		}
+		if first == nil {
+			first = elementType
+			continue
+		}
+		if !first.accepts(elementType) {
+			c.report(element, fmt.Sprintf("Array elements must share one type, got %s and %s", first, elementType))
+			return false
+		}
+	}
+	return true
+}
+
+// infer returns the statically known type of an expression or nil if it can only be known at runtime.
+func (c *TypeChecker) infer(node Node) *TypeAnnotation {
+	known := func(name string) *TypeAnnotation {
+		return &TypeAnnotation{Name: name, PositionStart: node.PosStart(), PositionEnd: node.PosEnd()}
+	}
+
+	switch n := node.(type) {
+	case *NumberNode:
+		return known("Number")
+	case *StringNode:
+		return known("String")
+	case *FuncDefNode:
+		return known("Function")
+	case *ComprehensionNode:
+		return known("Array")
+	case *ArrayNode:
+		array := known("Array")
+		for idx, element := range n.ElementNodes {
+			elementType := c.infer(element)
+			if elementType == nil || (idx > 0 && !elementType.accepts(array.Element)) {
+				array.Element = nil
+				break
+			}
+			array.Element = elementType
+		}
+		return array
+	case *VarAccessNode:
+		switch name := n.VarNameTok.Value.(string); name {
+		case "true", "false":
+			return known("Boolean")
+		case "null":
+			return known("Null")
+		default:
+			if c.lookupFunc(name) != nil {
+				return known("Function")
+			}
+			return c.lookupVar(name)
+		}
+	case *CallNode:
+		if callee, ok := n.NodeToCall.(*VarAccessNode); ok {
+			if funcDef := c.lookupFunc(callee.VarNameTok.Value.(string)); funcDef != nil {
+				return funcDef.ReturnType
+			}
+		}
+	case *UnaryOpNode:
+		if n.OpTok.Matches(TT_KEYWORD, "not") {
+			return known("Boolean")
+		}
+		return c.infer(n.Node)
+	case *BinOpNode:
+		left, right := c.infer(n.LeftNode), c.infer(n.RightNode)
+		switch n.OpTok.Type {
+		case TT_EE, TT_NE, TT_LT, TT_GT, TT_LTE, TT_GTE:
+			return known("Boolean")
+		case TT_KEYWORD:
+			return known("Boolean")
+		case TT_PLUS:
+			if left != nil && right != nil && left.Name == right.Name && (left.Name == "Number" || left.Name == "String") {
+				return known(left.Name)
+			}
+		case TT_MINUS, TT_STAR, TT_DIV, TT_POW:
+			if left != nil && right != nil && left.Name == "Number" && right.Name == "Number" {
+				return known("Number")
+			}
+		}
+	}
+	return nil
+}
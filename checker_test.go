@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypeChecker(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string // details of the first type error, empty if there is none
+		at     string // source text the error starts at
+	}{
+		{
+			name:   "matching array literal",
+			source: "var a: Array<Number> = [1, 2]",
+		},
+		{
+			name:   "elements of different types",
+			source: `var a: Array<Number> = ["a", 1]`,
+			want:   "Array elements must share one type, got String and Number",
+			at:     "1",
+		},
+		{
+			name:   "the first differing element is reported",
+			source: `var a: Array<String> = ["a", "b", 3, "c"]`,
+			want:   "Array elements must share one type, got String and Number",
+			at:     "3",
+		},
+		{
+			name:   "elements of one wrong type",
+			source: `var a: Array<Number> = ["a", "b"]`,
+			want:   "Cannot assign Array<String> to variable 'a' of type Array<Number>",
+			at:     `["a", "b"]`,
+		},
+		{
+			name:   "wrong return type",
+			source: `func f() -> Number => "a"`,
+			want:   "Expected a return value of type Number, but got String",
+			at:     `"a"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ast := NewLexerParser(NewLexer("<test>", test.source)).Parse()
+			if ast.Error != nil {
+				t.Fatalf("syntax error: %s", ast.Error.Details)
+			}
+			typeErrors := NewTypeChecker().Check(ast.Node)
+			if test.want == "" {
+				if len(typeErrors) > 0 {
+					t.Fatalf("unexpected type error: %s", typeErrors[0].Details)
+				}
+				return
+			}
+			if len(typeErrors) == 0 {
+				t.Fatalf("no type error, want %q", test.want)
+			}
+			typeError := typeErrors[0]
+			if typeError.Details != test.want {
+				t.Errorf("error = %q, want %q", typeError.Details, test.want)
+			}
+			if at := test.source[typeError.PosStart.Idx:]; !strings.HasPrefix(at, test.at) {
+				t.Errorf("error starts at %q, want %q", at, test.at)
+			}
+		})
+	}
+}
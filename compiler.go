@@ -0,0 +1,256 @@
+package main
+
+const (
+	OP_NUMBER           Opcode = iota // push the number of a NumberNode
+	OP_STRING                         // push the string of a StringNode
+	OP_NULL                           // push null
+	OP_POP                            // discard the top of the stack
+	OP_GET                            // push the value of a variable
+	OP_CHECK_ASSIGN                   // check that a variable may be assigned before its value is evaluated
+	OP_ASSIGN                         // pop a value and assign it to a variable
+	OP_BINARY                         // pop two operands and push the result of the operator
+	OP_UNARY                          // pop an operand and push the result of the prefix operator
+	OP_ARRAY                          // pop Operand values and push them as an array
+	OP_INDEX                          // pop an index and a target and push the element
+	OP_FUNCTION                       // create a function, bind it if it is named
+	OP_CALL                           // pop Operand arguments and the callee and push the return value
+	OP_PACKAGE                        // evaluate a package member and jump to Operand if the target is an imported package
+	OP_METHOD                         // push the function called by 'target.name(args)'
+	OP_CALL_METHOD                    // pop Operand arguments, the function and the target and push the return value
+	OP_JUMP                           // jump to Operand
+	OP_JUMP_IF_FALSE                  // pop a condition and jump to Operand if it is false
+	OP_JUMP_IF_NULL                   // replace a null on top of the stack with the result of an optional access and jump to Operand
+	OP_JUMP_IF_NOT_NULL               // jump to Operand if the top of the stack is not null
+	OP_LOOP                           // start a loop that ends at Operand, for loops pop their range
+	OP_FOR_NEXT                       // set the variable of a for loop or jump to Operand when it is done
+	OP_LOOP_APPEND                    // pop the value of a loop body and collect it
+	OP_LOOP_END                       // finish the innermost loop and push its result
+	OP_BREAK                          // jump to the end of the targeted loop
+	OP_CONTINUE                       // jump to the next iteration of the targeted loop
+	OP_RETURN                         // pop a value and return it from the function
+	OP_TAIL_CALL                      // pop Operand arguments and the callee and return by calling it in place of the function
+	OP_NODE                           // evaluate the node with the tree walker
+)
+
+// useTreeWalker runs programs with the tree walking interpreter instead of the bytecode VM.
+var useTreeWalker bool
+
+// runNode evaluates node in context with the bytecode VM, or with the tree walker if it was selected on the command line.
+func runNode(node Node, context *Context) *RTResult {
+	if useTreeWalker {
+		interpreter := NewInterpreter()
+		return interpreter.visit(node, context)
+	}
+	return NewVM().Run(NewCompiler().Compile(node), context)
+}
+
+// runCompiled is runNode for function bodies, which run many times. Their bytecode is compiled once into compiled and
+// reused, without compiled the body is compiled for this run only.
+func runCompiled(node Node, compiled *CompiledBody, context *Context) *RTResult {
+	if useTreeWalker || compiled == nil {
+		return runNode(node, context)
+	}
+	compiled.once.Do(func() { compiled.chunk = NewCompiler().Compile(node) })
+	return NewVM().Run(compiled.chunk, context)
+}
+
+// NewCompiler creates a new Compiler instance.
+func NewCompiler() *Compiler {
+	return &Compiler{chunk: &Chunk{}}
+}
+
+// Compile translates node into bytecode that leaves the value of node on the stack.
+func (c *Compiler) Compile(node Node) *Chunk {
+	c.compile(node)
+	return c.chunk
+}
+
+// emit appends an instruction and returns its index.
+func (c *Compiler) emit(op Opcode, operand int, node Node) int {
+	c.chunk.Code = append(c.chunk.Code, Instruction{Op: op, Operand: operand, Node: node})
+	return len(c.chunk.Code) - 1
+}
+
+// patch points the jump at index to the next instruction.
+func (c *Compiler) patch(index int) {
+	c.chunk.Code[index].Operand = len(c.chunk.Code)
+}
+
+func (c *Compiler) compile(node Node) {
+	switch n := node.(type) {
+	case *NumberNode:
+		c.emit(OP_NUMBER, 0, n)
+	case *StringNode:
+		c.emit(OP_STRING, 0, n)
+	case *VarAccessNode:
+		c.emit(OP_GET, 0, n)
+	case *VarAssignNode:
+		c.emit(OP_CHECK_ASSIGN, 0, n)
+		if n.ValueNode != nil {
+			c.compile(n.ValueNode)
+		} else {
+			c.emit(OP_NULL, 0, n)
+		}
+		c.emit(OP_ASSIGN, 0, n)
+	case *BinOpNode:
+		c.compile(n.LeftNode)
+		if n.OpTok.Type == TT_NULLISH {
+			// the right side is only evaluated when the left side is null
+			jump := c.emit(OP_JUMP_IF_NOT_NULL, 0, n)
+			c.emit(OP_POP, 0, n)
+			c.compile(n.RightNode)
+			c.patch(jump)
+			return
+		}
+		c.compile(n.RightNode)
+		c.emit(OP_BINARY, 0, n)
+	case *UnaryOpNode:
+		c.compile(n.Node)
+		c.emit(OP_UNARY, 0, n)
+	case *ArrayNode:
+		for _, elementNode := range n.ElementNodes {
+			c.compile(elementNode)
+		}
+		c.emit(OP_ARRAY, len(n.ElementNodes), n)
+	case *IndexNode:
+		c.compile(n.Target)
+		skip := c.optional(n.Optional, n)
+		c.compile(n.Index)
+		c.emit(OP_INDEX, 0, n)
+		c.patchOptional(skip)
+	case *IfNode:
+		c.compileIf(n)
+	case *WhileNode:
+		loop := c.emit(OP_LOOP, 0, n)
+		c.compile(n.ConditionNode)
+		exit := c.emit(OP_JUMP_IF_FALSE, 0, n.ConditionNode)
+		c.compileLoopBody(n.BodyNode, n.Flag, loop+1, n)
+		c.patch(loop)
+		c.patch(exit)
+		c.emit(OP_LOOP_END, 0, n)
+	case *ForNode:
+		c.compile(n.StartValueNode)
+		c.compile(n.EndValueNode)
+		if n.StepValueNode != nil {
+			c.compile(n.StepValueNode)
+		}
+		loop := c.emit(OP_LOOP, 0, n)
+		next := c.emit(OP_FOR_NEXT, 0, n)
+		c.compileLoopBody(n.BodyNode, n.Flag, next, n)
+		c.patch(loop)
+		c.patch(next)
+		c.emit(OP_LOOP_END, 0, n)
+	case *FuncDefNode:
+		if len(n.Decorators) > 0 {
+			c.emit(OP_NODE, 0, n)
+			return
+		}
+		c.emit(OP_FUNCTION, 0, n)
+	case *CallNode:
+		c.compile(n.NodeToCall)
+		skip := c.optional(n.Optional, n)
+		for _, argNode := range n.ArgNodes {
+			c.compile(argNode)
+		}
+		c.emit(OP_CALL, len(n.ArgNodes), n)
+		c.patchOptional(skip)
+	case *MethodCallNode:
+		c.compileMethodCall(n)
+	case *ReturnNode:
+		if n.TailCall {
+			call := n.NodeToReturn.(*CallNode)
+			c.compile(call.NodeToCall)
+			for _, argNode := range call.ArgNodes {
+				c.compile(argNode)
+			}
+			c.emit(OP_TAIL_CALL, len(call.ArgNodes), call)
+			break
+		}
+		if n.NodeToReturn != nil {
+			c.compile(n.NodeToReturn)
+		} else {
+			c.emit(OP_NULL, 0, n)
+		}
+		c.emit(OP_RETURN, 0, n)
+	case *BreakNode:
+		c.emit(OP_BREAK, 0, n)
+	case *ContinueNode:
+		c.emit(OP_CONTINUE, 0, n)
+	default:
+		// imports, comprehensions, spawn, defer, pointers and decorated functions run on the tree walker
+		c.emit(OP_NODE, 0, n)
+	}
+}
+
+// optional emits the null check of an optional access, it returns -1 if the access is not optional.
+func (c *Compiler) optional(optional bool, node Node) int {
+	if !optional {
+		return -1
+	}
+	return c.emit(OP_JUMP_IF_NULL, 0, node)
+}
+
+func (c *Compiler) patchOptional(index int) {
+	if index >= 0 {
+		c.patch(index)
+	}
+}
+
+func (c *Compiler) compileIf(node *IfNode) {
+	var exits []int
+	for _, ifcase := range node.Cases {
+		c.compile(ifcase.Condition)
+		next := c.emit(OP_JUMP_IF_FALSE, 0, ifcase.Condition)
+		c.compileBranch(ifcase.Expr, ifcase.Flag, node)
+		exits = append(exits, c.emit(OP_JUMP, 0, node))
+		c.patch(next)
+	}
+
+	if node.ElseCase != nil {
+		c.compileBranch(node.ElseCase.Expr, node.ElseCase.Flag, node)
+	} else {
+		c.emit(OP_NULL, 0, node)
+	}
+
+	for _, exit := range exits {
+		c.patch(exit)
+	}
+}
+
+// compileBranch compiles the body of an if case, multi-line bodies evaluate to null.
+func (c *Compiler) compileBranch(body Node, flag bool, node Node) {
+	c.compile(body)
+	if flag {
+		c.emit(OP_POP, 0, node)
+		c.emit(OP_NULL, 0, node)
+	}
+}
+
+// compileLoopBody collects the value of the body, unless the loop is a statement, and jumps back to start.
+func (c *Compiler) compileLoopBody(body Node, statement bool, start int, node Node) {
+	c.compile(body)
+	if statement {
+		c.emit(OP_POP, 0, node)
+	} else {
+		c.emit(OP_LOOP_APPEND, 0, node)
+	}
+	c.emit(OP_JUMP, start, node)
+}
+
+func (c *Compiler) compileMethodCall(node *MethodCallNode) {
+	// a variable can only name a package when it is not shadowed, which is only known at runtime
+	packageMember := -1
+	if _, ok := node.TargetNode.(*VarAccessNode); ok {
+		packageMember = c.emit(OP_PACKAGE, 0, node)
+	}
+
+	c.compile(node.TargetNode)
+	skip := c.optional(node.Optional, node)
+	c.emit(OP_METHOD, 0, node)
+	for _, argNode := range node.ArgNodes {
+		c.compile(argNode)
+	}
+	c.emit(OP_CALL_METHOD, len(node.ArgNodes), node)
+	c.patchOptional(skip)
+	c.patchOptional(packageMember)
+}
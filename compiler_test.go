@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestCompile(t *testing.T) {
+	ast := NewLexerParser(NewLexer("<test>", "var x = 1 + 2")).Parse()
+	if ast.Error != nil {
+		t.Fatalf("unexpected syntax error: %s", ast.Error.Details)
+	}
+	NewResolver(buildInSymbolTable()).Resolve(ast.Node)
+
+	chunk := NewCompiler().Compile(ast.Node.(*ArrayNode).ElementNodes[0])
+	want := []Opcode{OP_CHECK_ASSIGN, OP_NUMBER, OP_NUMBER, OP_BINARY, OP_ASSIGN}
+	if len(chunk.Code) != len(want) {
+		t.Fatalf("compiled %d instructions, want %d", len(chunk.Code), len(want))
+	}
+	for i, instruction := range chunk.Code {
+		if instruction.Op != want[i] {
+			t.Errorf("instruction %d = %d, want %d", i, instruction.Op, want[i])
+		}
+	}
+}
+
+func TestBackendsAgree(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "for loops count up to the end with the step",
+			source: "var out = []\nfor i = 0 to 7 step 2 {\n\tif i == 4 {\n\t\tcontinue\n\t}\n\tappend(out, i)\n}",
+			want:   "[0, 2, 6]",
+		},
+		{
+			name:   "while loops run until the condition is false",
+			source: "var i = 0\nvar out = []\nwhile i < 3 {\n\tappend(out, i)\n\ti = i + 1\n}",
+			want:   "[0, 1, 2]",
+		},
+		{
+			name:   "closures keep their variables",
+			source: "func counter() {\n\tvar n = 0\n\treturn func() {\n\t\tn = n + 1\n\t\treturn n\n\t}\n}\nvar c = counter()\nc()\nvar out = [c(), counter()()]",
+			want:   "[2, 1]",
+		},
+		{
+			name:   "recursion",
+			source: "func fib(n) => n < 2 ? n : fib(n - 1) + fib(n - 2)\nvar out = fib(15)",
+			want:   "610",
+		},
+		{
+			name:   "short circuiting operators skip the right side",
+			source: "var calls = 0\nfunc f() {\n\tcalls = calls + 1\n\treturn 1\n}\nvar a = 1 ?? f()\nvar b = null ?? f()\nvar out = [a, b, calls]",
+			want:   "[1, 1, 1]",
+		},
+	})
+}
+
+func TestBackendsReportTheSameErrorPosition(t *testing.T) {
+	sources := []string{
+		"var a = [1, 2]\nvar b = a[5]",
+		"func f(x) => x / 0\nvar y = 1\nf(y)",
+		"var s = \"a\"\nvar n = s - 1",
+		"var c = null\nvar n = 1 + (c ? 1 : 2)",
+		"var c = 0\nwhile c {\n\tc = c + 1\n}",
+	}
+	for _, source := range sources {
+		vmError := runtimeError(t, source, false)
+		treeWalkerError := runtimeError(t, source, true)
+		if vmError.Details != treeWalkerError.Details {
+			t.Errorf("%q: vm error %q, tree walker error %q", source, vmError.Details, treeWalkerError.Details)
+		}
+		if vmError.PosStart.Idx != treeWalkerError.PosStart.Idx || vmError.PosEnd.Idx != treeWalkerError.PosEnd.Idx {
+			t.Errorf("%q: vm error at %d-%d, tree walker error at %d-%d", source, vmError.PosStart.Idx, vmError.PosEnd.Idx, treeWalkerError.PosStart.Idx, treeWalkerError.PosEnd.Idx)
+		}
+	}
+}
+
+func TestCompiledBodiesBelongToTheDeclaration(t *testing.T) {
+	context, err := runProgram("func make() => func() => 1\nvar f = make()\nvar g = make()\nvar out = f() + g()", false)
+	if err != "" {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f, _, _ := context.SymbolTable.Get("f")
+	g, _, _ := context.SymbolTable.Get("g")
+	if f.Function().Compiled == nil || f.Function().Compiled != g.Function().Compiled {
+		t.Fatal("functions of one declaration do not share the compiled body")
+	}
+	if f.Function().Compiled.chunk == nil {
+		t.Error("the body was not compiled by the call")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+func (b *BuildInFunction) ExecuteAwait(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	task, exists, _ := execCtx.SymbolTable.Get("task")
+
+	if !exists || task.Task() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Argument must be a Task, got: %v", task.Type()), execCtx))
+	}
+
+	value, err := task.Task().Await()
+	if err != nil {
+		return res.Failure(err)
+	}
+	return res.Success(value)
+}
+
+func (b *BuildInFunction) ExecuteChannel(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	size, exists, _ := execCtx.SymbolTable.Get("size")
+
+	if !exists || size.Number() == nil || !size.Number().IsInt() || size.Number().Int < 0 {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Size of a channel must be a positive int", execCtx))
+	}
+
+	channel := NewChannel(size.Number().Int)
+	channel.SetContext(execCtx.Parent).SetPos(b.Base.PosStart(), b.Base.PosEnd())
+	return res.Success(channel)
+}
+
+func (b *BuildInFunction) ExecuteSend(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	channel, exists, _ := execCtx.SymbolTable.Get("channel")
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	if !exists || channel.Channel() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("First argument must be a Channel, got: %v", channel.Type()), execCtx))
+	}
+
+	if err := channel.Channel().Send(value.Copy()); err != nil {
+		err.SetLocation(b.Base)
+		return res.Failure(err)
+	}
+	return res.Success(NewNull())
+}
+
+func (b *BuildInFunction) ExecuteReceive(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	channel, exists, _ := execCtx.SymbolTable.Get("channel")
+
+	if !exists || channel.Channel() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Argument must be a Channel, got: %v", channel.Type()), execCtx))
+	}
+
+	return res.Success(channel.Channel().Receive())
+}
+
+func (b *BuildInFunction) ExecuteClose(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	channel, exists, _ := execCtx.SymbolTable.Get("channel")
+
+	if !exists || channel.Channel() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Argument must be a Channel, got: %v", channel.Type()), execCtx))
+	}
+
+	if err := channel.Channel().Close(); err != nil {
+		err.SetLocation(b.Base)
+		return res.Failure(err)
+	}
+	return res.Success(NewNull())
+}
+
+// ExecuteSelect waits until one of the given channels can be received from and returns [index, value].
+func (b *BuildInFunction) ExecuteSelect(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	channels, exists, _ := execCtx.SymbolTable.Get("channels")
+
+	if !exists || channels.Array() == nil || len(channels.Array().Elements) == 0 {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Argument must be a non-empty Array of Channels", execCtx))
+	}
+
+	cases := make([]reflect.SelectCase, len(channels.Array().Elements))
+	for idx, element := range channels.Array().Elements {
+		if element.Channel() == nil {
+			return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Element at index %d is not a Channel, got: %v", idx, element.Type()), execCtx))
+		}
+		cases[idx] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(element.Channel().state.ch)}
+	}
+
+	chosen, received, ok := reflect.Select(cases)
+	value := NewNull()
+	if ok {
+		value = received.Interface().(*Value)
+	}
+	return res.Success(NewArray([]*Value{NewNumber(chosen), value}))
+}
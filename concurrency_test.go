@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestTasksAndChannels(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name: "await returns the result of the spawned call",
+			source: `func square(n) => n * n
+var task = spawn square(7)
+var out = await(task)`,
+			want: "49",
+		},
+		{
+			name: "channels pass values between tasks",
+			source: `var ch = channel(3)
+func produce(n) {
+	for i = 0 to n {
+		send(ch, i)
+	}
+	close(ch)
+}
+var task = spawn produce(3)
+await(task)
+var out = ""
+for i = 0 to 3 {
+	out = out + str(receive(ch))
+}`,
+			want: "012",
+		},
+		{
+			name: "select reports the index of the ready channel",
+			source: `var a = channel(1)
+var b = channel(1)
+send(b, "b")
+var out = select([a, b])`,
+			want: `[1, "b"]`,
+		},
+		{
+			name: "tasks can append to a shared array",
+			source: `var data = []
+func add(n) {
+	append(data, n)
+}
+var tasks = []
+for i = 0 to 20 {
+	append(tasks, spawn add(i))
+}
+for i = 0 to 20 {
+	await(tasks[i])
+}
+var out = len(data)`,
+			want: "20",
+		},
+		{
+			name:   "tasks and channels in arrays show their type",
+			source: "func f() => 1\nvar task = spawn f()\nawait(task)\nvar out = [task, channel(1)]",
+			want:   "[<Task>, <Channel>]",
+		},
+		{
+			name:    "await rejects values that are not tasks",
+			source:  `var out = await(1)`,
+			wantErr: "Argument must be a Task",
+		},
+	})
+}
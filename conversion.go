@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ExecuteTypeOf returns the name of the type of a value, the same name that is used in type annotations.
+func (b *BuildInFunction) ExecuteTypeOf(execCtx *Context) *RTResult {
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	return NewRTResult().Success(NewString(value.Type()))
+}
+
+// ExecuteInt converts a value to an integer, floats and decimals are truncated towards zero.
+func (b *BuildInFunction) ExecuteInt(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	if value.Boolean() != nil {
+		return res.Success(NewNumber(int(value.Boolean().Binary)))
+	}
+
+	number := value.Number()
+	if value.String() != nil {
+		text := strings.TrimSpace(value.String().ValueField)
+		if integer, ok := parseInteger(text); ok {
+			return res.Success(NewNumber(integer))
+		}
+		if decimal, ok := ParseDecimal(text); ok {
+			number = NewNumber(decimal).Number()
+		}
+	}
+	if number != nil {
+		switch v := number.Value().(type) {
+		case int, *big.Int:
+			return res.Success(NewNumber(v))
+		case *Decimal:
+			return res.Success(NewNumber(normalizeInt(v.Rescale(0, RoundDown).Unscaled)))
+		case float64:
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				truncated, _ := big.NewFloat(v).Int(nil)
+				return res.Success(NewNumber(normalizeInt(truncated)))
+			}
+		}
+	}
+	return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not convert %s %v to an int", value.Type(), value.Value()), execCtx))
+}
+
+func (b *BuildInFunction) ExecuteFloat(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	switch {
+	case value.Number() != nil:
+		return res.Success(NewNumber(toFloat(value.Number().Value())))
+	case value.Boolean() != nil:
+		return res.Success(NewNumber(float64(value.Boolean().Binary)))
+	case value.String() != nil:
+		if float, err := strconv.ParseFloat(strings.TrimSpace(value.String().ValueField), 64); err == nil {
+			return res.Success(NewNumber(float))
+		}
+	}
+	return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not convert %s %v to a float", value.Type(), value.Value()), execCtx))
+}
+
+// ExecuteBool converts a value to a boolean. Zero, empty strings, empty arrays and null are false, everything else is true.
+func (b *BuildInFunction) ExecuteBool(execCtx *Context) *RTResult {
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	truthy := true
+	switch {
+	case value.Boolean() != nil:
+		truthy = value.Boolean().IsTrue()
+	case value.Number() != nil:
+		truthy = !isZero(value.Number().Value())
+	case value.String() != nil:
+		truthy = value.String().IsTrue()
+	case value.Array() != nil:
+		truthy = len(value.Array().Elements) > 0
+	case value.ByteArray() != nil:
+		truthy = len(value.ByteArray().ValueField) > 0
+	case value.Null() != nil:
+		truthy = false
+	}
+	return NewRTResult().Success(NewBoolean(ConvertBoolToInt(truthy)))
+}
+
+// ExecuteBytes encodes a string into a ByteArray. Supported encodings are utf-8, ascii, latin-1, utf-16le and utf-16be.
+func (b *BuildInFunction) ExecuteBytes(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+	encoding, _, _ := execCtx.SymbolTable.Get("encoding")
+
+	if value.String() == nil || encoding.String() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Can not encode %s with encoding %v, expected two strings", value.Type(), encoding.Value()), execCtx))
+	}
+
+	text := value.String().ValueField
+	var encoded []byte
+	switch strings.ToLower(encoding.String().ValueField) {
+	case "utf-8", "utf8":
+		encoded = []byte(text)
+	case "ascii", "latin-1", "latin1", "iso-8859-1":
+		limit := rune(utf8.RuneSelf - 1)
+		if strings.ToLower(encoding.String().ValueField) != "ascii" {
+			limit = 0xFF
+		}
+		for _, char := range text {
+			if char > limit {
+				return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Character '%c' can not be encoded as %s", char, encoding.String().ValueField), execCtx))
+			}
+			encoded = append(encoded, byte(char))
+		}
+	case "utf-16le", "utf-16be":
+		bigEndian := strings.HasSuffix(strings.ToLower(encoding.String().ValueField), "be")
+		for _, unit := range utf16.Encode([]rune(text)) {
+			if bigEndian {
+				encoded = append(encoded, byte(unit>>8), byte(unit))
+			} else {
+				encoded = append(encoded, byte(unit), byte(unit>>8))
+			}
+		}
+	default:
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Unknown encoding '%s', expected one of 'utf-8', 'ascii', 'latin-1', 'utf-16le', 'utf-16be'", encoding.String().ValueField), execCtx))
+	}
+	return res.Success(NewByteArray(encoded))
+}
+
+// ExecuteChr returns the character of a unicode code point.
+func (b *BuildInFunction) ExecuteChr(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	if value.Number() == nil || !value.Number().IsInt() || !utf8.ValidRune(rune(value.Number().Int)) {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("%v is not a valid code point", value.Value()), execCtx))
+	}
+	return res.Success(NewString(string(rune(value.Number().Int))))
+}
+
+// ExecuteOrd returns the unicode code point of a string with a single character.
+func (b *BuildInFunction) ExecuteOrd(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	value, _, _ := execCtx.SymbolTable.Get("value")
+
+	if value.String() == nil || utf8.RuneCountInString(value.String().ValueField) != 1 {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Expected a string with a single character, but got %s %v", value.Type(), value.Value()), execCtx))
+	}
+	char, _ := utf8.DecodeRuneInString(value.String().ValueField)
+	return res.Success(NewNumber(int(char)))
+}
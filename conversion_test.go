@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestConversions(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "typeOf returns the type name",
+			source: `var out = [typeOf(1), typeOf(1.5), typeOf(1d), typeOf("a"), typeOf([1]), typeOf(true), typeOf(null), typeOf(len), typeOf(func() => 1), typeOf(bytes("a", "utf-8"))]`,
+			want:   `["Number", "Number", "Number", "String", "Array", "Boolean", "Null", "BuildInFunction", "Function", "ByteArray"]`,
+		},
+		{
+			name:   "int truncates towards zero",
+			source: `var out = [int("42"), int(3.9), int(-3.9)]`,
+			want:   "[42, 3, -3]",
+		},
+		{
+			name:   "float parses strings",
+			source: `var out = [float("1.5"), float(2) / 4]`,
+			want:   "[1.5, 0.5]",
+		},
+		{
+			name:   "bool follows truthiness",
+			source: `var out = [bool(0), bool(""), bool([1]), bool("a")]`,
+			want:   "[false, false, true, true]",
+		},
+		{
+			name:   "chr and ord use code points",
+			source: `var out = [chr(228), ord("ä")]`,
+			want:   `["ä", 228]`,
+		},
+		{
+			name:   "bytes encodes with the given encoding",
+			source: `var out = [len(bytes("äb", "utf-8")), len(bytes("äb", "latin-1")), len(bytes("äb", "utf-16le"))]`,
+			want:   "[3, 2, 4]",
+		},
+		{
+			name:   "functions can be inspected",
+			source: "func add(a, b) => a + b\nvar out = [funcName(add), funcParams(add), funcArity(add), funcFile(add), funcLine(add)]",
+			want:   `["add", ["a", "b"], 2, "<test>", 1]`,
+		},
+		{
+			name:   "built-in functions can be inspected",
+			source: "var out = [funcName(len), funcArity(len)]",
+			want:   `["len", 1]`,
+		},
+		{
+			name:    "int rejects text that is not a number",
+			source:  `int("x")`,
+			wantErr: "Can not convert String x to an int",
+		},
+		{
+			name:    "bytes rejects characters the encoding does not have",
+			source:  `bytes("ä", "ascii")`,
+			wantErr: "Character 'ä' can not be encoded as ascii",
+		},
+		{
+			name:    "ord expects a single character",
+			source:  `ord("ab")`,
+			wantErr: "Expected a string with a single character, but got String ab",
+		},
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// wrapFunction returns a build-in function with the name and parameters of fn that runs call instead.
+// Decorators use it to build the replacement of the function they decorate.
+func wrapFunction(fn *Value, call func(execCtx *Context, args []*Value) *RTResult) *Value {
+	base, params, _ := callableBase(fn)
+	name := base.Name
+	wrapper := &BuildInFunction{Base: NewBaseFunction(&name), Methods: make(map[string]Method)}
+	wrapper.Base.Definition = base.Definition
+	wrapper.Methods[name] = Method{ArgsNames: params, Fn: func(execCtx *Context) *RTResult {
+		args := make([]*Value, len(params))
+		for idx, param := range params {
+			args[idx], _, _ = execCtx.SymbolTable.Get(param)
+		}
+		return call(execCtx, args)
+	}}
+	return &Value{Kind: KIND_BUILD_IN_FUNCTION, ref: wrapper}
+}
+
+// callWrapped calls the decorated function as if it was called directly at the position of the wrapper's call.
+func callWrapped(fn *Value, execCtx *Context, args []*Value) *RTResult {
+	interpreter := NewInterpreter()
+	callee := fn.Copy().SetPos(execCtx.ParentEntryPos, execCtx.ParentEntryPos).SetContext(execCtx.Parent)
+	return interpreter.callValue(callee, args)
+}
+
+// decoratedFunction returns the function argument of a decorator or an error if it is not callable.
+func (b *BuildInFunction) decoratedFunction(execCtx *Context) (*Value, *RuntimeError) {
+	fn, _, _ := execCtx.SymbolTable.Get("function")
+	if _, _, ok := callableBase(fn); !ok {
+		return nil, NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("'%s' can only decorate functions, but got %s", b.Base.Name, fn.Type()), execCtx)
+	}
+	return fn, nil
+}
+
+// formatArgs formats arguments the way elements of an array are printed.
+func formatArgs(args []*Value) string {
+	formatted := NewArray(args).Array().String()
+	return formatted[1 : len(formatted)-1]
+}
+
+// writeMemoKey writes a key of value that only equal values share, numbers keep their form so that 3 and 3.0 differ
+// and arrays are keyed by their elements. It returns false for values that can not be compared, e.g. functions.
+func writeMemoKey(key *strings.Builder, value *Value) bool {
+	switch value.Kind {
+	case KIND_NUMBER:
+		switch number := value.Number().Value().(type) {
+		case int:
+			fmt.Fprintf(key, "i%d;", number)
+		case float64:
+			fmt.Fprintf(key, "f%s;", strconv.FormatFloat(number, 'g', -1, 64))
+		case *big.Int:
+			fmt.Fprintf(key, "b%s;", number)
+		case *Decimal:
+			fmt.Fprintf(key, "d%s;", number)
+		}
+	case KIND_STRING:
+		fmt.Fprintf(key, "s%d:%s", len(value.String().ValueField), value.String().ValueField)
+	case KIND_BOOLEAN:
+		fmt.Fprintf(key, "t%t;", value.Boolean().IsTrue())
+	case KIND_NULL:
+		key.WriteString("n;")
+	case KIND_BYTE_ARRAY:
+		fmt.Fprintf(key, "y%x;", value.ByteArray().ValueField)
+	case KIND_ARRAY:
+		fmt.Fprintf(key, "a%d[", len(value.Array().Elements))
+		for _, element := range value.Array().Elements {
+			if !writeMemoKey(key, element) {
+				return false
+			}
+		}
+		key.WriteString("]")
+	default:
+		return false
+	}
+	return true
+}
+
+// ExecuteMemoize returns a function that caches the results of fn by its arguments.
+func (b *BuildInFunction) ExecuteMemoize(execCtx *Context) *RTResult {
+	fn, err := b.decoratedFunction(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+
+	var mu sync.Mutex
+	cache := make(map[string]*Value)
+	return NewRTResult().Success(wrapFunction(fn, func(execCtx *Context, args []*Value) *RTResult {
+		var key strings.Builder
+		for _, arg := range args {
+			if !writeMemoKey(&key, arg) {
+				return NewRTResult().Failure(NewRTError(execCtx.ParentEntryPos, execCtx.ParentEntryPos, fmt.Sprintf("Cannot memoize '%s' called with an argument of type %s", fn.FunctionName(), arg.Type()), execCtx.Parent))
+			}
+		}
+
+		mu.Lock()
+		cached, exists := cache[key.String()]
+		mu.Unlock()
+		if exists {
+			return NewRTResult().Success(cached.Share())
+		}
+
+		res := NewRTResult()
+		result := res.Register(callWrapped(fn, execCtx, args))
+		if res.ShouldReturn() {
+			return res
+		}
+		mu.Lock()
+		cache[key.String()] = result.Share()
+		mu.Unlock()
+		return res.Success(result)
+	}))
+}
+
+// ExecuteTrace returns a function that logs every call of fn with its arguments and its return value to stderr.
+func (b *BuildInFunction) ExecuteTrace(execCtx *Context) *RTResult {
+	fn, err := b.decoratedFunction(execCtx)
+	if err != nil {
+		return NewRTResult().Failure(err)
+	}
+
+	name := fn.FunctionName()
+	return NewRTResult().Success(wrapFunction(fn, func(execCtx *Context, args []*Value) *RTResult {
+		fmt.Fprintf(os.Stderr, "trace: %s(%s)\n", name, formatArgs(args))
+
+		res := NewRTResult()
+		result := res.Register(callWrapped(fn, execCtx, args))
+		if res.Error != nil {
+			fmt.Fprintf(os.Stderr, "trace: %s failed: %s\n", name, res.Error.Details)
+			return res
+		}
+		fmt.Fprintf(os.Stderr, "trace: %s returned %s\n", name, formatArgs([]*Value{result}))
+		return res.Success(result)
+	}))
+}
+
+// ExecuteDeprecated returns a decorator that warns on stderr the first time the decorated function is called.
+func (b *BuildInFunction) ExecuteDeprecated(execCtx *Context) *RTResult {
+	message, _, _ := execCtx.SymbolTable.Get("message")
+	if message.String() == nil {
+		return NewRTResult().Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Message of 'deprecated' must be a String, but got %s", message.Type()), execCtx))
+	}
+
+	decoratorName := "deprecated"
+	decorator := &BuildInFunction{Base: NewBaseFunction(&decoratorName), Methods: make(map[string]Method)}
+	decorator.Methods[decoratorName] = Method{ArgsNames: []string{"function"}, Fn: func(execCtx *Context) *RTResult {
+		fn, err := decorator.decoratedFunction(execCtx)
+		if err != nil {
+			return NewRTResult().Failure(err)
+		}
+
+		var once sync.Once
+		return NewRTResult().Success(wrapFunction(fn, func(execCtx *Context, args []*Value) *RTResult {
+			once.Do(func() {
+				fmt.Fprintf(os.Stderr, "Warning: '%s' is deprecated: %s\n", fn.FunctionName(), message.String().ValueField)
+			})
+			return callWrapped(fn, execCtx, args)
+		}))
+	}}
+	return NewRTResult().Success(&Value{Kind: KIND_BUILD_IN_FUNCTION, ref: decorator})
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestMemoize(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name: "ints and floats are cached apart",
+			source: `var calls = 0
+@memoize
+func half(x) {
+	calls = calls + 1
+	return x / 2
+}
+var out = [half(3), half(3.0), half(3), calls]`,
+			want: "[1, 1.5, 1, 2]",
+		},
+		{
+			name: "strings and numbers are cached apart",
+			source: `@memoize
+func kind(x) => isString(x)
+var out = [kind(1), kind("1")]`,
+			want: "[false, true]",
+		},
+		{
+			name: "arrays are cached by their elements",
+			source: `var calls = 0
+@memoize
+func total(xs) {
+	calls = calls + 1
+	var sum = 0
+	for i = 0 to len(xs) {
+		sum = sum + xs[i]
+	}
+	return sum
+}
+var a = [1, 2]
+var first = total(a)
+var second = total([1, 2])
+append(a, 3)
+var third = total(a)
+var out = [first, second, third, calls]`,
+			want: "[3, 3, 6, 2]",
+		},
+		{
+			name: "nested arrays are cached by their elements",
+			source: `var calls = 0
+@memoize
+func count(xs) {
+	calls = calls + 1
+	return len(xs)
+}
+count([["a", "b"]])
+count([["ab"]])
+count([["a", "b"]])
+var out = calls`,
+			want: "2",
+		},
+		{
+			name: "functions can not be used as keys",
+			source: `@memoize
+func call(f) => f()
+call(func() => 1)`,
+			wantErr: "Cannot memoize 'call' called with an argument of type",
+		},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -18,12 +19,12 @@ func NewError(posStart *Position, posEnd *Position, errorName string, details st
 // AsString converts the error to a string format.
 func (e *Error) AsString() string {
 	pos := e.PosStart
-	ErrorFilePath, _ := filepath.Abs(pos.Fn)
+	ErrorFilePath, _ := filepath.Abs(pos.Src.Fn)
 
 	result := fmt.Sprintf("%s%s: %s%s\n", bold, e.ErrorName, e.Details, reset)
-	result += fmt.Sprintf("File %s, line %d\n", e.PosStart.Fn, e.PosStart.Ln+1)
+	result += fmt.Sprintf("File %s, line %d\n", e.PosStart.Src.Fn, e.PosStart.Ln+1)
 	result += fmt.Sprintf(" %s:%v\n\n", ErrorFilePath, pos.Ln+1)
-	result += stringWithArrows(e.PosStart.Ftxt, *e.PosStart, *e.PosEnd)
+	result += stringWithArrows(e.PosStart.Src.Text, *e.PosStart, *e.PosEnd)
 	return result
 }
 
@@ -37,6 +38,16 @@ func NewInvalidSyntaxError(posStart *Position, posEnd *Position, details string)
 	return &InvalidSyntaxError{Error{posStart, posEnd, "Invalid Syntax", details}}
 }
 
+// NewTypeError creates a new TypeError instance.
+func NewTypeError(posStart *Position, posEnd *Position, details string) *TypeError {
+	return &TypeError{Error{posStart, posEnd, "Type Error", details}}
+}
+
+// NewNameError creates a new NameError instance.
+func NewNameError(posStart *Position, posEnd *Position, details string) *NameError {
+	return &NameError{Error{posStart, posEnd, "Name Error", details}}
+}
+
 func NewExpectedCharError(posStart *Position, posEnd *Position, details string) *ExpectedCharError {
 	return &ExpectedCharError{Error{posStart, posEnd, "Expected Character", details}}
 }
@@ -90,7 +101,7 @@ func stringWithArrows(text string, posStart Position, posEnd Position) string {
 		}
 		colEnd := posEnd.Col
 		if i != lineCount-1 {
-			colEnd = len(line) - 1
+			colEnd = utf8.RuneCountInString(line) - 1
 		}
 
 		// Append to result
@@ -117,25 +128,50 @@ func stringWithArrows(text string, posStart Position, posEnd Position) string {
 }
 
 func (e RuntimeError) generateTraceback() string {
-	var result string
+	var entries, locations []string // from the innermost context outwards
 	pos := e.PosStart
 	ctx := e.Context
 
-	ErrorFilePath, _ := filepath.Abs(pos.Fn)
+	ErrorFilePath, _ := filepath.Abs(pos.Src.Fn)
 	for ctx != nil {
-		result = fmt.Sprintf("File %s, line %d, in %s\n%s", pos.Fn, pos.Ln+1, ctx.DisplayName, result)
-		result += fmt.Sprintf(" %s:%v\n", ErrorFilePath, pos.Ln+1)
+		entries = append(entries, fmt.Sprintf("File %s, line %d, in %s\n", pos.Src.Fn, pos.Ln+1, ctx.DisplayName))
+		locations = append(locations, fmt.Sprintf(" %s:%v\n", ErrorFilePath, pos.Ln+1))
 		pos = ctx.ParentEntryPos
 		ctx = ctx.Parent
 	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return "Traceback (most recent call last):\n" + joinRepeated(entries) + joinRepeated(locations)
+}
 
-	return "Traceback (most recent call last):\n" + result
+// maxRepeatedLines is the number of times a traceback line is repeated before the rest of the repetitions are counted.
+const maxRepeatedLines = 3
+
+// joinRepeated joins lines, runs of the same line, e.g. from deep recursion, are shortened to a count.
+func joinRepeated(lines []string) string {
+	var result strings.Builder
+	for idx := 0; idx < len(lines); {
+		run := 1
+		for idx+run < len(lines) && lines[idx+run] == lines[idx] {
+			run++
+		}
+		for n := 0; n < min(run, maxRepeatedLines); n++ {
+			result.WriteString(lines[idx])
+		}
+		if run > maxRepeatedLines {
+			fmt.Fprintf(&result, "[Previous line repeated %d more times]\n", run-maxRepeatedLines)
+		}
+		idx += run
+	}
+	return result.String()
 }
 
 func (e RuntimeError) AsString() string {
 	result := e.generateTraceback()
 	result += fmt.Sprintf("%s%s: %s%s\n\n", bold, e.ErrorName, e.Details, reset)
-	result += stringWithArrows(e.PosStart.Ftxt, *e.PosStart, *e.PosEnd)
+	result += stringWithArrows(e.PosStart.Src.Text, *e.PosStart, *e.PosEnd)
 	return result
 }
 
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ExecuteEval evaluates code and returns the value of its last statement.
+func (b *BuildInFunction) ExecuteEval(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	code, _, _ := execCtx.SymbolTable.Get("code")
+	env, _, _ := execCtx.SymbolTable.Get("env")
+
+	evalCtx, err := b.evalContext("<eval>", env, execCtx)
+	if err != nil {
+		return res.Failure(err)
+	}
+	result := res.Register(b.runCode(code, evalCtx, execCtx))
+	if res.ShouldReturn() {
+		return res
+	}
+
+	if result.Array() != nil {
+		for idx := len(result.Array().Elements) - 1; idx >= 0; idx-- {
+			if element := result.Array().Elements[idx]; element != nil && !element.IsEmpty() {
+				return res.Success(element)
+			}
+		}
+	}
+	return res.Success(NewNull())
+}
+
+// ExecuteExec runs statements. Without an environment they run in the scope of the caller and their declarations stay
+// visible there, with an environment the scope is isolated and exec returns its variables as [name, value] pairs.
+func (b *BuildInFunction) ExecuteExec(execCtx *Context) *RTResult {
+	res := NewRTResult()
+	code, _, _ := execCtx.SymbolTable.Get("code")
+	env, _, _ := execCtx.SymbolTable.Get("env")
+
+	evalCtx, err := b.evalContext("<exec>", env, execCtx)
+	if err != nil {
+		return res.Failure(err)
+	}
+	res.Register(b.runCode(code, evalCtx, execCtx))
+	if res.ShouldReturn() {
+		return res
+	}
+
+	if env.Null() != nil {
+		return res.Success(NewNull())
+	}
+	evalCtx.SymbolTable.mu.RLock()
+	defer evalCtx.SymbolTable.mu.RUnlock()
+	var pairs []*Value
+	for _, symbols := range []map[string]*Value{evalCtx.SymbolTable.symbols, evalCtx.SymbolTable.constants} {
+		for name, value := range symbols {
+			pairs = append(pairs, NewArray([]*Value{NewString(name), value.Share()}))
+		}
+	}
+	sortPairs(pairs)
+	return res.Success(NewArray(pairs))
+}
+
+// evalContext returns the context evaluated code runs in, a child of the caller entered at the call of eval or exec.
+// A null environment shares the scope of the caller, an array of [name, value] pairs creates an isolated scope that
+// only sees these names and the build-in functions.
+func (b *BuildInFunction) evalContext(displayName string, env *Value, execCtx *Context) (*Context, *RuntimeError) {
+	if env.Null() != nil {
+		evalCtx := *execCtx.Parent
+		evalCtx.DisplayName, evalCtx.Parent, evalCtx.ParentEntryPos = displayName, execCtx.Parent, execCtx.ParentEntryPos
+		evalCtx.Depth = execCtx.Parent.Depth + 1
+		return &evalCtx, nil
+	}
+
+	invalid := NewRTError(b.Base.PosStart(), b.Base.PosEnd(), "Environment must be null or an array of [name, value] pairs", execCtx)
+	if env.Array() == nil {
+		return nil, invalid
+	}
+
+	evalCtx := NewContext(displayName, execCtx.Parent, execCtx.ParentEntryPos)
+	evalCtx.SymbolTable = NewIsolatedSymbolTable(buildInSymbolTable())
+	for _, pair := range env.Array().Elements {
+		if pair.Array() == nil || len(pair.Array().Elements) != 2 || pair.Array().Elements[0].String() == nil {
+			return nil, invalid
+		}
+		evalCtx.SymbolTable.Set(pair.Array().Elements[0].String().ValueField, pair.Array().Elements[1].Share(), false)
+	}
+	return evalCtx, nil
+}
+
+// runCode tokenizes, parses and runs code. Positions of syntax and runtime errors are relative to the code string.
+func (b *BuildInFunction) runCode(code *Value, evalCtx *Context, execCtx *Context) *RTResult {
+	res := NewRTResult()
+	if code.String() == nil {
+		return res.Failure(NewRTError(b.Base.PosStart(), b.Base.PosEnd(), fmt.Sprintf("Code must be a String, but got %s", code.Type()), execCtx))
+	}
+
+	fileName := "<" + b.Base.Name + ">"
+	ast := NewLexerParser(NewLexer(fileName, code.String().ValueField)).Parse()
+	if ast.Error != nil {
+		return res.Failure(&RuntimeError{Error: ast.Error, Context: execCtx})
+	}
+	// code in the shared scope of a function sees the local variables of the call
+	resolver := NewResolver(evalCtx.SymbolTable)
+	if nameErrors := resolver.ResolveIn(ast.Node, evalCtx.Frame); len(nameErrors) > 0 {
+		return res.Failure(&RuntimeError{Error: &nameErrors[0].Error, Context: execCtx})
+	}
+	return runNode(NewOptimizer(resolver).Optimize(ast.Node), evalCtx)
+}
+
+// buildInSymbolTable returns a symbol table that only contains the build-in functions and values of the global scope.
+func buildInSymbolTable() *SymbolTable {
+	symbolTable := NewSymbolTable(nil)
+	GlobalSymbolTable.mu.RLock()
+	defer GlobalSymbolTable.mu.RUnlock()
+	for name, value := range GlobalSymbolTable.buildIn {
+		symbolTable.buildIn[name] = value
+	}
+	return symbolTable
+}
+
+// sortPairs orders [name, value] pairs by name.
+func sortPairs(pairs []*Value) {
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Array().Elements[0].String().ValueField < pairs[j].Array().Elements[0].String().ValueField
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalAndExec(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "eval returns the value of the code",
+			source: "var x = 2\nvar out = [eval(\"1 + 2\"), eval(\"x * 3\")]",
+			want:   "[3, 6]",
+		},
+		{
+			name:   "eval in a function sees its parameters",
+			source: "func f(n) {\n\treturn eval(\"n * 10\")\n}\nvar out = f(4)",
+			want:   "40",
+		},
+		{
+			name:   "eval with an environment only sees its names",
+			source: `var out = [eval("a + b", [["a", 1], ["b", 2]]), eval("len([1, 2])", [])]`,
+			want:   "[3, 2]",
+		},
+		{
+			name:    "the isolated scope hides the variables of the caller",
+			source:  "var x = 1\neval(\"x\", [])",
+			wantErr: "Unresolved reference 'x'",
+		},
+		{
+			name:   "exec declares variables in the scope of the caller",
+			source: "var x = 2\nexec(\"var y = x + 1\")\nvar out = y",
+			want:   "3",
+		},
+		{
+			name:   "exec with an environment returns its variables",
+			source: `var out = exec("var z = a * 2", [["a", 5]])`,
+			want:   `[["a", 5], ["z", 10]]`,
+		},
+		{
+			name:    "the environment must contain pairs",
+			source:  `eval("1", [1])`,
+			wantErr: "Environment must be null or an array of [name, value] pairs",
+		},
+		{
+			name:    "code must be a string",
+			source:  `eval(1)`,
+			wantErr: "Code must be a String, but got Number",
+		},
+	})
+}
+
+func TestEvalErrorPositions(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string // the lines of the traceback that name the files
+		ln   int
+		col  int
+	}{
+		{
+			name: "syntax error",
+			code: `"1 +\n  (2"`,
+			want: "File <test>, line 5, in <program>\nFile <test>, line 3, in f\nFile <eval>, line 2, in eval\n",
+			ln:   1,
+			col:  2,
+		},
+		{
+			name: "runtime error",
+			code: `"1 +\n  1 / 0"`,
+			want: "File <test>, line 5, in <program>\nFile <test>, line 3, in f\nFile <eval>, line 2, in <eval>\n",
+			ln:   1,
+			col:  2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := runtimeError(t, "var x = 1\nfunc f() {\n\treturn eval("+test.code+")\n}\nf()", false)
+			if pos := err.PosStart; pos.Src.Fn != "<eval>" || pos.Ln != test.ln || pos.Col != test.col {
+				t.Errorf("error at %s line %d column %d, want <eval> line %d column %d", pos.Src.Fn, pos.Ln, pos.Col, test.ln, test.col)
+			}
+			if traceback := err.generateTraceback(); !strings.Contains(traceback, test.want) {
+				t.Errorf("traceback is\n%s\nwant it to contain\n%s", traceback, test.want)
+			}
+		})
+	}
+}
@@ -69,7 +69,7 @@ func (b *BuildInFunction) executeInput(execCtx *Context) *RTResult {
 func (b *BuildInFunction) executeIsString(execCtx *Context) *RTResult {
 	value, exists, _ := execCtx.SymbolTable.Get("value")
 
-	if exists && value.String != nil {
+	if exists && value.String() != nil {
 		return NewRTResult().Success(NewBoolean(One))
 	} else {
 		return NewRTResult().Success(NewBoolean(Zero))
@@ -1,18 +1,25 @@
 package main
 
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 // Matches checks if the token matches the given type and value
 func (t Token) Matches(tType TokenTypes, value string) bool {
 	return t.Type == tType && t.Value == value
 }
 
 // NewPosition creates a new Position instance.
-func NewPosition(idx, ln, col int, fn, ftxt string) *Position {
-	return &Position{idx, ln, col, fn, ftxt}
+func NewPosition(idx, ln, col int, src *Source) *Position {
+	return &Position{idx, ln, col, src}
 }
 
-// Advance moves the position forward based on the current character.
-func (p *Position) Advance(currentChar byte) *Position {
-	p.Idx++
+// Advance moves the position past the current character. Idx is a byte offset into the source, Col counts runes,
+// so that columns stay correct for characters that take more than one byte.
+func (p *Position) Advance(currentChar rune, width int) *Position {
+	p.Idx += width
 	p.Col++
 
 	if currentChar == '\n' {
@@ -25,7 +32,7 @@ func (p *Position) Advance(currentChar byte) *Position {
 
 // Copy creates a copy of the current position.
 func (p *Position) Copy() *Position {
-	return &Position{p.Idx, p.Ln, p.Col, p.Fn, p.Ftxt}
+	return &Position{p.Idx, p.Ln, p.Col, p.Src}
 }
 
 // NewToken creates a new Token instance.
@@ -38,12 +45,31 @@ func NewToken(typ TokenTypes, value interface{}, posStart, posEnd *Position) *To
 	}
 }
 
+// keywords holds KEYWORDS for fast lookups.
+var keywords = func() map[string]bool {
+	set := make(map[string]bool, len(KEYWORDS))
+	for _, kw := range KEYWORDS {
+		set[kw] = true
+	}
+	return set
+}()
+
+// singleCharTokens maps the ASCII characters that are a token on their own to their type.
+var singleCharTokens = [utf8.RuneSelf]TokenTypes{
+	'\n': TT_NEWLINE, ';': TT_NEWLINE, '+': TT_PLUS, '{': TT_LBRACE, '}': TT_RBRACE, '(': TT_LPAREN, ')': TT_RPAREN,
+	'[': TT_LSQUARE, ']': TT_RSQUARE, '^': TT_POW, ',': TT_COMMA, '.': TT_DOT, '&': TT_AND, '*': TT_STAR,
+	':': TT_COLON, '@': TT_AT, '/': TT_DIV,
+}
+
 // NewLexer creates a new Lexer instance.
 func NewLexer(fn, text string) *Lexer {
+	src := &Source{Fn: fn, Text: text}
 	lexer := &Lexer{
-		Fn:   fn,
+		Src:  src,
 		Text: text,
-		Pos:  NewPosition(-1, 0, -1, fn, text),
+		Pos:  Position{-1, 0, -1, src},
+		// moves the position from -1 to the first character
+		charWidth: 1,
 	}
 	lexer.Advance()
 	return lexer
@@ -51,158 +77,178 @@ func NewLexer(fn, text string) *Lexer {
 
 // Advance moves the lexer forward.
 func (l *Lexer) Advance() {
-	l.Pos.Advance(l.CurrentChar)
-	if l.Pos.Idx < len(l.Text) {
-		l.CurrentChar = l.Text[l.Pos.Idx]
+	l.Pos.Advance(l.CurrentChar, l.charWidth)
+	if l.Pos.Idx >= len(l.Text) {
+		l.CurrentChar, l.charWidth = 0, 1 // Null character
+	} else if c := l.Text[l.Pos.Idx]; c < utf8.RuneSelf {
+		l.CurrentChar, l.charWidth = rune(c), 1
 	} else {
-		l.CurrentChar = 0 // Null character
+		l.CurrentChar, l.charWidth = utf8.DecodeRuneInString(l.Text[l.Pos.Idx:])
+	}
+}
+
+// peek returns the byte after the current character, or 0 at the end of the source.
+func (l *Lexer) peek() byte {
+	if next := l.Pos.Idx + l.charWidth; next < len(l.Text) {
+		return l.Text[next]
+	}
+	return 0
+}
+
+// token creates a token that spans from posStart to posEnd, the token and its positions share one allocation.
+func (l *Lexer) token(typ TokenTypes, value interface{}, posStart, posEnd Position) *Token {
+	lexed := &struct {
+		token    Token
+		posStart Position
+		posEnd   Position
+	}{Token{Type: typ, Value: value}, posStart, posEnd}
+	token := &lexed.token
+	token.PosStart, token.PosEnd = &lexed.posStart, &lexed.posEnd
+	l.lastType = typ
+	if l.trace != nil {
+		l.trace(token)
 	}
+	return token
 }
 
 // MakeTokens tokenizes the input text.
 func (l *Lexer) MakeTokens() ([]*Token, *Error) {
 	var tokens []*Token
+	for {
+		token, err := l.NextToken()
+		if err != nil {
+			return []*Token{}, err
+		}
+		tokens = append(tokens, token)
+		if token.Type == TT_EOF {
+			return tokens, nil
+		}
+	}
+}
 
-	for l.CurrentChar != 0 {
+// NextToken produces the next token of the input text. The tokens end with a newline and TT_EOF, which is
+// returned again by further calls.
+func (l *Lexer) NextToken() (*Token, *Error) {
+	for {
 		if l.CurrentChar == ' ' || l.CurrentChar == '\t' || l.CurrentChar == '\r' { // macOS only uses \r may adapt to register only \r as newline
 			l.Advance()
-		} else if l.CurrentChar == '\n' || l.CurrentChar == ';' /* || l.CurrentChar == '\v'*/ {
-			tokens = append(tokens, NewToken(TT_NEWLINE, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if isDigit(l.CurrentChar) {
-			tokens = append(tokens, l.MakeNumber())
-		} else if isLetter(l.CurrentChar) {
-			tokens = append(tokens, l.MakeIdentifier())
-		} else if l.CurrentChar == '"' {
-			tokens = append(tokens, l.MakeString())
-		} else if l.CurrentChar == '+' {
-			tokens = append(tokens, NewToken(TT_PLUS, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '-' {
-			tokens = append(tokens, NewToken(TT_MINUS, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '{' {
-			tokens = append(tokens, NewToken(TT_LBRACE, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '}' {
-			tokens = append(tokens, NewToken(TT_RBRACE, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '/' {
-			tokens = append(tokens, l.DivisionOrComment())
-		} else if l.CurrentChar == '(' {
-			tokens = append(tokens, NewToken(TT_LPAREN, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == ')' {
-			tokens = append(tokens, NewToken(TT_RPAREN, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '[' {
-			tokens = append(tokens, NewToken(TT_LSQUARE, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == ']' {
-			tokens = append(tokens, NewToken(TT_RSQUARE, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '^' {
-			tokens = append(tokens, NewToken(TT_POW, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '!' {
-			token, err := l.MakeNotEquals()
-			if err != nil {
-				return nil, err
+		} else if l.CurrentChar == '/' && l.peek() == '/' {
+			// a comment runs until the end of the line, the newline still ends the statement
+			for l.CurrentChar != 0 && l.CurrentChar != '\n' {
+				l.Advance()
 			}
-			tokens = append(tokens, token)
-		} else if l.CurrentChar == '=' {
-			tokens = append(tokens, l.MakeEqualsOrArrow())
-		} else if l.CurrentChar == '<' {
-			tokens = append(tokens, l.MakeLessThan())
-		} else if l.CurrentChar == '>' {
-			tokens = append(tokens, l.MakeGreaterThan())
-		} else if l.CurrentChar == ',' {
-			tokens = append(tokens, NewToken(TT_COMMA, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '.' {
-			tokens = append(tokens, NewToken(TT_DOT, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '&' {
-			tokens = append(tokens, NewToken(TT_AND, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
-		} else if l.CurrentChar == '*' {
-			tokens = append(tokens, NewToken(TT_STAR, nil, l.Pos.Copy(), l.Pos.Copy()))
-			l.Advance()
 		} else {
-			posStart := l.Pos.Copy()
-			char := string(l.CurrentChar)
-			l.Advance()
-			return []*Token{}, &NewIllegalCharError(posStart, l.Pos, "'"+char+"'").Error
+			break
+		}
+	}
+
+	posStart := l.Pos
+	if l.CurrentChar == 0 {
+		// wehn newline not last breaks things
+		if l.lastType != TT_NEWLINE && l.lastType != TT_EOF {
+			return l.token(TT_NEWLINE, nil, posStart, posStart), nil
 		}
+		return l.token(TT_EOF, nil, posStart, posStart), nil
 	}
 
-	// wehn newline not last breaks things
-	if tokens[len(tokens)-1].Type != TT_NEWLINE {
-		tokens = append(tokens, NewToken(TT_NEWLINE, nil, l.Pos.Copy(), l.Pos.Copy()))
+	if l.CurrentChar < utf8.RuneSelf && singleCharTokens[l.CurrentChar] != "" {
+		tokenType := singleCharTokens[l.CurrentChar]
+		l.Advance()
+		return l.token(tokenType, nil, posStart, posStart), nil
+	}
+
+	switch {
+	case isDigit(l.CurrentChar):
+		return l.MakeNumber(), nil
+	case isLetter(l.CurrentChar):
+		return l.MakeIdentifier(), nil
 	}
-	tokens = append(tokens, NewToken(TT_EOF, nil, l.Pos.Copy(), l.Pos.Copy()))
-	return tokens, nil
+
+	switch l.CurrentChar {
+	case '"':
+		return l.MakeString(), nil
+	case '-':
+		return l.MakeMinusOrArrow(), nil
+	case '!':
+		return l.MakeNotEquals()
+	case '|':
+		return l.MakePipe()
+	case '?':
+		return l.MakeQuestion(), nil
+	case '=':
+		return l.MakeEqualsOrArrow(), nil
+	case '<':
+		return l.MakeLessThan(), nil
+	case '>':
+		return l.MakeGreaterThan(), nil
+	}
+
+	char := string(l.CurrentChar)
+	l.Advance()
+	return nil, &NewIllegalCharError(&posStart, l.Pos.Copy(), "'"+char+"'").Error
 }
 
-// MakeString parses a string token from the input text
+// MakeString parses a string token from the input text. Strings without escapes are sliced from the source.
 func (l *Lexer) MakeString() *Token {
-	var result string
-	posStart := l.Pos.Copy()
-	escapeCharacter := false
+	posStart := l.Pos
 	l.Advance()
+	start := l.Pos.Idx
 
-	escapeCharacters := map[byte]string{
-		'n': "\n",
-		't': "\t",
+	for l.CurrentChar != 0 && l.CurrentChar != '"' && l.CurrentChar != '\\' {
+		l.Advance()
+	}
+	if l.CurrentChar != '\\' {
+		result := l.Text[start:min(l.Pos.Idx, len(l.Text))]
+		l.Advance()
+		return l.token(TT_STRING, result, posStart, l.Pos)
 	}
 
+	var result strings.Builder
+	result.WriteString(l.Text[start:l.Pos.Idx])
+	escapeCharacter := false
 	for l.CurrentChar != 0 && (l.CurrentChar != '"' || escapeCharacter) {
 		if escapeCharacter {
-			if val, ok := escapeCharacters[l.CurrentChar]; ok {
-				result += val
-			} else {
-				result += string(l.CurrentChar)
+			switch l.CurrentChar {
+			case 'n':
+				result.WriteByte('\n')
+			case 't':
+				result.WriteByte('\t')
+			default:
+				result.WriteRune(l.CurrentChar)
 			}
 			escapeCharacter = false
+		} else if l.CurrentChar == '\\' {
+			escapeCharacter = true
 		} else {
-			if l.CurrentChar == '\\' {
-				escapeCharacter = true
-			} else {
-				result += string(l.CurrentChar)
-			}
+			result.WriteRune(l.CurrentChar)
 		}
 		l.Advance()
 	}
 	l.Advance()
 
-	return &Token{Type: TT_STRING, Value: result, PosStart: posStart, PosEnd: l.Pos}
+	return l.token(TT_STRING, result.String(), posStart, l.Pos)
 }
 
 func (l *Lexer) MakeIdentifier() *Token {
-	idStr := ""
-	posStart := l.Pos.Copy()
+	posStart := l.Pos
 
 	for l.CurrentChar != 0 && (isLetter(l.CurrentChar) || isDigit(l.CurrentChar) || l.CurrentChar == '_') {
-		idStr += string(l.CurrentChar)
 		l.Advance()
 	}
 
-	posEnd := l.Pos.Copy().Advance(l.CurrentChar)
-	posEnd.Col = posEnd.Col - 1
-	posEnd.Idx = posEnd.Idx - 1
+	idStr := l.Text[posStart.Idx:l.Pos.Idx]
 	tokenType := TT_IDENTIFIER
 	if isKeyword(idStr) {
 		tokenType = TT_KEYWORD
 	}
 
-	return NewToken(tokenType, idStr, posStart, posEnd)
+	return l.token(tokenType, idStr, posStart, l.Pos)
 }
 
 // MakeNumber tokenizes a number.
 func (l *Lexer) MakeNumber() *Token {
-	numStr := ""
 	dotCount := 0
-	posStart := l.Pos.Copy()
+	posStart := l.Pos
 
 	for l.CurrentChar != 0 && (isDigit(l.CurrentChar) || l.CurrentChar == '.') {
 		if l.CurrentChar == '.' {
@@ -210,38 +256,85 @@ func (l *Lexer) MakeNumber() *Token {
 				break
 			}
 			dotCount++
-			numStr += "."
-		} else {
-			numStr += string(l.CurrentChar)
 		}
 		l.Advance()
 	}
+	numStr := l.Text[posStart.Idx:l.Pos.Idx]
 
-	posEnd := l.Pos.Copy()
+	// a 'd' suffix marks an exact decimal literal like 12.50d
+	decimal := l.CurrentChar == 'd'
+	if decimal {
+		l.Advance()
+	}
+
+	posEnd := l.Pos
 	posEnd.Col = posEnd.Col - 1
 	posEnd.Idx = posEnd.Idx - 1
 
+	if decimal {
+		return l.token(TT_DECIMAL, numStr, posStart, posEnd)
+	}
 	if dotCount == 0 {
-		return NewToken(TT_INT, numStr, posStart, posEnd)
+		return l.token(TT_INT, numStr, posStart, posEnd)
 	}
-	return NewToken(TT_FLOAT, numStr, posStart, posEnd)
+	return l.token(TT_FLOAT, numStr, posStart, posEnd)
 }
 
 func (l *Lexer) MakeNotEquals() (*Token, *Error) {
-	PosStart := l.Pos.Copy()
+	PosStart := l.Pos
 	l.Advance()
 
 	if l.CurrentChar == '=' {
 		l.Advance()
-		return NewToken(TT_NE, nil, PosStart, l.Pos), nil
+		return l.token(TT_NE, nil, PosStart, l.Pos), nil
+	}
+	l.Advance()
+	return nil, &NewExpectedCharError(&PosStart, l.Pos.Copy(), "'=' (after '!')").Error
+}
+
+func (l *Lexer) MakePipe() (*Token, *Error) {
+	PosStart := l.Pos
+	l.Advance()
+
+	if l.CurrentChar == '>' {
+		l.Advance()
+		return l.token(TT_PIPE, nil, PosStart, l.Pos), nil
 	}
 	l.Advance()
-	return nil, &NewExpectedCharError(PosStart, l.Pos, "'=' (after '!')").Error
+	return nil, &NewExpectedCharError(&PosStart, l.Pos.Copy(), "'>' (after '|')").Error
+}
+
+// MakeMinusOrArrow tokenizes '-' and the return type arrow '->'.
+func (l *Lexer) MakeMinusOrArrow() *Token {
+	PosStart := l.Pos
+	l.Advance()
+
+	if l.CurrentChar == '>' {
+		l.Advance()
+		return l.token(TT_RARROW, nil, PosStart, l.Pos)
+	}
+	return l.token(TT_MINUS, nil, PosStart, PosStart)
+}
+
+// MakeQuestion tokenizes '?', the optional chaining '?.' and the null-coalescing '??'.
+func (l *Lexer) MakeQuestion() *Token {
+	TokenType := TT_QUESTION
+	PosStart := l.Pos
+	l.Advance()
+
+	if l.CurrentChar == '?' {
+		l.Advance()
+		TokenType = TT_NULLISH
+	} else if l.CurrentChar == '.' {
+		l.Advance()
+		TokenType = TT_QDOT
+	}
+	return l.token(TokenType, nil, PosStart, l.Pos)
 }
 
 func (l *Lexer) MakeEqualsOrArrow() *Token {
 	TokenType := TT_EQ
-	PosStart := l.Pos.Copy()
+	PosStart := l.Pos
 	l.Advance()
 
 	if l.CurrentChar == '=' {
@@ -251,63 +344,44 @@ func (l *Lexer) MakeEqualsOrArrow() *Token {
 		l.Advance()
 		TokenType = TT_ARROW
 	}
-	return NewToken(TokenType, nil, PosStart, l.Pos)
+	return l.token(TokenType, nil, PosStart, l.Pos)
 }
 
 func (l *Lexer) MakeLessThan() *Token {
 	TokenType := TT_LT
-	PosStart := l.Pos.Copy()
+	PosStart := l.Pos
 	l.Advance()
 	if l.CurrentChar == '=' {
 		l.Advance()
 		TokenType = TT_LTE
 	}
 
-	return NewToken(TokenType, nil, PosStart, l.Pos)
+	return l.token(TokenType, nil, PosStart, l.Pos)
 }
 
 func (l *Lexer) MakeGreaterThan() *Token {
 	TokenType := TT_GT
-	PosStart := l.Pos.Copy()
+	PosStart := l.Pos
 	l.Advance()
 	if l.CurrentChar == '=' {
 		l.Advance()
 		TokenType = TT_GTE
 	}
-	return NewToken(TokenType, nil, PosStart, l.Pos)
+	return l.token(TokenType, nil, PosStart, l.Pos)
 }
 
-func (l *Lexer) DivisionOrComment() *Token {
-	posStart := l.Pos.Copy()
-	tokenType := TT_DIV
-	l.Advance()
-
-	if l.CurrentChar == '/' {
-		for l.CurrentChar != '\r' {
-			l.Advance()
-		}
-		// TODO comment functionality
-		return nil
-	}
-
-	l.Advance()
-
-	return &Token{tokenType, nil, posStart, l.Pos}
-}
-
-func isDigit(char byte) bool {
+func isDigit(char rune) bool {
 	return char >= '0' && char <= '9'
 }
 
-func isLetter(char byte) bool {
-	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+// isLetter accepts letters of any script, identifiers like 'größe' are valid.
+func isLetter(char rune) bool {
+	if char < utf8.RuneSelf {
+		return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+	}
+	return unicode.IsLetter(char)
 }
 
 func isKeyword(str string) bool {
-	for _, kw := range KEYWORDS {
-		if kw == str {
-			return true
-		}
-	}
-	return false
+	return keywords[str]
 }
@@ -0,0 +1,126 @@
+package main
+
+import "testing"
+
+func TestLexerDecodesUTF8(t *testing.T) {
+	tokens, err := NewLexer("<test>", `var größe = "äö" + x`).MakeTokens()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Details)
+	}
+
+	tests := []struct {
+		typ      TokenTypes
+		value    interface{}
+		col, idx int
+	}{
+		{TT_KEYWORD, "var", 0, 0},
+		{TT_IDENTIFIER, "größe", 4, 4},
+		{TT_EQ, nil, 10, 12},
+		{TT_STRING, "äö", 12, 14},
+		{TT_PLUS, nil, 17, 21},
+		{TT_IDENTIFIER, "x", 19, 23},
+	}
+	for i, test := range tests {
+		token := tokens[i]
+		if token.Type != test.typ || token.Value != test.value {
+			t.Fatalf("token %d = %s %v, want %s %v", i, token.Type, token.Value, test.typ, test.value)
+		}
+		if token.PosStart.Col != test.col || token.PosStart.Idx != test.idx {
+			t.Errorf("token %d starts at column %d, index %d, want column %d, index %d", i, token.PosStart.Col, token.PosStart.Idx, test.col, test.idx)
+		}
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "len counts characters",
+			source: `var out = [len("größe"), len("日本語"), len("")]`,
+			want:   "[5, 3, 0]",
+		},
+		{
+			name:   "indexing returns characters",
+			source: `var s = "日本語"` + "\nvar out = [s[0], s[2]]",
+			want:   `["日", "語"]`,
+		},
+		{
+			name:   "slicing counts characters",
+			source: `var out = [slice("größe", 1, 4), slice([1, 2, 3], 1, 3)]`,
+			want:   `["röß", [2, 3]]`,
+		},
+		{
+			name:   "identifiers may contain letters of any script",
+			source: "var größe = 3\nvar out = größe",
+			want:   "3",
+		},
+		{
+			name:    "indices past the last character are out of bounds",
+			source:  `var out = "äö"[2]`,
+			wantErr: "Character at index 2 could not be retrieved from string",
+		},
+		{
+			name:    "invalid slice indices are rejected",
+			source:  `var out = slice("äö", 1, 3)`,
+			wantErr: "Invalid slice indices 1 and 3 for a string of length 2",
+		},
+	})
+}
+
+func TestNextToken(t *testing.T) {
+	source := "var s = \"a\\tb\"\nfunc f(x) => x * 2.5"
+	tokens, err := NewLexer("<test>", source).MakeTokens()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Details)
+	}
+
+	lexer := NewLexer("<test>", source)
+	for i, want := range tokens {
+		got, err := lexer.NextToken()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Details)
+		}
+		if got.Type != want.Type || got.Value != want.Value || got.PosStart.Idx != want.PosStart.Idx || got.PosEnd.Idx != want.PosEnd.Idx {
+			t.Fatalf("token %d = %s %v, want %s %v", i, got.Type, got.Value, want.Type, want.Value)
+		}
+	}
+	if got, _ := lexer.NextToken(); got.Type != TT_EOF {
+		t.Errorf("token after the end = %s, want %s", got.Type, TT_EOF)
+	}
+
+	// all positions refer to the one source
+	for _, token := range tokens {
+		if token.PosStart.Src != tokens[0].PosStart.Src || token.PosEnd.Src != tokens[0].PosStart.Src {
+			t.Fatalf("%s token refers to another source", token.Type)
+		}
+	}
+	if src := tokens[0].PosStart.Src; src.Fn != "<test>" || src.Text != source {
+		t.Errorf("source = %q %q, want %q %q", src.Fn, src.Text, "<test>", source)
+	}
+}
+
+func TestParserReadsTokensOnDemand(t *testing.T) {
+	lexer := NewLexer("<test>", "var a = 1\nvar b = 2\nvar c = 3")
+	lexed := 0
+	lexer.trace = func(*Token) { lexed++ }
+
+	parser := NewLexerParser(lexer)
+	if lexed != 1 {
+		t.Errorf("lexed %d tokens before parsing, want 1", lexed)
+	}
+	if ast := parser.Parse(); ast.Error != nil {
+		t.Fatalf("unexpected syntax error: %s", ast.Error.Details)
+	}
+	if tokens, _ := NewLexer("<test>", "var a = 1\nvar b = 2\nvar c = 3").MakeTokens(); lexed != len(tokens) {
+		t.Errorf("lexed %d tokens, want all %d", lexed, len(tokens))
+	}
+}
+
+func TestLexErrorsEndTheTokens(t *testing.T) {
+	ast := NewLexerParser(NewLexer("<test>", "var a = 1\nvar b = $")).Parse()
+	if ast.Error == nil {
+		t.Fatal("expected an error")
+	}
+	if ast.Error.ErrorName != "Illegal Character" || ast.Error.Details != "'$'" || ast.Error.PosStart.Ln != 1 || ast.Error.PosStart.Col != 8 {
+		t.Errorf("error = %s: %s at line %d column %d, want Illegal Character: '$' at line 1 column 8", ast.Error.ErrorName, ast.Error.Details, ast.Error.PosStart.Ln, ast.Error.PosStart.Col)
+	}
+}
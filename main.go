@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 )
@@ -18,6 +19,7 @@ import (
 const (
 	TT_INT        TokenTypes = "INT"
 	TT_FLOAT      TokenTypes = "FLOAT"
+	TT_DECIMAL    TokenTypes = "DECIMAL"
 	TT_STRING     TokenTypes = "STRING"
 	TT_IDENTIFIER TokenTypes = "IDENTIFIER"
 	TT_KEYWORD    TokenTypes = "KEYWORD"
@@ -45,50 +47,101 @@ const (
 	TT_DOT        TokenTypes = "DOT"
 	TT_AND        TokenTypes = "AND"
 	TT_STAR       TokenTypes = "STAR"
+	TT_COLON      TokenTypes = "COLON"
+	TT_PIPE       TokenTypes = "PIPE"
+	TT_QUESTION   TokenTypes = "QUESTION"
+	TT_QDOT       TokenTypes = "QDOT"
+	TT_NULLISH    TokenTypes = "NULLISH"
+	TT_RARROW     TokenTypes = "RARROW"
+	TT_AT         TokenTypes = "AT"
 	Zero          Binary     = 0
 	One           Binary     = 1
 )
 
-var KEYWORDS = []string{"var", "and", "or", "not", "if", "else", "elif", "for", "to", "step", "while", "func", "return", "continue", "break", "import", "from", "const"}
+var KEYWORDS = []string{"var", "and", "or", "not", "if", "else", "elif", "for", "to", "step", "while", "func", "return", "continue", "break", "import", "from", "const", "spawn", "defer", "in"}
 var GlobalSymbolTable = NewSymbolTable(nil)
 var memory *Memory
 
 func run(fileName, text string) (*Value, *RuntimeError) {
 	lexer := NewLexer(fileName, text)
-
-	tokens, err := lexer.MakeTokens()
-	if err != nil {
-		fmt.Println(err.AsString())
-		return nil, nil
-	}
-
-	for _, token := range tokens {
+	lexer.trace = func(token *Token) {
 		if token.PosStart != nil && token.PosEnd != nil {
 			fmt.Println(token.Type, token.Value)
 		} else {
 			fmt.Println(token.Type)
 		}
 	}
-	parser := NewParser(tokens)
+	parser := NewLexerParser(lexer)
 	ast := parser.Parse()
 	if ast.Error != nil {
-		fmt.Println(ast.Error.AsString())
+		for _, syntaxError := range parser.Errors {
+			fmt.Println(syntaxError.AsString())
+		}
+		return nil, nil
+	}
+	resolver := NewResolver(GlobalSymbolTable)
+	if nameErrors := resolver.Resolve(ast.Node); len(nameErrors) > 0 {
+		for _, nameError := range nameErrors {
+			fmt.Println(nameError.AsString())
+		}
 		return nil, nil
 	}
+	ast.Node = NewOptimizer(resolver).Optimize(ast.Node)
 	// TODO fix pos:
 	//IDENTIFIER input START: {0 0 0 file.ecp input()} END: {5 0 5 file.ecp input()}
 	//LPAREN <nil> START: {5 0 5 file.ecp input()} END: {5 0 5 file.ecp input()}
 	//RPAREN <nil> START: {6 0 6 file.ecp input()} END: {6 0 6 file.ecp input()}
 	context := NewContext("<program>", nil, nil)
 	context.SymbolTable = GlobalSymbolTable
-	interpreter := NewInterpreter()
 	log.Println("ast:", ast.Node)
-	result := interpreter.visit(ast.Node, context)
+	result := runNode(ast.Node, context)
 	return result.Value, result.Error
 }
 
-func main() {
-	memory = NewMemory()
+// readSourceFile reads a script from the given path and returns its file name and cleaned source code.
+func readSourceFile(arg string) (string, string, bool) {
+	filePath, _ := filepath.Abs(arg)
+	fileName := path.Base(filePath)
+	file, err := os.Open(filePath)
+	if err != nil {
+		fmt.Println("Invalid path, cannot open specified file.")
+		return "", "", false
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+
+	cleanedSourceCode := strings.ReplaceAll(string(content), "\v", "")
+	if err != nil {
+		fmt.Println("Error: cannot read specified file.")
+		return "", "", false
+	}
+	return fileName, cleanedSourceCode, true
+}
+
+// Check parses a script and reports unknown names and type annotation mismatches without running it, it returns false if any were found.
+func Check(fileName, text string) bool {
+	parser := NewLexerParser(NewLexer(fileName, text))
+	ast := parser.Parse()
+	if ast.Error != nil {
+		for _, syntaxError := range parser.Errors {
+			fmt.Println(syntaxError.AsString())
+		}
+		return false
+	}
+
+	nameErrors := NewResolver(GlobalSymbolTable).Resolve(ast.Node)
+	for _, nameError := range nameErrors {
+		fmt.Println(nameError.AsString())
+	}
+	typeErrors := NewTypeChecker().Check(ast.Node)
+	for _, typeError := range typeErrors {
+		fmt.Println(typeError.AsString())
+	}
+	return len(nameErrors) == 0 && len(typeErrors) == 0
+}
+
+// registerBuildIns declares the build-in values and functions in the global symbol table.
+func registerBuildIns() {
 	GlobalSymbolTable.SetBuildIn("null", NewNull())
 	GlobalSymbolTable.SetBuildIn("false", NewBoolean(0))
 	GlobalSymbolTable.SetBuildIn("true", NewBoolean(1))
@@ -104,25 +157,80 @@ func main() {
 	GlobalSymbolTable.SetBuildIn("pop", NewBuildInFunction("pop"))
 	GlobalSymbolTable.SetBuildIn("str", NewBuildInFunction("str"))
 	GlobalSymbolTable.SetBuildIn("num", NewBuildInFunction("num"))
+	GlobalSymbolTable.SetBuildIn("split", NewBuildInFunction("split"))
+	GlobalSymbolTable.SetBuildIn("join", NewBuildInFunction("join"))
+	GlobalSymbolTable.SetBuildIn("freeze", NewBuildInFunction("freeze"))
+	GlobalSymbolTable.SetBuildIn("isFrozen", NewBuildInFunction("isFrozen"))
+	GlobalSymbolTable.SetBuildIn("clone", NewBuildInFunction("clone"))
+	GlobalSymbolTable.SetBuildIn("deepClone", NewBuildInFunction("deepClone"))
+	GlobalSymbolTable.SetBuildIn("memoize", NewBuildInFunction("memoize"))
+	GlobalSymbolTable.SetBuildIn("trace", NewBuildInFunction("trace"))
+	GlobalSymbolTable.SetBuildIn("deprecated", NewBuildInFunction("deprecated"))
+	GlobalSymbolTable.SetBuildIn("eval", NewBuildInFunction("eval"))
+	GlobalSymbolTable.SetBuildIn("exec", NewBuildInFunction("exec"))
+	GlobalSymbolTable.SetBuildIn("typeOf", NewBuildInFunction("typeOf"))
+	GlobalSymbolTable.SetBuildIn("int", NewBuildInFunction("int"))
+	GlobalSymbolTable.SetBuildIn("float", NewBuildInFunction("float"))
+	GlobalSymbolTable.SetBuildIn("bool", NewBuildInFunction("bool"))
+	GlobalSymbolTable.SetBuildIn("bytes", NewBuildInFunction("bytes"))
+	GlobalSymbolTable.SetBuildIn("chr", NewBuildInFunction("chr"))
+	GlobalSymbolTable.SetBuildIn("ord", NewBuildInFunction("ord"))
+	GlobalSymbolTable.SetBuildIn("funcName", NewBuildInFunction("funcName"))
+	GlobalSymbolTable.SetBuildIn("funcParams", NewBuildInFunction("funcParams"))
+	GlobalSymbolTable.SetBuildIn("funcArity", NewBuildInFunction("funcArity"))
+	GlobalSymbolTable.SetBuildIn("funcFile", NewBuildInFunction("funcFile"))
+	GlobalSymbolTable.SetBuildIn("funcLine", NewBuildInFunction("funcLine"))
+	GlobalSymbolTable.SetBuildIn("slice", NewBuildInFunction("slice"))
+	GlobalSymbolTable.SetBuildIn("decimal", NewBuildInFunction("decimal"))
+	GlobalSymbolTable.SetBuildIn("round", NewBuildInFunction("round"))
+	GlobalSymbolTable.SetBuildIn("setDecimalScale", NewBuildInFunction("setDecimalScale"))
+	GlobalSymbolTable.SetBuildIn("setDecimalRounding", NewBuildInFunction("setDecimalRounding"))
+	GlobalSymbolTable.SetBuildIn("await", NewBuildInFunction("await"))
+	GlobalSymbolTable.SetBuildIn("channel", NewBuildInFunction("channel"))
+	GlobalSymbolTable.SetBuildIn("send", NewBuildInFunction("send"))
+	GlobalSymbolTable.SetBuildIn("receive", NewBuildInFunction("receive"))
+	GlobalSymbolTable.SetBuildIn("close", NewBuildInFunction("close"))
+	GlobalSymbolTable.SetBuildIn("select", NewBuildInFunction("select"))
+}
 
-	if len(os.Args) >= 2 {
-		filePath, _ := filepath.Abs(os.Args[1])
-		fileName := path.Base(filePath)
-		file, err := os.Open(filePath)
-		if err != nil {
-			fmt.Println("Invalid path, cannot open specified file.")
-			return
+func main() {
+	memory = NewMemory()
+	registerBuildIns()
+
+	// options come before the file:
+	// '--tree-walker' runs the program without compiling it to bytecode, e.g. to compare results with the VM
+	// '--max-depth=N' sets the number of nested calls after which a call fails
+	for len(os.Args) >= 2 && strings.HasPrefix(os.Args[1], "--") {
+		if os.Args[1] == "--tree-walker" {
+			useTreeWalker = true
+		} else if depth, ok := strings.CutPrefix(os.Args[1], "--max-depth="); ok {
+			limit, err := strconv.Atoi(depth)
+			if err != nil || limit < 1 {
+				fmt.Println("Invalid maximum call depth '" + depth + "'")
+				os.Exit(1)
+			}
+			maxCallDepth = limit
+		} else {
+			break
 		}
-		defer file.Close()
-		content, err := io.ReadAll(file)
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 
-		cleanedSourceCode := strings.ReplaceAll(string(content), "\v", "")
-		if err != nil {
-			fmt.Println("Error: cannot read specified file.")
+	if len(os.Args) >= 3 && os.Args[1] == "check" {
+		fileName, source, ok := readSourceFile(os.Args[2])
+		if !ok {
+			return
+		}
+		if !Check(fileName, source) {
+			os.Exit(1)
+		}
+	} else if len(os.Args) >= 2 {
+		fileName, source, ok := readSourceFile(os.Args[1])
+		if !ok {
 			return
 		}
 
-		Scan(fileName, cleanedSourceCode)
+		Scan(fileName, source)
 	} else {
 		for {
 			buf := make([]byte, 1024)
@@ -153,26 +261,26 @@ func Scan(fileName string, line string) {
 		fmt.Println(err.AsString())
 		return
 	} else if result != nil {
-		if result.Number != nil {
-			fmt.Println(result.Number.ValueField)
-		} else if result.Function != nil {
-			fmt.Println(result.Function.String())
-		} else if result.String != nil {
-			fmt.Println(result.String.ValueField)
-		} else if result.Array != nil {
-			if len(result.Array.Elements) == 1 && result.Array.Elements[0] != nil {
-				if result.Array.Elements[0].Array != nil {
-					fmt.Println(result.Array.Elements[0].Array.String())
+		if result.Number() != nil {
+			fmt.Println(result.Number().Value())
+		} else if result.Function() != nil {
+			fmt.Println(result.Function().String())
+		} else if result.String() != nil {
+			fmt.Println(result.String().ValueField)
+		} else if result.Array() != nil {
+			if len(result.Array().Elements) == 1 && result.Array().Elements[0] != nil {
+				if result.Array().Elements[0].Array() != nil {
+					fmt.Println(result.Array().Elements[0].Array().String())
 				} else {
-					fmt.Println(result.Array.Elements[0].Value())
+					fmt.Println(result.Array().Elements[0].Value())
 				}
 			} else {
-				fmt.Println(result.Array.String())
+				fmt.Println(result.Array().String())
 			}
-		} else if result.Boolean != nil {
-			fmt.Println(result.Boolean.String())
+		} else if result.Boolean() != nil {
+			fmt.Println(result.Boolean().String())
 		} else {
-			fmt.Println(result.Null.String())
+			fmt.Println(result.Null().String())
 		}
 
 	}
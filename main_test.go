@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	memory = NewMemory()
+	registerBuildIns()
+	os.Exit(m.Run())
+}
+
+// programTest runs source and compares the global variable out, or the first error, with the expected text.
+type programTest struct {
+	name    string
+	source  string
+	want    string
+	wantErr string
+}
+
+// runProgram parses, resolves and runs source in a fresh global scope and returns that scope.
+func runProgram(source string, treeWalker bool) (*Context, string) {
+	parser := NewLexerParser(NewLexer("<test>", source))
+	ast := parser.Parse()
+	if ast.Error != nil {
+		details := make([]string, len(parser.Errors))
+		for i, syntaxError := range parser.Errors {
+			details[i] = syntaxError.Details
+		}
+		return nil, strings.Join(details, "\n")
+	}
+	context := NewContext("<program>", nil, nil)
+	context.SymbolTable = buildInSymbolTable()
+	resolver := NewResolver(context.SymbolTable)
+	if nameErrors := resolver.Resolve(ast.Node); len(nameErrors) > 0 {
+		return nil, nameErrors[0].Details
+	}
+	useTreeWalker = treeWalker
+	defer func() { useTreeWalker = false }()
+	if result := runNode(NewOptimizer(resolver).Optimize(ast.Node), context); result.Error != nil {
+		return nil, result.Error.Details
+	}
+	return context, ""
+}
+
+// runtimeError runs source, which has to be free of syntax and name errors, and returns the error it fails with.
+func runtimeError(t *testing.T, source string, treeWalker bool) *RuntimeError {
+	t.Helper()
+	ast := NewLexerParser(NewLexer("<test>", source)).Parse()
+	if ast.Error != nil {
+		t.Fatalf("unexpected syntax error: %s", ast.Error.Details)
+	}
+	context := NewContext("<program>", nil, nil)
+	context.SymbolTable = buildInSymbolTable()
+	resolver := NewResolver(context.SymbolTable)
+	if nameErrors := resolver.Resolve(ast.Node); len(nameErrors) > 0 {
+		t.Fatalf("unexpected name error: %s", nameErrors[0].Details)
+	}
+	useTreeWalker = treeWalker
+	defer func() { useTreeWalker = false }()
+	result := runNode(NewOptimizer(resolver).Optimize(ast.Node), context)
+	if result.Error == nil {
+		t.Fatal("expected an error")
+	}
+	return result.Error
+}
+
+// runProgramTests runs every test with the bytecode VM and with the tree walker.
+func runProgramTests(t *testing.T, tests []programTest) {
+	t.Helper()
+	for _, backend := range []struct {
+		name       string
+		treeWalker bool
+	}{{"vm", false}, {"tree-walker", true}} {
+		for _, test := range tests {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				context, err := runProgram(test.source, backend.treeWalker)
+				if test.wantErr != "" {
+					if !strings.Contains(err, test.wantErr) {
+						t.Fatalf("error = %q, want it to contain %q", err, test.wantErr)
+					}
+					return
+				}
+				if err != "" {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				out, exists, _ := context.SymbolTable.Get("out")
+				if !exists {
+					t.Fatalf("program did not set out")
+				}
+				if got := string(interfaceToBytes(out.Value())); got != test.want {
+					t.Errorf("out = %q, want %q", got, test.want)
+				}
+			})
+		}
+	}
+}
@@ -8,8 +8,8 @@ import (
 func (b *BuildInFunction) Cos(execCtx *Context) *RTResult {
 	res := NewRTResult()
 	value, exists, _ := execCtx.SymbolTable.Get("x")
-	if exists && value.Number != nil {
-		switch v := value.Number.ValueField.(type) {
+	if exists && value.Number() != nil {
+		switch v := value.Number().Value().(type) {
 		case float64:
 			return res.Success(NewNumber(math.Cos(v)))
 		case int:
@@ -25,8 +25,8 @@ func (b *BuildInFunction) Sin(execCtx *Context) *RTResult {
 	res := NewRTResult()
 
 	value, exists, _ := execCtx.SymbolTable.Get("x")
-	if exists && value.Number != nil {
-		switch v := value.Number.ValueField.(type) {
+	if exists && value.Number() != nil {
+		switch v := value.Number().Value().(type) {
 		case float64:
 			return res.Success(NewNumber(math.Sin(v)))
 		case int:
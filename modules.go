@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// modules holds the packages imported in this run, keyed by the absolute path of their file.
+var modules = struct {
+	sync.Mutex
+	loaded map[string]*Module
+}{loaded: map[string]*Module{}}
+
+// importModule returns the context of the evaluated package name. The package is evaluated by its first import,
+// later imports wait for it to finish and share its exports.
+func importModule(name string, node ImportNode, context *Context) (*Context, *RuntimeError) {
+	path, err := filepath.Abs(name + ".ecp")
+	if err != nil {
+		path = name + ".ecp"
+	}
+
+	modules.Lock()
+	module, loaded := modules.loaded[path]
+	if !loaded {
+		packageContext := NewContext(fmt.Sprintf("<package %v>", name), context, node.PositionStart)
+		packageContext.SymbolTable = NewSymbolTable(nil)
+		module = &Module{Context: packageContext, done: make(chan struct{})}
+		modules.loaded[path] = module
+	}
+	modules.Unlock()
+
+	if loaded {
+		select {
+		case <-module.done:
+		default:
+			for ctx := context; ctx != nil; ctx = ctx.Parent {
+				if ctx == module.Context {
+					return nil, NewRTError(node.PositionStart, node.PositionEnd, fmt.Sprintf("Circular import of package '%s'", name), context)
+				}
+			}
+			<-module.done
+		}
+		if module.Error != nil {
+			return nil, module.Error
+		}
+		return module.Context, nil
+	}
+
+	module.Error = evaluateModule(name, node, module.Context, context)
+	if module.Error != nil {
+		// a failed import is tried again by the next one
+		modules.Lock()
+		delete(modules.loaded, path)
+		modules.Unlock()
+	}
+	close(module.done)
+	return module.Context, module.Error
+}
+
+// evaluateModule runs the package name in packageContext, its AST is read from the module cache if possible.
+func evaluateModule(name string, node ImportNode, packageContext *Context, context *Context) *RuntimeError {
+	fileName := name + ".ecp"
+	packageContent, err := LoadPackage(name)
+	if err != nil {
+		return NewRTError(
+			node.PositionStart, node.PositionEnd,
+			fmt.Sprintf("Failed to load package, no package named '%s', ", name),
+			context,
+		)
+	}
+
+	packageNode := readCachedAST(fileName, packageContent)
+	if packageNode == nil {
+		parser := NewLexerParser(NewLexer(fileName, packageContent))
+		packageAst := parser.Parse()
+		if parser.LexError != nil {
+			return NewRTError(node.PositionStart, node.PositionEnd, fmt.Sprintf("Error tokenizing imported package %v", name), context)
+		}
+		if packageAst.Error != nil {
+			return NewRTError(packageAst.Error.PosStart, packageAst.Error.PosEnd, packageAst.Error.Details, packageContext)
+		}
+		packageNode = packageAst.Node
+		writeCachedAST(packageContent, packageNode)
+	}
+
+	resolver := NewResolver(packageContext.SymbolTable)
+	if nameErrors := resolver.Resolve(packageNode); len(nameErrors) > 0 {
+		return &RuntimeError{Error: &nameErrors[0].Error, Context: packageContext}
+	}
+	packageNode = NewOptimizer(resolver).Optimize(packageNode)
+
+	return runNode(packageNode, packageContext).Error
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePackages writes the given packages into a new directory and makes it the working directory of the test.
+func writePackages(t *testing.T, packages map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range packages {
+		if err := os.WriteFile(filepath.Join(dir, name+".ecp"), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestModules(t *testing.T) {
+	writePackages(t, map[string]string{
+		"shared": "var value = 1",
+		"other":  "import value from \"shared\"\nvar twice = value * 2",
+		"cycleA": "import b from \"cycleB\"\nvar a = 1",
+		"cycleB": "import a from \"cycleA\"\nvar b = 2",
+		"broken": "var x = (1",
+	})
+
+	runProgramTests(t, []programTest{
+		{
+			name:   "a package can be imported by several packages",
+			source: "import value from \"shared\"\nimport twice from \"other\"\nvar out = [value, twice]",
+			want:   "[1, 2]",
+		},
+		{
+			name:    "circular imports are reported",
+			source:  "import a from \"cycleA\"",
+			wantErr: "Circular import of package 'cycleA'",
+		},
+		{
+			name:    "syntax errors of a package are reported",
+			source:  "import x from \"broken\"",
+			wantErr: "'(' was never closed",
+		},
+		{
+			name:    "missing packages are reported",
+			source:  "import x from \"missing\"",
+			wantErr: "no package named 'missing'",
+		},
+	})
+}
+
+func TestModuleIsEvaluatedOnce(t *testing.T) {
+	writePackages(t, map[string]string{"once": "var value = [1, 2]"})
+	context := NewContext("<program>", nil, nil)
+	context.SymbolTable = buildInSymbolTable()
+
+	first, err := importModule("once", ImportNode{}, context)
+	if err != nil {
+		t.Fatal(err.Details)
+	}
+	second, err := importModule("once", ImportNode{}, context)
+	if err != nil {
+		t.Fatal(err.Details)
+	}
+	if first != second {
+		t.Fatalf("the package was evaluated again by its second import")
+	}
+	firstValue, _, _ := first.SymbolTable.Get("value")
+	secondValue, _, _ := second.SymbolTable.Get("value")
+	if firstValue != secondValue {
+		t.Errorf("importers do not share the exports of the package")
+	}
+}
+
+func TestModulesFromTheCache(t *testing.T) {
+	writePackages(t, map[string]string{
+		"counter": "var n = 0\nfunc bump() {\n\tn = n + 1\n\treturn n\n}",
+		"user":    "import \"counter\"\nvar first = bump()",
+	})
+	forget := func() {
+		modules.Lock()
+		modules.loaded = map[string]*Module{}
+		modules.Unlock()
+	}
+	forget()
+	t.Cleanup(forget)
+
+	// the first run caches the ASTs of the packages, the second run reads them from the cache
+	for run := 1; run <= 2; run++ {
+		context, err := runProgram("import first from \"user\"\nvar out = first", false)
+		if err != "" {
+			t.Fatalf("run %d: unexpected error: %s", run, err)
+		}
+		out, _, _ := context.SymbolTable.Get("out")
+		if got := string(interfaceToBytes(out.Value())); got != "1" {
+			t.Errorf("run %d: out = %q, want %q", run, got, "1")
+		}
+		forget()
+	}
+}
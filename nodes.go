@@ -31,16 +31,22 @@ func NewUnaryOpNode(opTok *Token, node Node) *UnaryOpNode {
 
 // NewVarAccessNode creates a new VarAccessNode instance.
 func NewVarAccessNode(varNameTok *Token) *VarAccessNode {
-	return &VarAccessNode{varNameTok, varNameTok.PosStart, varNameTok.PosEnd}
+	return &VarAccessNode{varNameTok, varNameTok.PosStart, varNameTok.PosEnd, nil}
 }
 
-func NewIndexNode(varAccessNode *VarAccessNode, index *NumberNode) *IndexNode {
-	return &IndexNode{varAccessNode, index, varAccessNode.PosStart(), index.PosEnd()}
+func NewIndexNode(target Node, index Node, optional bool, posEnd *Position) *IndexNode {
+	return &IndexNode{target, index, optional, target.PosStart(), posEnd}
 }
 
 // NewVarAssignNode creates a new VarAssignNode instance.
 func NewVarAssignNode(varNameTok *Token, valueNode Node, isConst bool, declaration bool) *VarAssignNode {
-	return &VarAssignNode{varNameTok, valueNode, isConst, declaration, varNameTok.PosStart, varNameTok.PosEnd}
+	return &VarAssignNode{varNameTok, valueNode, isConst, declaration, nil, varNameTok.PosStart, varNameTok.PosEnd, nil}
+}
+
+// WithType sets the annotated type of a declaration, nil leaves it untyped.
+func (v *VarAssignNode) WithType(varType *TypeAnnotation) *VarAssignNode {
+	v.Type = varType
+	return v
 }
 
 func NewIfCaseNode(condition, expr Node, flag bool) *IfCaseNode {
@@ -71,8 +77,9 @@ func NewElseCaseNode(statement Node, flag bool) *ElseCaseNode {
 	return &ElseCaseNode{statement, flag}
 }
 
-func NewForNode(varNameTok *Token, startValueNode, endValueNode, stepValueNode, bodyNode Node, Flag bool) *ForNode {
+func NewForNode(labelTok, varNameTok *Token, startValueNode, endValueNode, stepValueNode, bodyNode Node, Flag bool) *ForNode {
 	return &ForNode{
+		LabelTok:       labelTok,
 		VarNameTok:     varNameTok,
 		StartValueNode: startValueNode,
 		EndValueNode:   endValueNode,
@@ -84,8 +91,9 @@ func NewForNode(varNameTok *Token, startValueNode, endValueNode, stepValueNode,
 	}
 }
 
-func NewWhileNode(conditionNode, bodyNode Node, Flag bool) *WhileNode {
+func NewWhileNode(labelTok *Token, conditionNode, bodyNode Node, Flag bool) *WhileNode {
 	return &WhileNode{
+		LabelTok:      labelTok,
 		ConditionNode: conditionNode,
 		BodyNode:      bodyNode,
 		PositionStart: conditionNode.PosStart(),
@@ -94,7 +102,7 @@ func NewWhileNode(conditionNode, bodyNode Node, Flag bool) *WhileNode {
 	}
 }
 
-func NewFuncDefNode(varNameTok *Token, argNameToks []*Token, bodyNode Node, Flag bool) *FuncDefNode {
+func NewFuncDefNode(varNameTok *Token, argNameToks []*Token, argTypes []*TypeAnnotation, returnType *TypeAnnotation, bodyNode Node, Flag bool) *FuncDefNode {
 	var posStart, posEnd *Position
 
 	if varNameTok != nil {
@@ -110,10 +118,13 @@ func NewFuncDefNode(varNameTok *Token, argNameToks []*Token, bodyNode Node, Flag
 	return &FuncDefNode{
 		VarNameTok:    varNameTok,
 		ArgNameToks:   argNameToks,
+		ArgTypes:      argTypes,
+		ReturnType:    returnType,
 		BodyNode:      bodyNode,
 		PositionStart: posStart,
 		PositionEnd:   posEnd,
 		Flag:          Flag,
+		Compiled:      &CompiledBody{},
 	}
 }
 
@@ -137,25 +148,57 @@ func NewCallNode(nodeToCall Node, argNodes []Node) *CallNode {
 }
 
 func NewArrayNode(ElementNodes []Node, PosStart *Position, PosEnd *Position) *ArrayNode {
-	return &ArrayNode{ElementNodes, PosStart, PosEnd}
+	return &ArrayNode{ElementNodes, false, PosStart, PosEnd}
 }
 
 func NewReturnNode(NodeToReturn Node, PosStart *Position, PosEnd *Position) *ReturnNode {
-	return &ReturnNode{NodeToReturn, PosStart, PosEnd}
+	return &ReturnNode{NodeToReturn, false, PosStart, PosEnd}
+}
+
+func NewContinueNode(LabelTok *Token, PosStart *Position, PosEnd *Position) *ContinueNode {
+	return &ContinueNode{LabelTok, PosStart, PosEnd}
 }
 
-func NewContinueNode(PosStart *Position, PosEnd *Position) *ContinueNode {
-	return &ContinueNode{PosStart, PosEnd}
+func NewBreakNode(LabelTok *Token, PosStart *Position, PosEnd *Position) *BreakNode {
+	return &BreakNode{LabelTok, PosStart, PosEnd}
 }
 
-func NewBreakNode(PosStart *Position, PosEnd *Position) *BreakNode {
-	return &BreakNode{PosStart, PosEnd}
+// labelName returns the name of a loop label or an empty string for loops without a label.
+func labelName(labelTok *Token) string {
+	if labelTok == nil {
+		return ""
+	}
+	return labelTok.Value.(string)
 }
 
 func NewImportNode(importNames []*Token, packageName []*Token, posStart *Position, posEnd *Position) *ImportNode {
 	return &ImportNode{importNames, packageName, posStart, posEnd}
 }
 
+func NewMethodCallNode(targetNode Node, methodTok *Token, argNodes []Node, isCall bool, optional bool, posEnd *Position) *MethodCallNode {
+	return &MethodCallNode{targetNode, methodTok, argNodes, isCall, optional, targetNode.PosStart(), posEnd, nil}
+}
+
+// NewPipeNode inserts value as the first argument of the call on the right side of '|>'.
+func NewPipeNode(value Node, target Node) Node {
+	switch t := target.(type) {
+	case *CallNode:
+		call := NewCallNode(t.NodeToCall, append([]Node{value}, t.ArgNodes...))
+		call.Optional = t.Optional
+		return call
+	case *MethodCallNode:
+		if t.IsCall {
+			return NewMethodCallNode(t.TargetNode, t.MethodTok, append([]Node{value}, t.ArgNodes...), true, t.Optional, t.PositionEnd)
+		}
+	}
+	return NewCallNode(target, []Node{value})
+}
+
+// NewConditionalNode builds 'condition ? thenNode : elseNode' as an if expression with an else case.
+func NewConditionalNode(condition Node, thenNode Node, elseNode Node) *IfNode {
+	return NewIfNode([]*IfCaseNode{NewIfCaseNode(condition, thenNode, false)}, NewElseCaseNode(elseNode, false))
+}
+
 func NewPackageMethod(packageTok *Token, methodName string, callNode Node) *PackageMethod {
 	return &PackageMethod{
 		PackageName:   packageTok.Value.(string),
@@ -166,6 +209,18 @@ func NewPackageMethod(packageTok *Token, methodName string, callNode Node) *Pack
 	}
 }
 
+func NewComprehensionNode(elementNode Node, clauses []*ComprehensionClause, posStart *Position, posEnd *Position) *ComprehensionNode {
+	return &ComprehensionNode{elementNode, clauses, posStart, posEnd, nil}
+}
+
+func NewDeferNode(expr Node, posStart *Position) *DeferNode {
+	return &DeferNode{expr, posStart, expr.PosEnd()}
+}
+
+func NewSpawnNode(callNode *CallNode, posStart *Position) *SpawnNode {
+	return &SpawnNode{callNode, posStart, callNode.PosEnd()}
+}
+
 func NewReference(target Node) *ReferenceNode {
 	return &ReferenceNode{target, target.PosStart(), target.PosEnd()}
 }
@@ -404,7 +459,7 @@ func (i *IndexNode) PosEnd() *Position {
 }
 
 func (i *IndexNode) String() string {
-	return fmt.Sprintf("(%v, %v)", i.VarAccessNode, i.IndexNode)
+	return fmt.Sprintf("(%v, %v)", i.Target, i.Index)
 }
 
 func (p *PackageMethod) PosStart() *Position {
@@ -442,3 +497,61 @@ func (d *DereferenceNode) PosEnd() *Position {
 func (d *DereferenceNode) String() string {
 	return fmt.Sprintf("(%v)", d.Target)
 }
+
+func (s *SpawnNode) PosStart() *Position {
+	return s.PositionStart
+}
+
+func (s *SpawnNode) PosEnd() *Position {
+	return s.PositionEnd
+}
+
+func (s *SpawnNode) String() string {
+	return fmt.Sprintf("(spawn %v)", s.CallNode)
+}
+
+func (d *DeferNode) PosStart() *Position {
+	return d.PositionStart
+}
+
+func (d *DeferNode) PosEnd() *Position {
+	return d.PositionEnd
+}
+
+func (d *DeferNode) String() string {
+	return fmt.Sprintf("(defer %v)", d.Expr)
+}
+
+func (c *ComprehensionNode) PosStart() *Position {
+	return c.PositionStart
+}
+
+func (c *ComprehensionNode) PosEnd() *Position {
+	return c.PositionEnd
+}
+
+func (c *ComprehensionNode) String() string {
+	clauses := ""
+	for _, clause := range c.Clauses {
+		clauses += fmt.Sprintf(" for %v in %v", clause.VarNameTok.Value, clause.IterableNode)
+		if clause.ConditionNode != nil {
+			clauses += fmt.Sprintf(" if %v", clause.ConditionNode)
+		}
+	}
+	return fmt.Sprintf("[%v%s]", c.ElementNode, clauses)
+}
+
+func (m *MethodCallNode) PosStart() *Position {
+	return m.PositionStart
+}
+
+func (m *MethodCallNode) PosEnd() *Position {
+	return m.PositionEnd
+}
+
+func (m *MethodCallNode) String() string {
+	if m.IsCall {
+		return fmt.Sprintf("(%v.%v%v)", m.TargetNode, m.MethodTok.Value, m.ArgNodes)
+	}
+	return fmt.Sprintf("(%v.%v)", m.TargetNode, m.MethodTok.Value)
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// precedences from the loosest to the tightest binding operators
+const (
+	precLowest     precedence = iota
+	precTernary               // a ? b : c
+	precPipe                  // a |> f
+	precNullish               // a ?? b
+	precNot                   // not a
+	precComparison            // a == b, a != b, a < b, a > b, a <= b, a >= b
+	precSum                   // a + b, a - b
+	precProduct               // a * b, a / b
+	precUnary                 // +a, -a
+	precPower                 // a ^ b
+	precPostfix               // f(a), a.b, a?.b, a[b], a?[b] and the operands themselves
+)
+
+// prefixRules, keywordRules, infixRules and postfixRules drive the expression parser. They are filled in init because
+// their parse functions parse nested expressions with them.
+var (
+	prefixRules  map[TokenTypes]prefixRule
+	keywordRules map[string]prefixRule
+	infixRules   map[TokenTypes]infixRule
+	postfixRules map[TokenTypes]postfixRule
+)
+
+func init() {
+	prefixRules = map[TokenTypes]prefixRule{
+		TT_INT:        {precPostfix, (*Parser).number},
+		TT_FLOAT:      {precPostfix, (*Parser).number},
+		TT_DECIMAL:    {precPostfix, (*Parser).number},
+		TT_STRING:     {precPostfix, (*Parser).str},
+		TT_IDENTIFIER: {precPostfix, (*Parser).variable},
+		TT_LSQUARE:    {precPostfix, grammarRule((*Parser).ArrayExpr)},
+		TT_LPAREN:     {precPostfix, (*Parser).group},
+		TT_PLUS:       {precUnary, (*Parser).unary},
+		TT_MINUS:      {precUnary, (*Parser).unary},
+		TT_AND:        {precPostfix, (*Parser).reference},
+		TT_STAR:       {precPostfix, (*Parser).dereference},
+	}
+	keywordRules = map[string]prefixRule{
+		"if":     {precPostfix, grammarRule((*Parser).ifExpr)},
+		"for":    {precPostfix, grammarRule((*Parser).ForExpr)},
+		"while":  {precPostfix, grammarRule((*Parser).WhileExpr)},
+		"func":   {precPostfix, grammarRule((*Parser).FuncDef)},
+		"spawn":  {precPostfix, grammarRule((*Parser).SpawnExpr)},
+		"import": {precPostfix, grammarRule((*Parser).ImportExpr)},
+		"not":    {precPostfix, (*Parser).not},
+	}
+	// 'and' and 'or' are keywords but no operators yet, binaryOperation can not combine booleans
+	infixRules = map[TokenTypes]infixRule{
+		TT_QUESTION: {precTernary, precLowest, (*Parser).conditional},
+		TT_PIPE:     {precPipe, precPostfix, (*Parser).pipe},
+		TT_NULLISH:  {precNullish, precNot, (*Parser).binary},
+		TT_EE:       {precComparison, precSum, (*Parser).binary},
+		TT_NE:       {precComparison, precSum, (*Parser).binary},
+		TT_LT:       {precComparison, precSum, (*Parser).binary},
+		TT_GT:       {precComparison, precSum, (*Parser).binary},
+		TT_LTE:      {precComparison, precSum, (*Parser).binary},
+		TT_GTE:      {precComparison, precSum, (*Parser).binary},
+		TT_PLUS:     {precSum, precProduct, (*Parser).binary},
+		TT_MINUS:    {precSum, precProduct, (*Parser).binary},
+		TT_STAR:     {precProduct, precUnary, (*Parser).binary},
+		TT_DIV:      {precProduct, precUnary, (*Parser).binary},
+		// right associative, and the exponent may have a sign
+		TT_POW: {precPower, precUnary, (*Parser).binary},
+	}
+	postfixRules = map[TokenTypes]postfixRule{
+		TT_LPAREN:  (*Parser).call,
+		TT_DOT:     (*Parser).member,
+		TT_QDOT:    (*Parser).optionalMember,
+		TT_LSQUARE: (*Parser).index,
+	}
+}
+
+// grammarRule turns a grammar function that returns its own ParseResult into the parse function of a prefix rule.
+func grammarRule(parse func(p *Parser) *ParseResult) func(p *Parser, res *ParseResult) Node {
+	return func(p *Parser, res *ParseResult) Node {
+		return res.Register(parse(p))
+	}
+}
+
+// prefixRule returns the rule for an expression starting at the current token.
+func (p *Parser) prefixRule() (prefixRule, bool) {
+	if p.Current.Type == TT_KEYWORD {
+		rule, ok := keywordRules[p.Current.Value.(string)]
+		return rule, ok
+	}
+	rule, ok := prefixRules[p.Current.Type]
+	return rule, ok
+}
+
+// postfixRule returns the rule for a postfix form at the current token.
+func (p *Parser) postfixRule() (postfixRule, bool) {
+	if p.isOptionalIndex() {
+		return (*Parser).optionalIndex, true
+	}
+	rule, ok := postfixRules[p.Current.Type]
+	return rule, ok
+}
+
+// tokenNames describe the token types in syntax errors.
+var tokenNames = map[TokenTypes]string{
+	TT_INT: "int", TT_FLOAT: "float", TT_DECIMAL: "decimal", TT_STRING: "string", TT_IDENTIFIER: "identifier",
+	TT_LPAREN: "'('", TT_LSQUARE: "'['", TT_PLUS: "'+'", TT_MINUS: "'-'", TT_AND: "'&'", TT_STAR: "'*'",
+}
+
+// expectedOperands lists the tokens that can start an operand of operators with at least the precedence minPrec.
+func expectedOperands(minPrec precedence) []string {
+	var expected []string
+	for typ, rule := range prefixRules {
+		if rule.prec >= minPrec {
+			expected = append(expected, tokenNames[typ])
+		}
+	}
+	for keyword, rule := range keywordRules {
+		if rule.prec >= minPrec {
+			expected = append(expected, "'"+keyword+"'")
+		}
+	}
+	sort.Strings(expected)
+	return expected
+}
+
+// expected reports that the current token is none of the expected tokens. A statement that ends before them is
+// incomplete.
+func (p *Parser) expected(expected ...string) Error {
+	list := expected[len(expected)-1]
+	if len(expected) > 1 {
+		list = strings.Join(expected[:len(expected)-1], ", ") + " or " + list
+	}
+	details := "Expected " + list
+	switch p.Current.Type {
+	case TT_EOF:
+		details = "Incomplete statement, expected " + list + " before the end of the file"
+	case TT_NEWLINE:
+		details = "Incomplete statement, expected " + list + " before the end of the line"
+	}
+	return NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, details).Error
+}
+
+// startsExpression reports whether an expression can start at the current token.
+func (p *Parser) startsExpression() bool {
+	if p.Current.Matches(TT_KEYWORD, "var") || p.Current.Matches(TT_KEYWORD, "const") {
+		return true
+	}
+	_, ok := p.prefixRule()
+	return ok
+}
+
+// expression parses an expression whose operators have at least the precedence minPrec. Errors are recorded in res.
+func (p *Parser) expression(res *ParseResult, minPrec precedence) Node {
+	rule, ok := p.prefixRule()
+	if !ok || rule.prec < minPrec {
+		res.Failure(p.expected(expectedOperands(minPrec)...))
+		return nil
+	}
+	left := rule.parse(p, res)
+	if res.Error != nil {
+		return nil
+	}
+
+	optional := false
+	for {
+		if p.brackets > 0 {
+			p.skipNewlines(res)
+		}
+		if postfix, ok := p.postfixRule(); ok {
+			left = postfix(p, res, left, &optional)
+		} else if infix, ok := infixRules[p.Current.Type]; ok && infix.prec >= minPrec {
+			left = infix.parse(p, res, left, infix)
+			// an operator starts a new chain
+			optional = false
+		} else {
+			return left
+		}
+		if res.Error != nil {
+			return nil
+		}
+	}
+}
+
+func (p *Parser) number(res *ParseResult) Node {
+	tok := p.Current
+	res.RegisterAdvancement()
+	p.Advance()
+
+	var err error
+	if tok.Type == TT_FLOAT {
+		tok.Value, err = strconv.ParseFloat(tok.Value.(string), 64)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+	if tok.Type == TT_INT {
+		value, ok := parseInteger(tok.Value.(string))
+		if !ok {
+			res.Failure(NewInvalidSyntaxError(tok.PosStart, tok.PosEnd, fmt.Sprintf("Invalid integer literal '%v'", tok.Value)).Error)
+			return nil
+		}
+		tok.Value = value
+	}
+	if tok.Type == TT_DECIMAL {
+		tok.Value, _ = ParseDecimal(tok.Value.(string))
+	}
+	return NewNumberNode(tok)
+}
+
+func (p *Parser) str(res *ParseResult) Node {
+	tok := p.Current
+	res.RegisterAdvancement()
+	p.Advance()
+	return NewStringNode(tok)
+}
+
+func (p *Parser) variable(res *ParseResult) Node {
+	tok := p.Current
+	res.RegisterAdvancement()
+	p.Advance()
+	return NewVarAccessNode(tok)
+}
+
+// group parses a parenthesized expression or a tuple.
+func (p *Parser) group(res *ParseResult) Node {
+	tok := p.Current
+	res.RegisterAdvancement()
+	p.Advance()
+	defer p.enterBrackets(res)()
+
+	expr := res.Register(p.Expr())
+	if res.Error != nil {
+		return nil
+	}
+	if p.Current.Type == TT_COMMA {
+		return res.Register(p.TupleExpr(expr, tok))
+	}
+	if p.Current.Type != TT_RPAREN {
+		res.Failure(p.unclosed(tok, "','", "')'"))
+		return nil
+	}
+	res.RegisterAdvancement()
+	p.Advance()
+	return expr
+}
+
+// unary parses a prefix operator, its operand may start with the same operator again.
+func (p *Parser) unary(res *ParseResult) Node {
+	rule, _ := p.prefixRule()
+	return p.prefixOp(res, rule.prec)
+}
+
+// not parses a negation. It starts an operand anywhere but takes in everything up to a comparison, so not a == b is
+// not (a == b) and 1 + not a == b is 1 + not (a == b).
+func (p *Parser) not(res *ParseResult) Node {
+	return p.prefixOp(res, precNot)
+}
+
+// prefixOp parses a prefix operator followed by an operand of at least the precedence prec.
+func (p *Parser) prefixOp(res *ParseResult, prec precedence) Node {
+	opTok := p.Current
+	res.RegisterAdvancement()
+	p.Advance()
+
+	operand := p.expression(res, prec)
+	if res.Error != nil {
+		return nil
+	}
+	return NewUnaryOpNode(opTok, operand)
+}
+
+func (p *Parser) reference(res *ParseResult) Node {
+	res.RegisterAdvancement()
+	p.Advance()
+	target := res.Register(p.Expr())
+	if res.Error != nil {
+		return nil
+	}
+	return NewReference(target)
+}
+
+func (p *Parser) dereference(res *ParseResult) Node {
+	res.RegisterAdvancement()
+	p.Advance()
+	target := res.Register(p.Expr())
+	if res.Error != nil {
+		return nil
+	}
+	return NewDereference(target)
+}
+
+func (p *Parser) binary(res *ParseResult, left Node, rule infixRule) Node {
+	opTok := p.Current
+	res.RegisterAdvancement()
+	p.Advance()
+	p.skipNewlines(res)
+
+	right := p.expression(res, rule.right)
+	if res.Error != nil {
+		return nil
+	}
+	return NewBinOpNode(left, opTok, right)
+}
+
+// conditional parses 'condition ? then : else', both branches are whole expressions.
+func (p *Parser) conditional(res *ParseResult, condition Node, _ infixRule) Node {
+	res.RegisterAdvancement()
+	p.Advance()
+	p.skipNewlines(res)
+
+	thenNode := res.Register(p.Expr())
+	if res.Error != nil {
+		return nil
+	}
+	if p.Current.Type != TT_COLON {
+		res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected ':'").Error)
+		return nil
+	}
+	res.RegisterAdvancement()
+	p.Advance()
+	p.skipNewlines(res)
+
+	elseNode := res.Register(p.Expr())
+	if res.Error != nil {
+		return nil
+	}
+	return NewConditionalNode(condition, thenNode, elseNode)
+}
+
+// pipe parses 'value |> target', the target is an operand with its calls and member accesses.
+func (p *Parser) pipe(res *ParseResult, value Node, rule infixRule) Node {
+	res.RegisterAdvancement()
+	p.Advance()
+	p.skipNewlines(res)
+
+	target := p.expression(res, rule.right)
+	if res.Error != nil {
+		return nil
+	}
+	return NewPipeNode(value, target)
+}
+
+func (p *Parser) call(res *ParseResult, callee Node, optional *bool) Node {
+	argNodes := p.CallArgs(res)
+	if res.Error != nil {
+		return nil
+	}
+	call := NewCallNode(callee, argNodes)
+	call.Optional = *optional
+	return call
+}
+
+// member parses '.name' and the method call '.name(args)'.
+func (p *Parser) member(res *ParseResult, target Node, optional *bool) Node {
+	res.RegisterAdvancement()
+	p.Advance()
+	p.skipNewlines(res)
+
+	if p.Current.Type != TT_IDENTIFIER {
+		res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected identifier after '.'").Error)
+		return nil
+	}
+	methodTok := p.Current
+	posEnd := p.Current.PosEnd
+	res.RegisterAdvancement()
+	p.Advance()
+
+	if p.Current.Type != TT_LPAREN {
+		return NewMethodCallNode(target, methodTok, nil, false, *optional, posEnd)
+	}
+	argNodes := p.CallArgs(res)
+	if res.Error != nil {
+		return nil
+	}
+	return NewMethodCallNode(target, methodTok, argNodes, true, *optional, p.Tokens[p.TokIdx-1].PosEnd)
+}
+
+// optionalMember parses '?.name', '?.name(args)' and '?.(args)'. Once a link is optional, the rest of the chain is
+// skipped as well when it hits null.
+func (p *Parser) optionalMember(res *ParseResult, target Node, optional *bool) Node {
+	*optional = true
+	if p.Peek().Type == TT_LPAREN {
+		res.RegisterAdvancement()
+		p.Advance()
+		return p.call(res, target, optional)
+	}
+	return p.member(res, target, optional)
+}
+
+// index parses '[index]'.
+func (p *Parser) index(res *ParseResult, target Node, optional *bool) Node {
+	res.RegisterAdvancement()
+	p.Advance()
+	defer p.enterBrackets(res)()
+
+	index := res.Register(p.Expr())
+	if res.Error != nil {
+		return nil
+	}
+	if p.Current.Type != TT_RSQUARE {
+		res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected ']'").Error)
+		return nil
+	}
+	node := NewIndexNode(target, index, *optional, p.Current.PosEnd)
+	res.RegisterAdvancement()
+	p.Advance()
+	return node
+}
+
+// optionalIndex parses '?[index]', written without a space between '?' and '['.
+func (p *Parser) optionalIndex(res *ParseResult, target Node, optional *bool) Node {
+	*optional = true
+	res.RegisterAdvancement()
+	p.Advance()
+	return p.index(res, target, optional)
+}
@@ -0,0 +1,223 @@
+package main
+
+// NewOptimizer creates an Optimizer for an AST that was resolved by resolver.
+func NewOptimizer(resolver *Resolver) *Optimizer {
+	return &Optimizer{resolver: resolver}
+}
+
+// Optimize returns node with constant expressions folded and branches that can not run removed. Loops whose values
+// are not used become statements that do not collect them.
+func (o *Optimizer) Optimize(node Node) Node {
+	return o.optimize(node)
+}
+
+// optimize simplifies node and its children, the value of node is used.
+func (o *Optimizer) optimize(node Node) Node {
+	switch n := node.(type) {
+	case *ArrayNode:
+		for idx, element := range n.ElementNodes {
+			n.ElementNodes[idx] = o.optimize(element)
+		}
+	case *VarAssignNode:
+		n.ValueNode = o.optimize(n.ValueNode)
+	case *BinOpNode:
+		n.LeftNode = o.optimize(n.LeftNode)
+		n.RightNode = o.optimize(n.RightNode)
+		return o.foldBinary(n)
+	case *UnaryOpNode:
+		n.Node = o.optimize(n.Node)
+		return o.foldUnary(n)
+	case *IndexNode:
+		n.Target = o.optimize(n.Target)
+		n.Index = o.optimize(n.Index)
+	case *IfNode:
+		o.optimizeIf(n, false)
+	case *ForNode:
+		n.StartValueNode = o.optimize(n.StartValueNode)
+		n.EndValueNode = o.optimize(n.EndValueNode)
+		if n.StepValueNode != nil {
+			n.StepValueNode = o.optimize(n.StepValueNode)
+		}
+		n.BodyNode = o.body(n.BodyNode, n.Flag)
+	case *WhileNode:
+		n.ConditionNode = o.optimize(n.ConditionNode)
+		if value, constant := o.constantCondition(n.ConditionNode); constant && !value {
+			// the body never runs
+			n.BodyNode = NewArrayNode(nil, n.BodyNode.PosStart(), n.BodyNode.PosEnd())
+			break
+		}
+		n.BodyNode = o.body(n.BodyNode, n.Flag)
+	case *FuncDefNode:
+		for idx, decorator := range n.Decorators {
+			n.Decorators[idx] = o.optimize(decorator)
+		}
+		// only arrow functions return the value of their body
+		n.BodyNode = o.body(n.BodyNode, !n.Flag)
+	case *CallNode:
+		o.optimizeCall(n)
+	case *MethodCallNode:
+		n.TargetNode = o.optimize(n.TargetNode)
+		for idx, arg := range n.ArgNodes {
+			n.ArgNodes[idx] = o.optimize(arg)
+		}
+	case *ReturnNode:
+		if n.NodeToReturn != nil {
+			n.NodeToReturn = o.optimize(n.NodeToReturn)
+		}
+	case *ComprehensionNode:
+		for _, clause := range n.Clauses {
+			clause.IterableNode = o.optimize(clause.IterableNode)
+			if clause.ConditionNode != nil {
+				clause.ConditionNode = o.optimize(clause.ConditionNode)
+			}
+		}
+		n.ElementNode = o.optimize(n.ElementNode)
+	case *SpawnNode:
+		o.optimizeCall(n.CallNode)
+	case *DeferNode:
+		n.Expr = o.discard(n.Expr)
+	case *ReferenceNode:
+		n.Target = o.optimize(n.Target)
+	case *DereferenceNode:
+		n.Target = o.optimize(n.Target)
+	}
+	return node
+}
+
+// discard simplifies node whose value is not used, e.g. a statement of a block.
+func (o *Optimizer) discard(node Node) Node {
+	switch n := node.(type) {
+	case *ArrayNode:
+		for idx, element := range n.ElementNodes {
+			n.ElementNodes[idx] = o.discard(element)
+		}
+		return node
+	case *IfNode:
+		o.optimizeIf(n, true)
+		return node
+	case *ForNode:
+		n.Flag = true
+	case *WhileNode:
+		n.Flag = true
+	}
+	return o.optimize(node)
+}
+
+// body simplifies the body of a loop or a function, discarded tells whether its value is thrown away.
+func (o *Optimizer) body(node Node, discarded bool) Node {
+	if discarded {
+		return o.discard(node)
+	}
+	return o.optimize(node)
+}
+
+func (o *Optimizer) optimizeCall(node *CallNode) {
+	node.NodeToCall = o.optimize(node.NodeToCall)
+	for idx, arg := range node.ArgNodes {
+		node.ArgNodes[idx] = o.optimize(arg)
+	}
+}
+
+// optimizeIf removes the cases whose condition is always false, and the cases after one that is always true.
+func (o *Optimizer) optimizeIf(node *IfNode, discarded bool) {
+	var cases []*IfCaseNode
+	for _, ifCase := range node.Cases {
+		ifCase.Condition = o.optimize(ifCase.Condition)
+		value, constant := o.constantCondition(ifCase.Condition)
+		if constant && !value {
+			continue
+		}
+		ifCase.Expr = o.body(ifCase.Expr, discarded || ifCase.Flag)
+		cases = append(cases, ifCase)
+		if constant {
+			node.ElseCase = nil
+			break
+		}
+	}
+	node.Cases = cases
+
+	if node.ElseCase != nil {
+		node.ElseCase.Expr = o.body(node.ElseCase.Expr, discarded || node.ElseCase.Flag)
+	}
+}
+
+// constantCondition returns the value of a condition that is 'true' or 'false', unless the names are shadowed. Folded
+// comparisons are written as these names as well.
+func (o *Optimizer) constantCondition(node Node) (bool, bool) {
+	access, ok := node.(*VarAccessNode)
+	if !ok || access.Binding != nil {
+		return false, false
+	}
+	name := access.VarNameTok.Value.(string)
+	if (name != "true" && name != "false") || !o.resolver.isBuildIn(name) {
+		return false, false
+	}
+	return name == "true", true
+}
+
+// foldBinary replaces an operation on two constants with its result. Operations that fail are kept, their error is
+// reported when the program runs.
+func (o *Optimizer) foldBinary(node *BinOpNode) Node {
+	left, right := o.constantValue(node.LeftNode), o.constantValue(node.RightNode)
+	if left == nil || right == nil {
+		return node
+	}
+	result, err := binaryOperation(node, left, right, nil)
+	if err != nil {
+		return node
+	}
+	return o.constantNode(result, node, node.PosStart(), node.PosEnd())
+}
+
+// foldUnary replaces a sign in front of a number with the signed number.
+func (o *Optimizer) foldUnary(node *UnaryOpNode) Node {
+	operand := o.constantValue(node.Node)
+	if operand == nil {
+		return node
+	}
+	result, err := unaryOperation(node, operand, nil)
+	if err != nil {
+		return node
+	}
+	return o.constantNode(result, node, node.OpTok.PosStart, node.Node.PosEnd())
+}
+
+// constantValue returns the value of a number or string literal or of a constant condition, or nil for any other node.
+// Decimals are not constant, their results depend on the scale and rounding set when the program runs.
+func (o *Optimizer) constantValue(node Node) *Value {
+	if value, constant := o.constantCondition(node); constant {
+		return NewBoolean(ConvertBoolToInt(value))
+	}
+	switch n := node.(type) {
+	case *NumberNode:
+		if _, isDecimal := n.Value.(*Decimal); !isDecimal {
+			return NewNumber(n.Value)
+		}
+	case *StringNode:
+		if text, ok := n.Value.(string); ok {
+			return NewString(text)
+		}
+	}
+	return nil
+}
+
+// constantNode returns a literal for value in place of node, or node itself if value can not be written as a literal.
+// Booleans are written as the build-in names 'true' and 'false'.
+func (o *Optimizer) constantNode(value *Value, node Node, posStart, posEnd *Position) Node {
+	switch value.Kind {
+	case KIND_NUMBER:
+		tokType := TT_INT
+		switch value.Number().Value().(type) {
+		case float64:
+			tokType = TT_FLOAT
+		}
+		return NewNumberNode(NewToken(tokType, value.Number().Value(), posStart, posEnd))
+	case KIND_STRING:
+		return NewStringNode(NewToken(TT_STRING, value.String().ValueField, posStart, posEnd))
+	case KIND_BOOLEAN:
+		if name := value.Boolean().String(); o.resolver.isBuildIn(name) {
+			return NewVarAccessNode(NewToken(TT_IDENTIFIER, name, posStart, posEnd))
+		}
+	}
+	return node
+}
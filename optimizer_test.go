@@ -0,0 +1,124 @@
+package main
+
+import "testing"
+
+// optimize parses, resolves and optimizes source and returns its statements.
+func optimize(t *testing.T, source string) []Node {
+	t.Helper()
+	ast := NewLexerParser(NewLexer("<test>", source)).Parse()
+	if ast.Error != nil {
+		t.Fatalf("unexpected syntax error: %s", ast.Error.Details)
+	}
+	resolver := NewResolver(buildInSymbolTable())
+	if nameErrors := resolver.Resolve(ast.Node); len(nameErrors) > 0 {
+		t.Fatalf("unexpected name error: %s", nameErrors[0].Details)
+	}
+	return NewOptimizer(resolver).Optimize(ast.Node).(*ArrayNode).ElementNodes
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		source string
+		want   interface{} // value of the folded literal, nil if the expression is kept
+	}{
+		{"var x = 1 + 2 * 3", 7},
+		{"var x = -(2 ^ 3)", -8},
+		{"var x = 1.5 * 2", 3.0},
+		{`var x = "a" + "b"`, "ab"},
+		{"var x = 1 / 0", nil},
+		{"var x = 1.5d + 1", nil},
+		{"var y = 1\nvar x = y + 1", nil},
+	}
+	for _, test := range tests {
+		statements := optimize(t, test.source)
+		value := statements[len(statements)-1].(*VarAssignNode).ValueNode
+		var got interface{}
+		switch literal := value.(type) {
+		case *NumberNode:
+			got = literal.Value
+		case *StringNode:
+			got = literal.Value
+		}
+		if got != test.want {
+			t.Errorf("%q: folded to %#v, want %#v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestDeadBranches(t *testing.T) {
+	statements := optimize(t, "var a = 1\nif false {\n\ta = 2\nelif a == 1 {\n\ta = 3\nelif true {\n\ta = 4\nelif a == 2 {\n\ta = 5\nelse\n\ta = 6\n}")
+	ifNode := statements[1].(*IfNode)
+	if len(ifNode.Cases) != 2 {
+		t.Errorf("kept %d cases, want the 'a == 1' and 'true' cases", len(ifNode.Cases))
+	}
+	if ifNode.ElseCase != nil {
+		t.Error("kept the else case after a case that is always true")
+	}
+}
+
+func TestFoldedConditions(t *testing.T) {
+	statements := optimize(t, "var a = 1\nif 1 > 2 {\n\ta = 2\nelif \"a\" == \"b\" {\n\ta = 3\nelif 2 >= 2 {\n\ta = 4\nelse\n\ta = 5\n}\nwhile 1 == 2 {\n\ta = 6\n}\nvar b = 1 < 2 == true")
+	ifNode := statements[1].(*IfNode)
+	if len(ifNode.Cases) != 1 || ifNode.ElseCase != nil {
+		t.Errorf("kept %d cases and the else case %v, want only the '2 >= 2' case", len(ifNode.Cases), ifNode.ElseCase != nil)
+	}
+	if body := statements[2].(*WhileNode).BodyNode.(*ArrayNode); len(body.ElementNodes) != 0 {
+		t.Errorf("kept %d statements in a loop that never runs", len(body.ElementNodes))
+	}
+	if access, ok := statements[3].(*VarAssignNode).ValueNode.(*VarAccessNode); !ok || access.VarNameTok.Value != "true" {
+		t.Errorf("'1 < 2 == true' folded to %v, want true", statements[3].(*VarAssignNode).ValueNode)
+	}
+
+	// a declared name is not the build-in boolean
+	statements = optimize(t, "var false = 0\nif 1 > 2 {\n\tvar a = 1\n}")
+	if len(statements[1].(*IfNode).Cases) != 1 {
+		t.Error("removed a case whose condition is not the build-in false")
+	}
+}
+
+func TestUnusedLoopValues(t *testing.T) {
+	statements := optimize(t, "for i = 0 to 3 {\n\ti\n}\nvar a = for i = 0 to 3 { i\nvar w = 0\nwhile w < 3 {\n\tw = w + 1\n}\nfunc f() {\n\tfor i = 0 to 3 {\n\t\ti\n\t}\n}")
+	if !statements[0].(*ForNode).Flag {
+		t.Error("the values of a loop statement are collected")
+	}
+	if statements[1].(*VarAssignNode).ValueNode.(*ForNode).Flag {
+		t.Error("the values of an assigned loop are not collected")
+	}
+	if !statements[3].(*WhileNode).Flag {
+		t.Error("the values of a while statement are collected")
+	}
+	body := statements[4].(*FuncDefNode).BodyNode.(*ArrayNode)
+	if !body.ElementNodes[0].(*ForNode).Flag {
+		t.Error("the values of a loop statement in a function are collected")
+	}
+}
+
+func TestOptimizedPrograms(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "folded expressions keep their value",
+			source: "var out = [1 + 2 * 3, \"a\" + \"b\", 2 ^ 3 ^ 2, 7 / 2]",
+			want:   `[7, "ab", 512, 3]`,
+		},
+		{
+			name:    "operations that fail are reported when the program runs",
+			source:  "var out = 1\nout = 1 / 0",
+			wantErr: "Division by zero",
+		},
+		{
+			name:   "a shadowed false is not a constant condition",
+			source: "func f(false) {\n\tif false {\n\t\treturn 1\n\t}\n\treturn 2\n}\nvar out = f(true)",
+			want:   "1",
+		},
+		{
+			name:   "folded conditions keep their value",
+			source: "var out = [1 < 2, 2 == 3, not 0, 1 > 2 ? \"yes\" : \"no\"]",
+			want:   `[true, false, false, "no"]`,
+		},
+		{
+			name:   "assigned loops collect their values",
+			source: "var out = for i = 0 to 3 { i * 2",
+			want:   "[0, 2, 4]",
+		},
+	})
+}
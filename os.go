@@ -159,7 +159,7 @@ func CopyFile(args []*Value) *RTResult {
 		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("Failed to convert file path to UTF-16: %s", err), nil))
 	}
 
-	buffer := res.Register(ReadFile([]*Value{{Number: &Number{ValueField: int(sourceHandle)}}}))
+	buffer := res.Register(ReadFile([]*Value{NewNumber(int(sourceHandle))}))
 	if res.Error != nil {
 		return res
 	}
@@ -180,7 +180,7 @@ func CopyFile(args []*Value) *RTResult {
 		return nil
 	}()
 
-	writeRes := WriteFile([]*Value{{Number: &Number{ValueField: int(copyHandle)}}, buffer})
+	writeRes := WriteFile([]*Value{NewNumber(int(copyHandle)), buffer})
 	res.Register(writeRes)
 
 	fmt.Println("Copied file:", args[0].Value().(string))
@@ -188,7 +188,7 @@ func CopyFile(args []*Value) *RTResult {
 	if res.Error != nil {
 		return res
 	}
-	return res.Success(NewNumber(writeRes.Value.Number.ValueField))
+	return res.Success(NewNumber(writeRes.Value.Number().Value()))
 }
 
 func MoveFile(args []*Value) *RTResult {
@@ -492,7 +492,7 @@ func SetFileOwner(args []*Value) *RTResult {
 		return res.Failure(err)
 	}
 
-	err := metadata.Set_file_owner(args[0].String.ValueField, args[1].String.ValueField)
+	err := metadata.Set_file_owner(args[0].String().ValueField, args[1].String().ValueField)
 	if err != nil {
 		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("%s", err), nil))
 	}
@@ -510,13 +510,13 @@ func StartProcess(args []*Value) *RTResult {
 		return res.Failure(err)
 	}
 
-	flags := make([]string, len(args[1].Array.Elements))
+	flags := make([]string, len(args[1].Array().Elements))
 
-	for i, element := range args[1].Array.Elements {
+	for i, element := range args[1].Array().Elements {
 		flags[i] = element.Value().(string)
 	}
 
-	pid, err := sysops.Start_proc(args[0].String.ValueField, flags)
+	pid, err := sysops.Start_proc(args[0].String().ValueField, flags)
 	if err != nil {
 		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("%s", err), nil))
 	}
@@ -529,7 +529,7 @@ func KillProcess(args []*Value) *RTResult {
 		return res.Failure(err)
 	}
 
-	err := sysops.Kill_process(args[0].Number.ValueField.(int))
+	err := sysops.Kill_process(args[0].Number().Value().(int))
 	if err != nil {
 		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("%s", err), nil))
 	}
@@ -542,7 +542,7 @@ func GetEnv(args []*Value) *RTResult {
 		return res.Failure(err)
 	}
 
-	env, err := sysops.Get_env(args[0].String.ValueField)
+	env, err := sysops.Get_env(args[0].String().ValueField)
 	if err != nil {
 		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("%s", err), nil))
 	}
@@ -555,7 +555,7 @@ func SetEnv(args []*Value) *RTResult {
 		return res.Failure(err)
 	}
 
-	err := sysops.Set_env(args[0].String.ValueField, args[1].String.ValueField)
+	err := sysops.Set_env(args[0].String().ValueField, args[1].String().ValueField)
 	if err != nil {
 		return res.Failure(NewRTError(nil, nil, fmt.Sprintf("%s", err), nil))
 	}
@@ -583,12 +583,12 @@ func ExecCommand(args []*Value) *RTResult {
 		return res.Failure(err)
 	}
 
-	var argv = make([]string, len(args[1].Array.Elements))
-	for i, arg := range args[1].Array.Elements {
+	var argv = make([]string, len(args[1].Array().Elements))
+	for i, arg := range args[1].Array().Elements {
 		argv[i] = arg.Value().(string)
 	}
 
-	err := sysops.Exec_cmd(args[0].String.ValueField, argv)
+	err := sysops.Exec_cmd(args[0].String().ValueField, argv)
 	if err != nil {
 		return nil
 	}
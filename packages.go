@@ -3,7 +3,8 @@ package main
 var nativePackages = map[string]*Package{"os": packageOs}
 
 func (s *StdLibFunction) Copy() *Value {
-	f := &Value{StdLibFunction: &StdLibFunction{Base: s.Base, Function: s.Function}}
+	base := *s.Base
+	f := &Value{Kind: KIND_STD_LIB_FUNCTION, ref: &StdLibFunction{Base: &base, PackageName: s.PackageName, Function: s.Function}}
 	return f.SetPos(s.Base.PosStart(), s.Base.PosEnd()).SetContext(s.Base.Context)
 }
 
@@ -12,7 +13,7 @@ func (s *StdLibFunction) String() string {
 }
 
 func NewStdLibFunction(funcMethod func(args []*Value) *RTResult, base *BaseFunction, packageName string) *Value {
-	return &Value{StdLibFunction: &StdLibFunction{base, packageName, funcMethod}}
+	return &Value{Kind: KIND_STD_LIB_FUNCTION, ref: &StdLibFunction{base, packageName, funcMethod}}
 }
 
 var packageOs = &Package{Methods: map[string]*Value{
@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"strconv"
+	"sort"
 )
 
 // Register registers the result of a parsing operation.
@@ -20,14 +20,6 @@ func (pr *ParseResult) Success(node Node) *ParseResult {
 	return pr
 }
 
-func (pr *ParseResult) TryRegister(res *ParseResult) Node {
-	if res.Error != nil {
-		pr.ToReverseCount = res.AdvanceCount
-		return nil
-	}
-	return pr.Register(res)
-}
-
 // Failure returns a failed parsing result.
 func (pr *ParseResult) Failure(err Error) *ParseResult {
 	if pr.Error == nil || pr.AdvanceCount == 0 {
@@ -52,6 +44,33 @@ func NewParser(tokens []*Token) *Parser {
 	return parser
 }
 
+// NewLexerParser creates a Parser that reads the tokens from lexer while it parses.
+func NewLexerParser(lexer *Lexer) *Parser {
+	parser := &Parser{
+		TokIdx: -1,
+		lexer:  lexer,
+	}
+	parser.Advance()
+	return parser
+}
+
+// readTokens reads tokens from the lexer until the token at idx is available or the tokens ended.
+func (p *Parser) readTokens(idx int) {
+	for p.lexer != nil && idx >= len(p.Tokens) {
+		token, err := p.lexer.NextToken()
+		if err != nil {
+			// the error ends the tokens, syntax errors after it are caused by that
+			p.LexError = err
+			p.Errors = append(p.Errors, err)
+			token = &Token{Type: TT_EOF, PosStart: err.PosStart, PosEnd: err.PosEnd}
+		}
+		p.Tokens = append(p.Tokens, token)
+		if token.Type == TT_EOF {
+			p.lexer = nil
+		}
+	}
+}
+
 // Advance moves the parser to the next token.
 func (p *Parser) Advance() *Token {
 	p.TokIdx++
@@ -59,17 +78,77 @@ func (p *Parser) Advance() *Token {
 	return p.Current
 }
 
-func (p *Parser) Reverse(amount *int) *Token {
-	if amount == nil {
-		defaultAmount := 1
-		amount = &defaultAmount
+// Peek returns the token after the current one without advancing.
+func (p *Parser) Peek() *Token {
+	p.readTokens(p.TokIdx + 1)
+	if p.TokIdx+1 < len(p.Tokens) {
+		return p.Tokens[p.TokIdx+1]
 	}
-	p.TokIdx -= 1
-	p.UpdateCurrentTok()
 	return p.Current
 }
 
+// skipNewlines advances past newlines that do not end a statement, e.g. after a trailing operator.
+func (p *Parser) skipNewlines(res *ParseResult) {
+	for p.Current.Type == TT_NEWLINE {
+		res.RegisterAdvancement()
+		p.Advance()
+	}
+}
+
+// enterBrackets makes newlines insignificant until the returned function closes the brackets again.
+func (p *Parser) enterBrackets(res *ParseResult) func() {
+	p.brackets++
+	p.skipNewlines(res)
+	return func() { p.brackets-- }
+}
+
+// unclosed reports a missing closing bracket, at the end of the file it points to the bracket that was opened.
+func (p *Parser) unclosed(open *Token, expected ...string) Error {
+	if p.Current.Type == TT_EOF {
+		bracket := "("
+		if open.Type == TT_LSQUARE {
+			bracket = "["
+		}
+		return NewInvalidSyntaxError(open.PosStart, open.PosEnd, fmt.Sprintf("'%s' was never closed", bracket)).Error
+	}
+	return p.expected(expected...)
+}
+
+// takeLabel returns the label parsed in front of the current loop and clears it.
+func (p *Parser) takeLabel() *Token {
+	label := p.pendingLabel
+	p.pendingLabel = nil
+	return label
+}
+
+// enterLoop registers a loop whose body is parsed next.
+func (p *Parser) enterLoop(label *Token) {
+	p.loopLabels = append(p.loopLabels, labelName(label))
+}
+
+// exitLoop removes the innermost loop after its body was parsed.
+func (p *Parser) exitLoop() {
+	p.loopLabels = p.loopLabels[:len(p.loopLabels)-1]
+}
+
+// checkLoopTarget verifies that a break or continue is inside a loop and that its label belongs to an enclosing loop.
+func (p *Parser) checkLoopTarget(keyword *Token, label *Token) *InvalidSyntaxError {
+	if len(p.loopLabels) == 0 {
+		return NewInvalidSyntaxError(keyword.PosStart, keyword.PosEnd, fmt.Sprintf("'%s' outside of a loop", keyword.Value))
+	}
+	if label == nil {
+		return nil
+	}
+	for _, enclosing := range p.loopLabels {
+		if enclosing == label.Value.(string) {
+			return nil
+		}
+	}
+	return NewInvalidSyntaxError(label.PosStart, label.PosEnd, fmt.Sprintf("Unknown label '%s'", label.Value))
+}
+
 func (p *Parser) UpdateCurrentTok() {
+	p.readTokens(p.TokIdx)
 	if p.TokIdx >= 0 && p.TokIdx < len(p.Tokens) {
 		p.Current = p.Tokens[p.TokIdx]
 	}
@@ -78,19 +157,83 @@ func (p *Parser) UpdateCurrentTok() {
 // Parse parses the tokens into an abstract syntax tree.
 func (p *Parser) Parse() *ParseResult {
 	res := p.Statements()
-	if res.Error != nil && p.Current.Type != TT_EOF {
-		//log.Println("IMP ERR:", res.Error)
-		//err := NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected '+', '-', '*', '/', '^', '==', '!=', '<', '>', <=', '>=', 'AND' or 'OR'")
-		//n.return res.Failure(err.Error)
+	if len(p.Errors) > 0 {
+		return NewParseResult().Failure(*p.Errors[0])
 	}
 	return res
 }
 
+// report records a syntax error, unless the tokens ended at an error of the lexer.
+func (p *Parser) report(err *Error) {
+	if p.LexError == nil {
+		p.Errors = append(p.Errors, err)
+	}
+}
+
+// synchronize skips the rest of a statement with a syntax error. It stops at the next new line, '}' of the enclosing
+// block or keyword that starts a statement, unless it is inside of brackets or a block. The brackets that were opened
+// since the token at start are still open, the rest of them is skipped as well. skipCurrent skips the current token
+// in any case, so that a statement that failed at its first token is not parsed again.
+func (p *Parser) synchronize(res *ParseResult, start int, skipCurrent bool) {
+	depth := 0
+	for idx := start; idx < p.TokIdx; idx++ {
+		depth = nesting(depth, p.Tokens[idx], p.Tokens[idx+1])
+	}
+	if skipCurrent && p.Current.Type != TT_EOF {
+		depth = nesting(depth, p.Current, p.Peek())
+		res.RegisterAdvancement()
+		p.Advance()
+	}
+	for p.Current.Type != TT_EOF {
+		if depth == 0 && (p.Current.Type == TT_NEWLINE || p.Current.Type == TT_RBRACE && p.blocks > 1 || p.isSyncKeyword()) {
+			return
+		}
+		depth = nesting(depth, p.Current, p.Peek())
+		res.RegisterAdvancement()
+		p.Advance()
+	}
+}
+
+// nesting returns the depth of brackets and blocks after tok, next is the token that follows it.
+func nesting(depth int, tok *Token, next *Token) int {
+	switch tok.Type {
+	case TT_LPAREN, TT_LSQUARE:
+		return depth + 1
+	case TT_LBRACE:
+		// a block on a single line has no '}'
+		if next.Type == TT_NEWLINE {
+			return depth + 1
+		}
+	case TT_RPAREN, TT_RSQUARE, TT_RBRACE:
+		return max(depth-1, 0)
+	}
+	return depth
+}
+
+// syncKeywords are the keywords that start a statement after a syntax error.
+var syncKeywords = []string{"return", "continue", "break", "defer", "var", "const", "if", "for", "while", "func", "import"}
+
+// isSyncKeyword reports whether the current token is one of syncKeywords at the start of a statement, that is at the
+// start of the file, of a line or of a block on a single line. Elsewhere the keyword belongs to the statement that
+// failed, like the import of from "pk" import X.
+func (p *Parser) isSyncKeyword() bool {
+	if p.TokIdx > 0 && p.Tokens[p.TokIdx-1].Type != TT_NEWLINE && p.Tokens[p.TokIdx-1].Type != TT_LBRACE {
+		return false
+	}
+	for _, keyword := range syncKeywords {
+		if p.Current.Matches(TT_KEYWORD, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
 // listExpr method for Interpreter
 func (p *Parser) ArrayExpr() *ParseResult {
 	res := NewParseResult()
 	elementNodes := []Node{}
 	posStart := p.Current.PosStart.Copy()
+	open := p.Current
 
 	if p.Current.Type != TT_LSQUARE {
 		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected '['").Error)
@@ -98,20 +241,35 @@ func (p *Parser) ArrayExpr() *ParseResult {
 
 	res.RegisterAdvancement()
 	p.Advance()
+	defer p.enterBrackets(res)()
 
 	if p.Current.Type == TT_RSQUARE {
 		res.RegisterAdvancement()
 		p.Advance()
 	} else {
+		if !p.startsExpression() {
+			return res.Failure(p.unclosed(open, append([]string{"']'"}, expectedExpression()...)...))
+		}
 		elementNodes = append(elementNodes, res.Register(p.Expr()))
 		if res.Error != nil {
-			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected ']', 'var', 'if', 'for', 'while', 'func', int, float, identifier, '+', '-', '(', '[' or 'NOT'").Error)
+			return res
+		}
+
+		if p.Current.Matches(TT_KEYWORD, "for") {
+			return p.ComprehensionExpr(elementNodes[0], open)
 		}
 
 		for p.Current.Type == TT_COMMA {
 			res.RegisterAdvancement()
 			p.Advance()
+			p.skipNewlines(res)
 
+			if p.Current.Type == TT_RSQUARE {
+				break
+			}
+			if !p.startsExpression() {
+				return res.Failure(p.unclosed(open, append([]string{"']'"}, expectedExpression()...)...))
+			}
 			elementNodes = append(elementNodes, res.Register(p.Expr()))
 			if res.Error != nil {
 				return res
@@ -119,7 +277,7 @@ func (p *Parser) ArrayExpr() *ParseResult {
 		}
 
 		if p.Current.Type != TT_RSQUARE {
-			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected ',' or ']'").Error)
+			return res.Failure(p.unclosed(open, "','", "']'"))
 		}
 
 		res.RegisterAdvancement()
@@ -129,6 +287,91 @@ func (p *Parser) ArrayExpr() *ParseResult {
 	return res.Success(NewArrayNode(elementNodes, posStart, p.Current.PosEnd.Copy()))
 }
 
+// TupleExpr parses the remaining elements of '(a, b, ...)' into a frozen array, a single element needs a trailing comma.
+func (p *Parser) TupleExpr(first Node, open *Token) *ParseResult {
+	res := NewParseResult()
+	elementNodes := []Node{first}
+
+	for p.Current.Type == TT_COMMA {
+		res.RegisterAdvancement()
+		p.Advance()
+		p.skipNewlines(res)
+
+		if p.Current.Type == TT_RPAREN {
+			break
+		}
+		elementNodes = append(elementNodes, res.Register(p.Expr()))
+		if res.Error != nil {
+			return res
+		}
+	}
+
+	if p.Current.Type != TT_RPAREN {
+		return res.Failure(p.unclosed(open, "','", "')'"))
+	}
+	tuple := NewArrayNode(elementNodes, open.PosStart, p.Current.PosEnd.Copy())
+	tuple.Frozen = true
+	res.RegisterAdvancement()
+	p.Advance()
+
+	return res.Success(tuple)
+}
+
+// ComprehensionExpr parses the 'for x in iterable if condition' clauses following the first element of a list.
+func (p *Parser) ComprehensionExpr(elementNode Node, open *Token) *ParseResult {
+	res := NewParseResult()
+	clauses := []*ComprehensionClause{}
+	var expected []string
+
+	for p.Current.Matches(TT_KEYWORD, "for") {
+		res.RegisterAdvancement()
+		p.Advance()
+
+		if p.Current.Type != TT_IDENTIFIER {
+			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected identifier").Error)
+		}
+		varName := p.Current
+		res.RegisterAdvancement()
+		p.Advance()
+
+		if !p.Current.Matches(TT_KEYWORD, "in") {
+			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected 'in'").Error)
+		}
+		res.RegisterAdvancement()
+		p.Advance()
+
+		iterable := res.Register(p.Expr())
+		if res.Error != nil {
+			return res
+		}
+
+		// a clause has at most one condition
+		var condition Node
+		expected = []string{"'for'", "'if'", "']'"}
+		if p.Current.Matches(TT_KEYWORD, "if") {
+			res.RegisterAdvancement()
+			p.Advance()
+
+			condition = res.Register(p.Expr())
+			if res.Error != nil {
+				return res
+			}
+			expected = []string{"'for'", "']'"}
+		}
+
+		clauses = append(clauses, &ComprehensionClause{varName, iterable, condition, nil})
+	}
+
+	if p.Current.Type != TT_RSQUARE {
+		return res.Failure(p.unclosed(open, expected...))
+	}
+	posEnd := p.Current.PosEnd.Copy()
+	res.RegisterAdvancement()
+	p.Advance()
+
+	return res.Success(NewComprehensionNode(elementNode, clauses, open.PosStart, posEnd))
+}
+
 // ifExpr is a method of Parser that handles 'IF' expressions.
 func (p *Parser) ifExpr() *ParseResult {
 	res := NewParseResult()
@@ -295,6 +538,7 @@ func (p *Parser) ForExpr() *ParseResult {
 		).Error)
 	}
 
+	label := p.takeLabel()
 	res.RegisterAdvancement()
 	p.Advance()
 
@@ -360,6 +604,9 @@ func (p *Parser) ForExpr() *ParseResult {
 	res.RegisterAdvancement()
 	p.Advance()
 
+	p.enterLoop(label)
+	defer p.exitLoop()
+
 	if p.Current.Type == TT_NEWLINE {
 		body := res.Register(p.Statements())
 		if res.Error != nil {
@@ -375,7 +622,7 @@ func (p *Parser) ForExpr() *ParseResult {
 		res.RegisterAdvancement()
 		p.Advance()
 
-		return res.Success(NewForNode(varName, startValue, endValue, stepValue, body, true))
+		return res.Success(NewForNode(label, varName, startValue, endValue, stepValue, body, true))
 	}
 
 	body := res.Register(p.Statement())
@@ -383,7 +630,7 @@ func (p *Parser) ForExpr() *ParseResult {
 		return res
 	}
 
-	return res.Success(NewForNode(varName, startValue, endValue, stepValue, body, false))
+	return res.Success(NewForNode(label, varName, startValue, endValue, stepValue, body, false))
 }
 
 func (p *Parser) WhileExpr() *ParseResult {
@@ -396,6 +643,7 @@ func (p *Parser) WhileExpr() *ParseResult {
 		).Error)
 	}
 
+	label := p.takeLabel()
 	res.RegisterAdvancement()
 	p.Advance()
 
@@ -414,6 +662,9 @@ func (p *Parser) WhileExpr() *ParseResult {
 	res.RegisterAdvancement()
 	p.Advance()
 
+	p.enterLoop(label)
+	defer p.exitLoop()
+
 	if p.Current.Type == TT_NEWLINE {
 		res.RegisterAdvancement()
 		p.Advance()
@@ -430,7 +681,7 @@ func (p *Parser) WhileExpr() *ParseResult {
 		res.RegisterAdvancement()
 		p.Advance()
 
-		return res.Success(NewWhileNode(condition, body, true))
+		return res.Success(NewWhileNode(label, condition, body, true))
 	}
 
 	body := res.Register(p.Statement())
@@ -438,51 +689,211 @@ func (p *Parser) WhileExpr() *ParseResult {
 		return res
 	}
 
-	return res.Success(NewWhileNode(condition, body, false))
+	return res.Success(NewWhileNode(label, condition, body, false))
 }
 
-func (p *Parser) Call() *ParseResult {
-	res := NewParseResult()
-	atom := res.Register(p.Atom())
+// isOptionalIndex reports whether the parser is at '?[' written without a space, which starts an optional index.
+func (p *Parser) isOptionalIndex() bool {
+	next := p.Peek()
+	return p.Current.Type == TT_QUESTION && next.Type == TT_LSQUARE && next.PosStart.Idx == p.Current.PosEnd.Idx
+}
+
+// typeNames are the names that can be used in type annotations.
+var typeNames = []string{"Any", "Number", "String", "Boolean", "Array", "Null", "Function", "ByteArray", "Pointer", "Task", "Channel"}
+
+// OptionalType parses a type annotation if the current token is the given separator, e.g. ':' or '->'. Errors are recorded in res.
+func (p *Parser) OptionalType(res *ParseResult, separator TokenTypes) *TypeAnnotation {
+	if p.Current.Type != separator {
+		return nil
+	}
+	res.RegisterAdvancement()
+	p.Advance()
+	return p.TypeExpr(res)
+}
+
+// TypeExpr parses a type like 'Number' or 'Array<String>'. Errors are recorded in res.
+func (p *Parser) TypeExpr(res *ParseResult) *TypeAnnotation {
+	if p.Current.Type != TT_IDENTIFIER {
+		res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected type").Error)
+		return nil
+	}
+
+	nameTok := p.Current
+	known := false
+	for _, name := range typeNames {
+		if name == nameTok.Value.(string) {
+			known = true
+		}
+	}
+	if !known {
+		res.Failure(NewInvalidSyntaxError(nameTok.PosStart, nameTok.PosEnd, fmt.Sprintf("Unknown type '%s'", nameTok.Value)).Error)
+		return nil
+	}
+	res.RegisterAdvancement()
+	p.Advance()
+
+	annotation := &TypeAnnotation{Name: nameTok.Value.(string), PositionStart: nameTok.PosStart, PositionEnd: nameTok.PosEnd}
+	if annotation.Name != "Array" || p.Current.Type != TT_LT {
+		return annotation
+	}
+
+	res.RegisterAdvancement()
+	p.Advance()
+	annotation.Element = p.TypeExpr(res)
 	if res.Error != nil {
-		return res
+		return nil
+	}
+	if p.Current.Type != TT_GT {
+		res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected '>'").Error)
+		return nil
+	}
+	annotation.PositionEnd = p.Current.PosEnd
+	res.RegisterAdvancement()
+	p.Advance()
+	return annotation
+}
+
+// CallArgs parses a parenthesized, comma separated list of arguments, errors are recorded in res.
+func (p *Parser) CallArgs(res *ParseResult) []Node {
+	var ArgNodes []Node
+	open := p.Current
+
+	res.RegisterAdvancement()
+	p.Advance()
+	defer p.enterBrackets(res)()
+
+	if p.Current.Type == TT_RPAREN {
+		res.RegisterAdvancement()
+		p.Advance()
+		return ArgNodes
+	}
+
+	if !p.startsExpression() {
+		res.Failure(p.unclosed(open, append([]string{"')'"}, expectedExpression()...)...))
+		return nil
+	}
+	ArgNodes = append(ArgNodes, res.Register(p.Expr()))
+	if res.Error != nil {
+		return nil
 	}
 
-	if p.Current.Type == TT_LPAREN {
+	for p.Current.Type == TT_COMMA {
 		res.RegisterAdvancement()
 		p.Advance()
-		var ArgNodes []Node
+		p.skipNewlines(res)
 
 		if p.Current.Type == TT_RPAREN {
-			res.RegisterAdvancement()
-			p.Advance()
-		} else {
-			ArgNodes = append(ArgNodes, res.Register(p.Expr()))
-			if res.Error != nil {
-				return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected ')', 'var', 'if', 'for', 'while', 'func', int, float, identifier, pointer, '+', '-', '(', '[' or 'not'").Error)
-			}
+			break
+		}
+		if !p.startsExpression() {
+			res.Failure(p.unclosed(open, append([]string{"')'"}, expectedExpression()...)...))
+			return nil
+		}
+		ArgNodes = append(ArgNodes, res.Register(p.Expr()))
+		if res.Error != nil {
+			return nil
+		}
+	}
 
-			for p.Current.Type == TT_COMMA {
-				res.RegisterAdvancement()
-				p.Advance()
+	if p.Current.Type != TT_RPAREN {
+		res.Failure(p.unclosed(open, "','", "')'"))
+		return nil
+	}
 
-				ArgNodes = append(ArgNodes, res.Register(p.Expr()))
-				if res.Error != nil {
-					return res
-				}
-			}
+	res.RegisterAdvancement()
+	p.Advance()
+	return ArgNodes
+}
 
-			if p.Current.Type != TT_RPAREN {
-				return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected ',' or ')'").Error)
+// DecoratedFuncDef parses '@decorator' and '@decorator(args)' lines followed by a named function declaration.
+func (p *Parser) DecoratedFuncDef() *ParseResult {
+	res := NewParseResult()
+	var decorators []Node
+
+	for p.Current.Type == TT_AT {
+		res.RegisterAdvancement()
+		p.Advance()
+
+		if p.Current.Type != TT_IDENTIFIER {
+			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected decorator name after '@'").Error)
+		}
+		var decorator Node = NewVarAccessNode(p.Current)
+		res.RegisterAdvancement()
+		p.Advance()
+
+		if p.Current.Type == TT_LPAREN {
+			args := p.CallArgs(res)
+			if res.Error != nil {
+				return res
 			}
+			decorator = NewCallNode(decorator, args)
+		}
+		decorators = append(decorators, decorator)
 
+		if p.Current.Type != TT_NEWLINE {
+			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected new line after decorator").Error)
+		}
+		for p.Current.Type == TT_NEWLINE {
 			res.RegisterAdvancement()
 			p.Advance()
 		}
+	}
 
-		return res.Success(NewCallNode(atom, ArgNodes))
+	if !p.Current.Matches(TT_KEYWORD, "func") {
+		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected 'func' after decorator").Error)
 	}
-	return res.Success(atom)
+	funcDef := res.Register(p.FuncDef())
+	if res.Error != nil {
+		return res
+	}
+	node, ok := funcDef.(*FuncDefNode)
+	if !ok || node.VarNameTok == nil {
+		return res.Failure(NewInvalidSyntaxError(funcDef.PosStart(), funcDef.PosEnd(), "Decorators can only be applied to named functions").Error)
+	}
+	node.Decorators = decorators
+	return res.Success(node)
+}
+
+// FuncParams parses the parenthesized parameters of a function definition, errors are recorded in res.
+func (p *Parser) FuncParams(res *ParseResult) ([]*Token, []*TypeAnnotation) {
+	var ArgNameTokens []*Token
+	var ArgTypes []*TypeAnnotation
+	open := p.Current
+
+	res.RegisterAdvancement()
+	p.Advance()
+	defer p.enterBrackets(res)()
+
+	for p.Current.Type == TT_IDENTIFIER {
+		ArgNameTokens = append(ArgNameTokens, p.Current)
+		res.RegisterAdvancement()
+		p.Advance()
+		ArgTypes = append(ArgTypes, p.OptionalType(res, TT_COLON))
+		if res.Error != nil {
+			return nil, nil
+		}
+		p.skipNewlines(res)
+
+		if p.Current.Type != TT_COMMA {
+			break
+		}
+		res.RegisterAdvancement()
+		p.Advance()
+		p.skipNewlines(res)
+
+		if p.Current.Type != TT_IDENTIFIER && p.Current.Type != TT_RPAREN {
+			res.Failure(p.unclosed(open, "identifier", "')'"))
+			return nil, nil
+		}
+	}
+
+	if p.Current.Type != TT_RPAREN {
+		res.Failure(p.unclosed(open, "','", "')'"))
+		return nil, nil
+	}
+	res.RegisterAdvancement()
+	p.Advance()
+	return ArgNameTokens, ArgTypes
 }
 
 func (p *Parser) FuncDef() *ParseResult {
@@ -519,72 +930,46 @@ func (p *Parser) FuncDef() *ParseResult {
 		}
 
 	}
-	res.RegisterAdvancement()
-	p.Advance()
-	var ArgNameTokens []*Token
+	ArgNameTokens, ArgTypes := p.FuncParams(res)
+	if res.Error != nil {
+		return res
+	}
 
-	if p.Current.Type == TT_IDENTIFIER {
-		ArgNameTokens = append(ArgNameTokens, p.Current)
-		res.RegisterAdvancement()
-		p.Advance()
+	ReturnType := p.OptionalType(res, TT_RARROW)
+	if res.Error != nil {
+		return res
+	}
 
-		for p.Current.Type == TT_COMMA {
-			res.RegisterAdvancement()
-			p.Advance()
+	// break and continue inside of the body can not reach loops around the function definition
+	enclosingLoops := p.loopLabels
+	p.loopLabels = nil
+	defer func() { p.loopLabels = enclosingLoops }()
 
-			if p.Current.Type != TT_IDENTIFIER {
-				return res.Failure(NewInvalidSyntaxError(
-					p.Current.PosStart, p.Current.PosEnd,
-					"Expected identifier",
-				).Error)
-			}
+	if p.Current.Type == TT_ARROW {
+		res.RegisterAdvancement()
+		p.Advance()
+		p.skipNewlines(res)
 
-			ArgNameTokens = append(ArgNameTokens, p.Current)
-			res.RegisterAdvancement()
-			p.Advance()
+		body := res.Register(p.Expr())
+		if res.Error != nil {
+			return res
 		}
 
-		if p.Current.Type != TT_RPAREN {
-			return res.Failure(NewInvalidSyntaxError(
-				p.Current.PosStart, p.Current.PosEnd,
-				"Expected ',' or ')'",
-			).Error)
-		}
-	} else {
-		if p.Current.Type != TT_RPAREN {
-			return res.Failure(NewInvalidSyntaxError(
-				p.Current.PosStart, p.Current.PosEnd,
-				"Expected ',' or ')'",
-			).Error)
-		}
+		return res.Success(NewFuncDefNode(VarNameToken, ArgNameTokens, ArgTypes, ReturnType, body, true))
 	}
-	res.RegisterAdvancement()
-	p.Advance()
 
 	if p.Current.Type != TT_LBRACE {
 		return res.Failure(NewInvalidSyntaxError(
 			p.Current.PosStart, p.Current.PosEnd,
-			"Expected '{'",
+			"Expected '{' or '=>'",
 		).Error)
 	}
 
 	res.RegisterAdvancement()
 	p.Advance()
 
-	if p.Current.Type == TT_ARROW {
-		res.RegisterAdvancement()
-		p.Advance()
-
-		body := res.Register(p.Expr())
-		if res.Error != nil {
-			return res
-		}
-
-		return res.Success(NewFuncDefNode(VarNameToken, ArgNameTokens, body, true))
-	}
-
 	if p.Current.Type != TT_NEWLINE {
-		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected '=>' or new line").Error)
+		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected new line").Error)
 	}
 
 	res.RegisterAdvancement()
@@ -602,167 +987,34 @@ func (p *Parser) FuncDef() *ParseResult {
 	res.RegisterAdvancement()
 	p.Advance()
 
-	return res.Success(NewFuncDefNode(VarNameToken, ArgNameTokens, body, false))
+	return res.Success(NewFuncDefNode(VarNameToken, ArgNameTokens, ArgTypes, ReturnType, body, false))
 }
 
-func (p *Parser) Atom() *ParseResult {
+func (p *Parser) SpawnExpr() *ParseResult {
 	res := NewParseResult()
-	tok := p.Current
-	if tok.Type == TT_INT || tok.Type == TT_FLOAT {
-		p.Advance() // Advance token index here
-		var err error
-		if tok.Type == TT_FLOAT {
-			tok.Value, err = strconv.ParseFloat(tok.Value.(string), 64)
-			if err != nil {
-				fmt.Println(err)
-			}
-		}
-		if tok.Type == TT_INT {
-			value, err := strconv.ParseInt(tok.Value.(string), 10, 64)
-			if err != nil {
-				fmt.Println(err)
-			}
-			tok.Value = int(value)
-		}
-		return res.Success(NewNumberNode(tok))
-	} else if tok.Type == TT_STRING {
-		res.RegisterAdvancement()
-		p.Advance()
-		return res.Success(NewStringNode(tok))
-	} else if tok.Type == TT_IDENTIFIER {
-		res.RegisterAdvancement()
-		p.Advance()
-
-		// in case of an index from an array
-		if p.Current.Type == TT_LSQUARE {
-			res.RegisterAdvancement()
-			p.Advance()
-
-			if p.Current.Type == TT_INT {
-				index := p.Current
-				indexValue, err := strconv.ParseInt(p.Current.Value.(string), 10, 64)
-				index.Value = int(indexValue)
-
-				if err != nil {
-					fmt.Println(err)
-				}
-
-				res.RegisterAdvancement()
-				p.Advance()
-
-				if p.Current.Type == TT_RSQUARE {
-					res.RegisterAdvancement()
-					p.Advance()
-					return res.Success(NewIndexNode(NewVarAccessNode(tok), NewNumberNode(index)))
-				}
-			} else {
-				return res.Failure(NewInvalidSyntaxError(tok.PosStart, tok.PosEnd, fmt.Sprintf("Expected int, got: %v", tok.Value)).Error)
-			}
-		} else {
-			return res.Success(NewVarAccessNode(tok))
-		}
-	} else if tok.Type == TT_LSQUARE {
-		listExpr := res.Register(p.ArrayExpr())
-		if res.Error != nil {
-			return res
-		}
-		return res.Success(listExpr)
-	} else if tok.Type == TT_LPAREN {
-		p.Advance()
-		expr := res.Register(p.Expr())
-		if res.Error != nil {
-			return res
-		}
-		if p.Current.Type == TT_RPAREN {
-			p.Advance()
-			return res.Success(expr)
-		}
-		err := NewInvalidSyntaxError(tok.PosStart, tok.PosEnd, "Expected ')'")
-		return res.Failure(err.Error)
-	} else if tok.Matches(TT_KEYWORD, "if") {
-		IfExpr := res.Register(p.ifExpr())
-		if res.Error != nil {
-			return res
-		}
-		return res.Success(IfExpr)
-	} else if tok.Matches(TT_KEYWORD, "for") {
-		IfExpr := res.Register(p.ForExpr())
-		if res.Error != nil {
-			return res
-		}
-		return res.Success(IfExpr)
-	} else if tok.Matches(TT_KEYWORD, "while") {
-		WhileExpr := res.Register(p.WhileExpr())
-		if res.Error != nil {
-			return res
-		}
-		return res.Success(WhileExpr)
-	} else if tok.Matches(TT_KEYWORD, "func") {
-		FuncDef := res.Register(p.FuncDef())
-		if res.Error != nil {
-			return res
-		}
-		return res.Success(FuncDef)
-	} else if tok.Matches(TT_KEYWORD, "import") {
-		ImportExpr := res.Register(p.ImportExpr())
-		if res.Error != nil {
-			return res
-		}
-		return res.Success(ImportExpr)
-	}
-
-	return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected int, float, identifier, '+', '-' or '(', 'IF', 'FOR', 'WHILE', 'FUNC'").Error)
-}
-
-func (p *Parser) Power() *ParseResult {
-	return p.BinOp(p.Call, []TokenTypeInfo{{TT_POW, nil}}, p.Factor)
-}
-
-// ArithExpr parses an arithmetic expression.
-func (p *Parser) ArithExpr() *ParseResult {
-	return p.BinOp(p.Term, []TokenTypeInfo{{TT_PLUS, nil}, {TT_MINUS, nil}}, p.Term)
-}
-
-// CompExpr parses a comparison expression.
-func (p *Parser) CompExpr() *ParseResult {
-	res := ParseResult{AdvanceCount: 0}
-
-	if p.Current.Matches(TT_KEYWORD, "not") {
-		opTok := p.Current
-		res.RegisterAdvancement()
-		p.Advance()
+	posStart := p.Current.PosStart.Copy()
 
-		node := res.Register(p.CompExpr())
-		if res.Error != nil {
-			return &res
-		}
-		return res.Success(NewUnaryOpNode(opTok, node))
+	if !p.Current.Matches(TT_KEYWORD, "spawn") {
+		return res.Failure(NewInvalidSyntaxError(
+			p.Current.PosStart, p.Current.PosEnd,
+			"Expected 'spawn'",
+		).Error)
 	}
 
-	node := res.Register(p.BinOp(p.ArithExpr, []TokenTypeInfo{{TT_EE, nil}, {TT_NE, nil}, {TT_LT, nil}, {TT_GT, nil}, {TT_LTE, nil}, {TT_GTE, nil}}, p.ArithExpr))
+	res.RegisterAdvancement()
+	p.Advance()
 
+	call := p.expression(res, precPostfix)
 	if res.Error != nil {
-		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected int, float, identifier, '+', '-', '(', '[' or 'not'").Error)
+		return res
 	}
 
-	return res.Success(node)
-}
-
-// Factor parses a factor.
-func (p *Parser) Factor() *ParseResult {
-	res := NewParseResult()
-	tok := p.Current
-	if tok.Type == TT_PLUS || tok.Type == TT_MINUS {
-		p.Advance()
-		factor := res.Register(p.Factor())
-		return res.Success(NewUnaryOpNode(tok, factor))
+	callNode, ok := call.(*CallNode)
+	if !ok {
+		return res.Failure(NewInvalidSyntaxError(call.PosStart(), call.PosEnd(), "Expected function call after 'spawn'").Error)
 	}
 
-	return p.Power()
-}
-
-func (p *Parser) Term() *ParseResult {
-	return p.BinOp(p.Factor, []TokenTypeInfo{{TT_STAR, nil}, {TT_DIV, nil}}, p.Factor)
+	return res.Success(NewSpawnNode(callNode, posStart))
 }
 
 func (p *Parser) Statements() *ParseResult {
@@ -771,41 +1023,50 @@ func (p *Parser) Statements() *ParseResult {
 	var statements []Node
 	PosStart := p.Current.PosStart.Copy()
 
-	for p.Current.Type == TT_NEWLINE {
-		res.RegisterAdvancement()
-		p.Advance()
-	}
-
-	statement := res.Register(p.Statement())
-	if res.Error != nil {
-		return &res
-	}
+	// newlines separate the statements of a block, even if the block is inside of brackets
+	brackets := p.brackets
+	p.brackets = 0
+	defer func() { p.brackets = brackets }()
 
-	statements = append(statements, statement)
-
-	MoreStatements := true
+	p.blocks++
+	defer func() { p.blocks-- }()
 
 	for {
-		NewlineCount := 0
 		for p.Current.Type == TT_NEWLINE {
 			res.RegisterAdvancement()
 			p.Advance()
-			NewlineCount++
-		}
-		if NewlineCount == 0 {
-			MoreStatements = false
 		}
-		if !MoreStatements {
+		if p.Current.Type == TT_EOF || p.blocks > 1 && p.endsBlock() {
 			break
 		}
+		if p.skipUnmatched(&res) {
+			continue
+		}
 
-		statement = res.TryRegister(p.Statement())
-		if statement == nil {
-			p.Reverse(&res.ToReverseCount)
-			MoreStatements = false
+		start := p.TokIdx
+		statementRes := p.Statement()
+		statement := res.Register(statementRes)
+		if res.Error != nil {
+			// report the error and continue with the next statement
+			p.report(res.Error)
+			res.Error = nil
+			p.synchronize(&res, start, p.TokIdx == start)
 			continue
 		}
 		statements = append(statements, statement)
+
+		if p.Current.Type != TT_NEWLINE && p.Current.Type != TT_EOF && (p.blocks == 1 || !p.endsBlock()) {
+			if p.skipUnmatched(&res) {
+				continue
+			}
+			expected := []string{"new line", "';'"}
+			if p.blocks > 1 {
+				expected = append(expected, "'}'")
+			}
+			err := p.expected(expected...)
+			p.report(&err)
+			p.synchronize(&res, p.TokIdx, false)
+		}
 	}
 
 	return res.Success(NewArrayNode(statements, PosStart, p.Current.PosEnd))
@@ -819,31 +1080,119 @@ func (p *Parser) Statement() *ParseResult {
 		res.RegisterAdvancement()
 		p.Advance()
 
-		expr := res.TryRegister(p.Expr())
-		if expr == nil {
-			p.Reverse(&res.ToReverseCount)
+		var expr Node
+		if p.startsExpression() {
+			expr = res.Register(p.Expr())
+			if res.Error != nil {
+				return res
+			}
 		}
 		return res.Success(NewReturnNode(expr, PosStart, p.Current.PosEnd.Copy()))
 	}
-	if p.Current.Matches(TT_KEYWORD, "continue") {
+	if p.Current.Matches(TT_KEYWORD, "continue") || p.Current.Matches(TT_KEYWORD, "break") {
+		keyword := p.Current
 		res.RegisterAdvancement()
 		p.Advance()
-		return res.Success(NewContinueNode(PosStart, p.Current.PosEnd.Copy()))
+
+		var label *Token
+		if p.Current.Type == TT_IDENTIFIER {
+			label = p.Current
+			res.RegisterAdvancement()
+			p.Advance()
+		}
+
+		if err := p.checkLoopTarget(keyword, label); err != nil {
+			return res.Failure(err.Error)
+		}
+
+		if keyword.Value == "continue" {
+			return res.Success(NewContinueNode(label, PosStart, p.Current.PosEnd.Copy()))
+		}
+		return res.Success(NewBreakNode(label, PosStart, p.Current.PosEnd.Copy()))
 	}
-	if p.Current.Matches(TT_KEYWORD, "break") {
+	if p.Current.Type == TT_IDENTIFIER && p.Peek().Type == TT_COLON {
+		label := p.Current
 		res.RegisterAdvancement()
 		p.Advance()
-		return res.Success(NewBreakNode(PosStart, p.Current.PosEnd.Copy()))
+		res.RegisterAdvancement()
+		p.Advance()
+		p.skipNewlines(res)
+
+		if !p.Current.Matches(TT_KEYWORD, "for") && !p.Current.Matches(TT_KEYWORD, "while") {
+			return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected 'for' or 'while' after label").Error)
+		}
+		for _, enclosing := range p.loopLabels {
+			if enclosing == label.Value.(string) {
+				return res.Failure(NewInvalidSyntaxError(label.PosStart, label.PosEnd, fmt.Sprintf("Label '%s' is already used by an enclosing loop", enclosing)).Error)
+			}
+		}
+		p.pendingLabel = label
+	}
+	if p.Current.Type == TT_AT {
+		return p.DecoratedFuncDef()
+	}
+	if p.Current.Matches(TT_KEYWORD, "defer") {
+		res.RegisterAdvancement()
+		p.Advance()
+
+		expr := res.Register(p.Expr())
+		if res.Error != nil {
+			return res
+		}
+		if methodCall, ok := expr.(*MethodCallNode); !ok || !methodCall.IsCall {
+			if _, ok := expr.(*CallNode); !ok {
+				return res.Failure(NewInvalidSyntaxError(expr.PosStart(), expr.PosEnd(), "Expected function call after 'defer'").Error)
+			}
+		}
+		return res.Success(NewDeferNode(expr, PosStart))
 	}
 
 	expr := res.Register(p.Expr())
 	if res.Error != nil {
-		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected 'RETURN', 'CONTINUE', 'BREAK', 'VAR', 'IF', 'FOR', 'WHILE', 'FUN', int, float, identifier, '+', '-', '(', '[' or 'NOT'").Error)
+		return res.Failure(p.expected(expectedStatement()...))
 	}
 	return res.Success(expr)
 
 }
 
+// openingBrackets names the bracket that each closing bracket closes.
+var openingBrackets = map[TokenTypes][2]string{
+	TT_RPAREN:  {"')'", "'('"},
+	TT_RSQUARE: {"']'", "'['"},
+	TT_RBRACE:  {"'}'", "'{'"},
+}
+
+// skipUnmatched reports and skips a closing bracket between statements, it does not close anything there.
+func (p *Parser) skipUnmatched(res *ParseResult) bool {
+	names, closing := openingBrackets[p.Current.Type]
+	if !closing {
+		return false
+	}
+	p.report(&NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, fmt.Sprintf("Unexpected %s without a matching %s", names[0], names[1])).Error)
+	res.RegisterAdvancement()
+	p.Advance()
+	return true
+}
+
+// endsBlock reports whether the current token ends the statements of a block, e.g. '}' or 'elif'.
+func (p *Parser) endsBlock() bool {
+	return p.Current.Type == TT_RBRACE || p.Current.Matches(TT_KEYWORD, "elif") || p.Current.Matches(TT_KEYWORD, "else")
+}
+
+// expectedExpression lists the tokens that can start an expression.
+func expectedExpression() []string {
+	expected := append(expectedOperands(precLowest), "'var'", "'const'")
+	sort.Strings(expected)
+	return expected
+}
+
+// expectedStatement lists the tokens that can start a statement.
+func expectedStatement() []string {
+	expected := append(expectedExpression(), "'return'", "'continue'", "'break'", "'defer'", "'@'")
+	sort.Strings(expected)
+	return expected
+}
+
 // Expr parses an expression.
 func (p *Parser) Expr() *ParseResult {
 	res := NewParseResult()
@@ -869,6 +1218,11 @@ func (p *Parser) Expr() *ParseResult {
 		res.RegisterAdvancement()
 		p.Advance()
 
+		varType := p.OptionalType(res, TT_COLON)
+		if res.Error != nil {
+			return res
+		}
+
 		if p.Current.Type != TT_EQ {
 			if isConst {
 				return res.Failure(NewInvalidSyntaxError(
@@ -876,7 +1230,7 @@ func (p *Parser) Expr() *ParseResult {
 					"Missing assignment in const declaration",
 				).Error)
 			} else if p.Current.Type == TT_NEWLINE {
-				return res.Success(NewVarAssignNode(varName, nil, false, true))
+				return res.Success(NewVarAssignNode(varName, nil, false, true).WithType(varType))
 			} else {
 				return res.Failure(NewInvalidSyntaxError(
 					p.Current.PosStart, p.Current.PosEnd,
@@ -887,86 +1241,33 @@ func (p *Parser) Expr() *ParseResult {
 
 		res.RegisterAdvancement()
 		p.Advance()
-
-		if p.Current.Type == TT_AND {
-			res.RegisterAdvancement()
-			p.Advance()
-			expr := NewReference(res.Register(p.Expr()))
-			if res.Error != nil {
-				return res
-			}
-
-			return res.Success(NewVarAssignNode(varName, expr, isConst, true))
-		} else if p.Current.Type == TT_STAR {
-			res.RegisterAdvancement()
-			p.Advance()
-			expr := NewDereference(res.Register(p.Expr()))
-			if res.Error != nil {
-				return res
-			}
-
-			return res.Success(NewVarAssignNode(varName, expr, isConst, true))
-		}
+		p.skipNewlines(res)
 
 		expr := res.Register(p.Expr())
 		if res.Error != nil {
 			return res
 		}
-		return res.Success(NewVarAssignNode(varName, expr, isConst, true))
-	} else if p.Current.Type == TT_IDENTIFIER { // in case of a variable re-assignment, so we don't need the var keyword for each assignment, only for the initial
+		return res.Success(NewVarAssignNode(varName, expr, isConst, true).WithType(varType))
+	} else if p.Current.Type == TT_IDENTIFIER && p.Peek().Type == TT_EQ { // in case of a variable re-assignment, so we don't need the var keyword for each assignment, only for the initial
 		varName := p.Current
 		res.RegisterAdvancement()
 		p.Advance()
-
-		if p.Current.Type == TT_EQ {
-			res.RegisterAdvancement()
-			p.Advance()
-
-			expr := res.Register(p.Expr())
-			if res.Error != nil {
-				return res
-			}
-
-			return res.Success(NewVarAssignNode(varName, expr, false, false))
-		} else if p.Current.Type == TT_DOT {
-			res.RegisterAdvancement()
-			p.Advance()
-			callNode := p.Call()
-			if _, ok := callNode.Node.(*CallNode); ok {
-				return res.Success(NewPackageMethod(varName, callNode.Node.(*CallNode).NodeToCall.(*VarAccessNode).VarNameTok.Value.(string), callNode.Node))
-			} else if _, ok := callNode.Node.(*VarAccessNode); ok {
-				return res.Success(NewPackageMethod(varName, callNode.Node.(*VarAccessNode).VarNameTok.Value.(string), callNode.Node))
-			}
-		} else {
-			p.Reverse(&res.ToReverseCount)
-		}
-	} else if p.Current.Type == TT_AND {
 		res.RegisterAdvancement()
 		p.Advance()
-		ref := NewReference(res.Register(p.Expr()))
-		if res.Error != nil {
-			return res
-		}
+		p.skipNewlines(res)
 
-		return res.Success(ref)
-	} else if p.Current.Type == TT_STAR {
-		res.RegisterAdvancement()
-		p.Advance()
-		deref := NewDereference(res.Register(p.Expr()))
+		expr := res.Register(p.Expr())
 		if res.Error != nil {
 			return res
 		}
 
-		return res.Success(deref)
+		return res.Success(NewVarAssignNode(varName, expr, false, false))
 	}
 
-	and := "AND"
-	or := "OR"
-	node := res.Register(p.BinOp(p.CompExpr, []TokenTypeInfo{{TT_KEYWORD, &and}, {TT_KEYWORD, &or}}, p.CompExpr))
+	node := p.expression(res, precLowest)
 	if res.Error != nil {
-		return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, "Expected 'var', 'if', 'for', 'while', 'func', int, float, identifier, '+', '-', '(', '[' or 'not'").Error)
+		return res.Failure(p.expected(expectedExpression()...))
 	}
-
 	return res.Success(node)
 }
 
@@ -1016,48 +1317,6 @@ func (p *Parser) ImportExpr() *ParseResult {
 	return res.Failure(NewInvalidSyntaxError(p.Current.PosStart, p.Current.PosEnd, fmt.Sprintf("Can not import %s, type %s", p.Current.Value, p.Current.Type)).Error)
 }
 
-// / BinOp parses a binary operation.
-func (p *Parser) BinOp(funcAParser func() *ParseResult, ops []TokenTypeInfo, funcBParser func() *ParseResult) *ParseResult {
-	res := NewParseResult()
-
-	left := res.Register(funcAParser())
-	if res.Error != nil {
-		return res
-	}
-
-	for ContainsTypeOrValue(ops, p.Current.Type, p.Current.Value) {
-		opTok := p.Current
-		res.RegisterAdvancement()
-		p.Advance()
-
-		// Parse the right side of the expression
-		right := res.Register(funcBParser())
-
-		if res.Error != nil {
-			return res
-		}
-		left = NewBinOpNode(left, opTok, right)
-	}
-
-	return res.Success(left)
-}
-
-// ContainsTypeOrValue checks if the token type and value exist in the list of type-value pairs.
-func ContainsTypeOrValue(types []TokenTypeInfo, typ TokenTypes, val interface{}) bool {
-	for _, t := range types {
-		if val != nil {
-			if t.Value != nil {
-				if *t.Value == val.(string) && t.Type == typ {
-					return true
-				}
-			}
-		} else if t.Type == typ {
-			return true
-		}
-	}
-	return false
-}
-
 func NewParseResult() *ParseResult {
 	return &ParseResult{AdvanceCount: 0}
 }
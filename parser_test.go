@@ -0,0 +1,195 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"2 + 3 * 4", "14"},
+		{"(2 + 3) * 4", "20"},
+		{"10 - 2 - 3", "5"},
+		{"24 / 4 / 2", "3"},
+		{"2 ^ 3 ^ 2", "512"},
+		{"-2 ^ 2", "-4"},
+		{"2 ^ -1 * 4", "2"},
+		{"2 * 3 ^ 2", "18"},
+		{"1 + 2 == 3", "true"},
+		{"null ?? 1 + 2", "3"},
+		{"1 == 1 ? 2 : 3 + 4", "2"},
+		{"1 == 2 ? 2 : 1 == 1 ? 3 : 4", "3"},
+		{"[1, 2] + [3] |> len", "3"},
+		{"2 * 3 |> str", "6"},
+		{"[[1, 2], [3]][0][1] + 1", "3"},
+		{"func(x) => x * 2", "<function <anonymous>>"},
+		{"(func(x) => x * 2)(4) + 1", "9"},
+		{"(1)", "1"},
+		{"(1,)", "[1]"},
+		{"(1, 2)", "[1, 2]"},
+	}
+
+	programTests := make([]programTest, len(tests))
+	for idx, test := range tests {
+		programTests[idx] = programTest{name: test.expr, source: "var out = " + test.expr, want: test.want}
+	}
+	runProgramTests(t, programTests)
+}
+
+func TestPrefixOperandsOfBinaryOperators(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{name: "dereference after a sum", source: "var x = 3\nvar p = &x\nvar out = 1 + *p", want: "4"},
+		{name: "dereference after a product", source: "var x = 3\nvar q = &x\nvar out = 2 * *q", want: "6"},
+		{name: "reference after a prefix operator", source: "var x = 3\nvar out = 1 + *&x", want: "4"},
+		{name: "negation after a comparison", source: "var out = true == not 1", want: "true"},
+	})
+}
+
+// syntaxErrors parses source and returns the details of every syntax error.
+func syntaxErrors(source string) []string {
+	parser := NewLexerParser(NewLexer("<test>", source))
+	parser.Parse()
+	var details []string
+	for _, syntaxError := range parser.Errors {
+		details = append(details, syntaxError.Details)
+	}
+	return details
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string // details of every reported error
+	}{
+		{
+			name:   "second condition of a comprehension clause",
+			source: "var ys = [x for x in xs if x > 1 if x < 5]\nvar z = 1",
+			want:   []string{"Expected 'for' or ']'"},
+		},
+		{
+			name:   "comprehension clause without a condition",
+			source: "var ys = [x for x in xs y]",
+			want:   []string{"Expected 'for', 'if' or ']'"},
+		},
+		{
+			name:   "unmatched closing brace at the top level",
+			source: "var a = 1\n}\nvar b = 2",
+			want:   []string{"Unexpected '}' without a matching '{'"},
+		},
+		{
+			name:   "unmatched closing bracket after a statement",
+			source: "f())\nvar b = 2",
+			want:   []string{"Unexpected ')' without a matching '('"},
+		},
+		{
+			name:   "unmatched closing bracket in a block",
+			source: "func f() {\n\t]\n\treturn 1\n}",
+			want:   []string{"Unexpected ']' without a matching '['"},
+		},
+		{
+			name:   "every statement with an error is reported",
+			source: "var a = , 2\nvar b = 3\nvar c = 4 + / 5\nvar d = 6",
+			want: []string{
+				"Expected '&', '(', '*', '+', '-', '[', 'const', 'for', 'func', 'if', 'import', 'not', 'spawn', 'var', 'while', decimal, float, identifier, int or string",
+				"Expected '&', '(', '*', '+', '-', '[', 'for', 'func', 'if', 'import', 'not', 'spawn', 'while', decimal, float, identifier, int or string",
+			},
+		},
+		{
+			name:   "an error inside of brackets skips to the closing bracket",
+			source: "var xs = [\n\t1 + / 2,\n\t3\n]\nvar ok = 1",
+			want:   []string{"Expected '&', '(', '*', '+', '-', '[', 'for', 'func', 'if', 'import', 'not', 'spawn', 'while', decimal, float, identifier, int or string"},
+		},
+		{
+			name:   "unclosed bracket at the end of the file",
+			source: "var t = (1, 2",
+			want:   []string{"'(' was never closed"},
+		},
+		{
+			name:   "unclosed parameter list at the end of the file",
+			source: "func f(a,\n",
+			want:   []string{"'(' was never closed"},
+		},
+		{
+			name:   "an import after from is not a new statement",
+			source: "var a = ) from \"pk\" import X\nvar b = 2",
+			want:   []string{"Expected '&', '(', '*', '+', '-', '[', 'const', 'for', 'func', 'if', 'import', 'not', 'spawn', 'var', 'while', decimal, float, identifier, int or string"},
+		},
+		{
+			name:   "two statements on one line",
+			source: "var a = 1 var b = 2",
+			want:   []string{"Expected new line or ';'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := syntaxErrors(test.source)
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("errors are\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestMultiLineExpressions(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "arguments on several lines with a trailing comma",
+			source: "func add(\n\ta,\n\tb,\n) => a + b\nvar out = add(\n\t1,\n\t2,\n)",
+			want:   "3",
+		},
+		{
+			name:   "array literal on several lines",
+			source: "var out = [\n\t1,\n\t2\n\t, 3,\n]",
+			want:   "[1, 2, 3]",
+		},
+		{
+			name:   "trailing operators continue the line",
+			source: "var out = 1 +\n\t2 *\n\t3 ==\n\t7",
+			want:   "true",
+		},
+		{
+			name:   "newlines inside of parentheses are ignored",
+			source: "var out = (1\n\t+ 2\n\t+ 3)",
+			want:   "6",
+		},
+		{
+			name:   "blocks inside of brackets separate their statements",
+			source: "func apply(f, x) => f(x)\nvar out = apply(func(x) {\n\tvar y = x * 10\n\treturn y + 1\n}, 2)",
+			want:   "21",
+		},
+		{
+			name:   "semicolons separate statements",
+			source: "var a = 1; var b = 2; var out = a + b",
+			want:   "3",
+		},
+		{
+			name:   "tuples with a trailing comma",
+			source: "var out = (1, 2,)",
+			want:   "[1, 2]",
+		},
+	})
+}
+
+func TestIncompleteStatements(t *testing.T) {
+	const expression = "'&', '(', '*', '+', '-', '[', 'for', 'func', 'if', 'import', 'not', 'spawn', 'while', decimal, float, identifier, int or string"
+	const statement = "'&', '(', '*', '+', '-', '[', 'const', 'for', 'func', 'if', 'import', 'not', 'spawn', 'var', 'while', decimal, float, identifier, int or string"
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"var a = 1 +", "Incomplete statement, expected " + expression + " before the end of the file"},
+		{"var a =", "Incomplete statement, expected " + statement + " before the end of the file"},
+		{"f(1,,2)", "Expected ')', " + statement},
+		{"var a = [1, 2,,]", "Expected ']', " + statement},
+	}
+	for _, test := range tests {
+		if got := strings.Join(syntaxErrors(test.source), "\n"); got != test.want {
+			t.Errorf("%q: errors are\n%s\nwant\n%s", test.source, got, test.want)
+		}
+	}
+}
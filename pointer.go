@@ -3,11 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"sync"
 )
 
 type Memory struct {
 	data map[string]*Value
 	next int
+	mu   sync.Mutex
 }
 
 func NewMemory() *Memory {
@@ -18,6 +20,8 @@ func NewMemory() *Memory {
 }
 
 func (m *Memory) Allocate(value *Value) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	addr := fmt.Sprintf("0x%08X", m.next)
 	m.data[addr] = value
 	m.next++
@@ -25,17 +29,21 @@ func (m *Memory) Allocate(value *Value) string {
 }
 
 func (m *Memory) Get(addr string) (*Value, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	val, ok := m.data[addr]
 	return val, ok
 }
 
 func (m *Memory) Set(addr string, value *Value) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.data[addr] = value
 }
 
 func addressOf(value *Value, memory *Memory) *Value {
 	pointer := &Pointer{Addr: memory.Allocate(value)}
-	return &Value{Pointer: pointer}
+	return &Value{Kind: KIND_POINTER, ref: pointer}
 }
 
 func dereference(ptr *Pointer, memory *Memory) *Value {
@@ -0,0 +1,298 @@
+package main
+
+import "fmt"
+
+// NewResolver creates a Resolver for code that runs in the given symbol table.
+func NewResolver(symbols *SymbolTable) *Resolver {
+	return &Resolver{globals: map[string]*resolverGlobal{}, packages: map[string]bool{}, symbols: symbols}
+}
+
+func newResolverScope(names []string, enclosing bool) *resolverScope {
+	scope := &resolverScope{slots: map[string]int{}, consts: map[string]bool{}, enclosing: enclosing}
+	for _, name := range names {
+		scope.add(name)
+	}
+	return scope
+}
+
+// add gives name a new slot, a parameter listed twice refers to the last one like it did when arguments were set by name.
+func (s *resolverScope) add(name string) int {
+	s.names = append(s.names, name)
+	s.slots[name] = len(s.names) - 1
+	return len(s.names) - 1
+}
+
+// Resolve binds the variables of a program and returns the name errors found in it.
+func (r *Resolver) Resolve(node Node) []*NameError {
+	r.resolve(node)
+
+	// functions may use globals that are declared after them, so unknown names are only reported at the end
+	if !r.dynamic {
+		for _, tok := range r.unresolved {
+			if !r.known(tok.Value.(string)) {
+				r.report(tok.PosStart, tok.PosEnd, fmt.Sprintf("Unresolved reference '%s'", tok.Value))
+			}
+		}
+	}
+	return r.Errors
+}
+
+// ResolveIn resolves code that runs in the call of frame, e.g. with eval. The locals of frame and its enclosing calls
+// stay visible, new variables are declared in the symbol table.
+func (r *Resolver) ResolveIn(node Node, frame *Frame) []*NameError {
+	for ; frame != nil; frame = frame.Parent {
+		r.scopes = append([]*resolverScope{newResolverScope(frame.Names, true)}, r.scopes...)
+	}
+	return r.Resolve(node)
+}
+
+// isBuildIn reports whether a global name always refers to the build-in value of that name, it does not if the
+// program declares the name or may declare names at runtime.
+func (r *Resolver) isBuildIn(name string) bool {
+	if _, declared := r.globals[name]; declared || r.dynamic {
+		return false
+	}
+	GlobalSymbolTable.mu.RLock()
+	buildIn, exists := GlobalSymbolTable.buildIn[name]
+	GlobalSymbolTable.mu.RUnlock()
+	if value, found, _ := r.symbols.Get(name); found && value != buildIn {
+		return false
+	}
+	return exists
+}
+
+func (r *Resolver) report(posStart *Position, posEnd *Position, details string) {
+	r.Errors = append(r.Errors, NewNameError(posStart, posEnd, details))
+}
+
+// known reports whether a name that is not a local variable exists when the program runs.
+func (r *Resolver) known(name string) bool {
+	if _, exists := r.globals[name]; exists || r.packages[name] {
+		return true
+	}
+	if r.symbols.Contains(name) {
+		return true
+	}
+	GlobalSymbolTable.mu.RLock()
+	defer GlobalSymbolTable.mu.RUnlock()
+	_, exists := GlobalSymbolTable.buildIn[name]
+	return exists
+}
+
+// lookup returns the binding of a local variable and the scope it is declared in, or nil for globals.
+func (r *Resolver) lookup(name string) (*Binding, *resolverScope) {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if slot, exists := r.scopes[idx].slots[name]; exists {
+			return &Binding{Depth: len(r.scopes) - 1 - idx, Slot: slot}, r.scopes[idx]
+		}
+	}
+	return nil, nil
+}
+
+// reference resolves a read of a variable, unknown names are remembered to be reported later.
+func (r *Resolver) reference(tok *Token) *Binding {
+	name := tok.Value.(string)
+	if binding, _ := r.lookup(name); binding != nil {
+		return binding
+	}
+	// evaluated code can declare any name in the scope of the caller
+	if name == "eval" || name == "exec" {
+		r.dynamic = true
+	}
+	r.unresolved = append(r.unresolved, tok)
+	return nil
+}
+
+// declare declares a variable in the innermost function and returns its slot, variables of the top level have none.
+func (r *Resolver) declare(name string, isConst bool) *Binding {
+	if len(r.scopes) > 0 {
+		scope := r.scopes[len(r.scopes)-1]
+		slot, exists := scope.slots[name]
+		if exists || !scope.enclosing {
+			if !exists {
+				slot = scope.add(name)
+			}
+			if isConst {
+				scope.consts[name] = true
+			}
+			return &Binding{Slot: slot}
+		}
+	}
+
+	global, exists := r.globals[name]
+	if !exists {
+		global = &resolverGlobal{}
+		r.globals[name] = global
+	}
+	global.isConst = global.isConst || isConst
+	return nil
+}
+
+func (r *Resolver) resolve(node Node) {
+	switch n := node.(type) {
+	case *ArrayNode:
+		for _, element := range n.ElementNodes {
+			r.resolve(element)
+		}
+	case *VarAccessNode:
+		n.Binding = r.reference(n.VarNameTok)
+	case *VarAssignNode:
+		r.resolveVarAssign(n)
+	case *BinOpNode:
+		r.resolve(n.LeftNode)
+		r.resolve(n.RightNode)
+	case *UnaryOpNode:
+		r.resolve(n.Node)
+	case *IndexNode:
+		r.resolve(n.Target)
+		r.resolve(n.Index)
+	case *IfNode:
+		r.conditional++
+		for _, ifCase := range n.Cases {
+			r.resolve(ifCase.Condition)
+			r.resolve(ifCase.Expr)
+		}
+		if n.ElseCase != nil {
+			r.resolve(n.ElseCase.Expr)
+		}
+		r.conditional--
+	case *ForNode:
+		r.resolve(n.StartValueNode)
+		r.resolve(n.EndValueNode)
+		if n.StepValueNode != nil {
+			r.resolve(n.StepValueNode)
+		}
+		n.VarBinding = r.declare(n.VarNameTok.Value.(string), false)
+		r.conditional++
+		r.resolve(n.BodyNode)
+		r.conditional--
+	case *WhileNode:
+		r.conditional++
+		r.resolve(n.ConditionNode)
+		r.resolve(n.BodyNode)
+		r.conditional--
+	case *FuncDefNode:
+		r.resolveFuncDef(n)
+	case *CallNode:
+		r.resolve(n.NodeToCall)
+		for _, arg := range n.ArgNodes {
+			r.resolve(arg)
+		}
+	case *MethodCallNode:
+		r.resolve(n.TargetNode)
+		// the method may also be a build-in or a member of a package, unknown names are reported when the program runs
+		if n.IsCall {
+			n.MethodBinding, _ = r.lookup(n.MethodTok.Value.(string))
+		}
+		for _, arg := range n.ArgNodes {
+			r.resolve(arg)
+		}
+	case *ReturnNode:
+		if n.NodeToReturn != nil {
+			r.resolve(n.NodeToReturn)
+		}
+		// a returned call is the last thing the function does, it runs in place of the function instead of nesting in it
+		if call, ok := n.NodeToReturn.(*CallNode); ok && !call.Optional && len(r.scopes) > 0 && !r.scopes[len(r.scopes)-1].enclosing {
+			n.TailCall = true
+		}
+	case *ImportNode:
+		r.resolveImport(n)
+	case *ComprehensionNode:
+		// the loop variables are local to the comprehension, it runs in a frame of its own
+		scope := newResolverScope(nil, false)
+		r.scopes = append(r.scopes, scope)
+		r.conditional++
+		for _, clause := range n.Clauses {
+			r.resolve(clause.IterableNode)
+			clause.VarBinding = r.declare(clause.VarNameTok.Value.(string), false)
+			if clause.ConditionNode != nil {
+				r.resolve(clause.ConditionNode)
+			}
+		}
+		r.resolve(n.ElementNode)
+		r.conditional--
+		r.scopes = r.scopes[:len(r.scopes)-1]
+		n.Locals = scope.names
+	case *SpawnNode:
+		r.resolve(n.CallNode)
+	case *DeferNode:
+		r.resolve(n.Expr)
+	case *ReferenceNode:
+		r.resolve(n.Target)
+	case *DereferenceNode:
+		r.resolve(n.Target)
+	}
+}
+
+func (r *Resolver) resolveVarAssign(node *VarAssignNode) {
+	name := node.VarNameTok.Value.(string)
+	if node.ValueNode != nil {
+		r.resolve(node.ValueNode)
+	}
+
+	if node.declaration {
+		// only unconditional declarations of the top level are certain to be redeclared, inside of functions it is allowed
+		always := len(r.scopes) == 0 && r.conditional == 0
+		if global, exists := r.globals[name]; exists && always && global.always {
+			r.report(node.PosStart(), node.PosEnd(), fmt.Sprintf("Variable '%s' redeclared in scope", name))
+		}
+		node.Binding = r.declare(name, node.isConst)
+		if global, exists := r.globals[name]; exists && always {
+			global.always = true
+		}
+		return
+	}
+
+	if binding, scope := r.lookup(name); binding != nil {
+		if scope.consts[name] {
+			r.report(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot reassign constant '%v'", name))
+		}
+		node.Binding = binding
+		return
+	}
+	if global, exists := r.globals[name]; exists && global.isConst {
+		r.report(node.PosStart(), node.PosEnd(), fmt.Sprintf("Cannot reassign constant '%v'", name))
+	}
+	r.unresolved = append(r.unresolved, node.VarNameTok)
+}
+
+func (r *Resolver) resolveFuncDef(node *FuncDefNode) {
+	for _, decorator := range node.Decorators {
+		r.resolve(decorator)
+	}
+	// the name is declared before the body so that the function can call itself
+	if node.VarNameTok != nil {
+		node.Binding = r.declare(node.VarNameTok.Value.(string), false)
+	}
+
+	scope := newResolverScope(nil, false)
+	for _, argName := range node.ArgNameToks {
+		scope.add(argName.Value.(string))
+	}
+	r.scopes = append(r.scopes, scope)
+	r.resolve(node.BodyNode)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	node.Locals = scope.names
+}
+
+// resolveImport declares the imported names, they are stored by name in the symbol table the import runs in.
+func (r *Resolver) resolveImport(node *ImportNode) {
+	if node.ImportNames != nil {
+		for _, importName := range node.ImportNames {
+			name := importName.Value.(string)
+			if _, exists := r.globals[name]; !exists {
+				r.globals[name] = &resolverGlobal{}
+			}
+		}
+		return
+	}
+
+	for _, pkg := range node.PackageNames {
+		if _, native := nativePackages[pkg.Value.(string)]; native {
+			r.packages[pkg.Value.(string)] = true
+		} else {
+			// a package of the program exports all of its variables
+			r.dynamic = true
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+// resolve parses source and returns its statements after resolving them.
+func resolve(t *testing.T, source string) ([]Node, []*NameError) {
+	t.Helper()
+	ast := NewLexerParser(NewLexer("<test>", source)).Parse()
+	if ast.Error != nil {
+		t.Fatalf("unexpected syntax error: %s", ast.Error.Details)
+	}
+	nameErrors := NewResolver(buildInSymbolTable()).Resolve(ast.Node)
+	return ast.Node.(*ArrayNode).ElementNodes, nameErrors
+}
+
+func TestResolverBindings(t *testing.T) {
+	statements, nameErrors := resolve(t, "var g = 1\nfunc f(a, b) => func() => a + b + g")
+	if len(nameErrors) > 0 {
+		t.Fatalf("unexpected name error: %s", nameErrors[0].Details)
+	}
+
+	if binding := statements[0].(*VarAssignNode).Binding; binding != nil {
+		t.Errorf("global g has binding %v, want none", *binding)
+	}
+	outer := statements[1].(*FuncDefNode)
+	if len(outer.Locals) != 2 || outer.Locals[0] != "a" || outer.Locals[1] != "b" {
+		t.Errorf("locals of f = %v, want [a b]", outer.Locals)
+	}
+	sum := outer.BodyNode.(*FuncDefNode).BodyNode.(*BinOpNode)
+	a := sum.LeftNode.(*BinOpNode).LeftNode.(*VarAccessNode)
+	b := sum.LeftNode.(*BinOpNode).RightNode.(*VarAccessNode)
+	g := sum.RightNode.(*VarAccessNode)
+	if a.Binding == nil || *a.Binding != (Binding{Depth: 1, Slot: 0}) {
+		t.Errorf("a is bound to %v, want depth 1 slot 0", a.Binding)
+	}
+	if b.Binding == nil || *b.Binding != (Binding{Depth: 1, Slot: 1}) {
+		t.Errorf("b is bound to %v, want depth 1 slot 1", b.Binding)
+	}
+	if g.Binding != nil {
+		t.Errorf("g is bound to %v, want the symbol table", *g.Binding)
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"unknown names are reported", "var a = b", "Unresolved reference 'b'"},
+		{"unknown names in functions are reported", "func f() => missing", "Unresolved reference 'missing'"},
+		{"functions may use globals declared after them", "func f() => later\nvar later = 1", ""},
+		{"globals may not be declared twice", "var a = 1\nvar a = 2", "Variable 'a' redeclared in scope"},
+		{"conditional declarations may repeat", "var a = 1\nif a == 1 {\n\tvar a = 2\n}", ""},
+		{"local constants can not be reassigned", "func f() {\n\tconst c = 1\n\tc = 2\n}", "Cannot reassign constant 'c'"},
+		{"eval may declare any name", "exec(\"var x = 1\")\nvar y = x", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, nameErrors := resolve(t, test.source)
+			got := ""
+			if len(nameErrors) > 0 {
+				got = nameErrors[0].Details
+			}
+			if got != test.want {
+				t.Errorf("error = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
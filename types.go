@@ -1,5 +1,10 @@
 package main
 
+import (
+	"math/big"
+	"sync"
+)
+
 type Binary int
 
 // UnaryOpNode represents a unary operation node.
@@ -37,11 +42,13 @@ type VarAccessNode struct {
 	VarNameTok    *Token
 	PositionStart *Position
 	PositionEnd   *Position
+	Binding       *Binding // slot of a local variable, nil for names looked up in the symbol table
 }
 
 type IndexNode struct {
-	VarAccessNode *VarAccessNode
-	IndexNode     *NumberNode
+	Target        Node
+	Index         Node
+	Optional      bool
 	PositionStart *Position
 	PositionEnd   *Position
 }
@@ -52,16 +59,84 @@ type VarAssignNode struct {
 	ValueNode     Node
 	isConst       bool
 	declaration   bool
+	Type          *TypeAnnotation
 	PositionStart *Position
 	PositionEnd   *Position
+	Binding       *Binding // slot of a local variable, nil for names stored in the symbol table
+}
+
+// Binding is the location of a local variable found by the Resolver.
+type Binding struct {
+	Depth int // number of enclosing functions to go out of, 0 for the running function
+	Slot  int
+}
+
+// Frame holds the local variables of a function call.
+type Frame struct {
+	Slots  []*Value
+	Names  []string // variable name of each slot
+	Parent *Frame   // frame of the lexically enclosing function call, nil for functions declared at the top level
+}
+
+// Resolver binds the variables of an AST to the slots of their function before it runs and reports unknown names.
+type Resolver struct {
+	Errors      []*NameError
+	scopes      []*resolverScope           // scopes of the enclosing functions, empty at the top level
+	globals     map[string]*resolverGlobal // names declared at the top level
+	packages    map[string]bool            // names of the imported native packages
+	symbols     *SymbolTable               // the table the program runs in
+	unresolved  []*Token                   // references to unknown names, reported unless names can be declared at runtime
+	dynamic     bool                       // the program may declare names at runtime, e.g. with exec or a package import
+	conditional int                        // number of enclosing branches and loops
+}
+
+type resolverScope struct {
+	names     []string
+	slots     map[string]int
+	consts    map[string]bool
+	enclosing bool // scope of a running call that eval code is resolved into, new variables go to the symbol table
+}
+
+// Module is a package imported from a .ecp file. It is evaluated once per run, every importer shares its exports.
+type Module struct {
+	Context *Context      // context the package is evaluated in, its symbol table holds the exports
+	Error   *RuntimeError // error of the evaluation
+	done    chan struct{} // closed when the evaluation has finished
+}
+
+// astEncoder serializes an AST for the module cache.
+type astEncoder struct {
+	data []byte
+	err  error // first node or value that can not be encoded
+}
+
+// astDecoder restores an AST from the module cache, its positions are set to the given file.
+type astDecoder struct {
+	data   []byte
+	offset int
+	source *Source
+	err    error
+}
+
+// Optimizer simplifies a resolved AST before it runs.
+type Optimizer struct {
+	resolver *Resolver // knows which build-in names the program shadows
+}
+
+type resolverGlobal struct {
+	isConst bool
+	always  bool // declared by a top-level statement that runs unconditionally
 }
 
 // Lexer represents a lexer for tokenizing the code.
 type Lexer struct {
-	Fn          string
+	Src         *Source
 	Text        string
-	Pos         *Position
-	CurrentChar byte
+	Pos         Position
+	CurrentChar rune
+	charWidth   int          // number of bytes of CurrentChar in Text
+	lastType    TokenTypes   // type of the last token produced, TT_EOF once the source is exhausted
+	trace       func(*Token) // called with each token produced, before it is parsed
 }
 
 // Token represents a token in the code.
@@ -79,21 +154,41 @@ type IllegalCharError struct {
 
 // Position represents a position in the code.
 type Position struct {
-	Idx  int
-	Ln   int
-	Col  int
+	Idx int
+	Ln  int
+	Col int
+	Src *Source // script the position is in, shared by all of its positions
+}
+
+// Source is a script and the name of its file.
+type Source struct {
 	Fn   string
-	Ftxt string
+	Text string
 }
 
 // TokenTypes represents the different types of tokens.
 type TokenTypes string
 
-type TokenTypeInfo struct {
-	Type  TokenTypes
-	Value *string
+// precedence orders how tightly operators bind, operators with a higher precedence bind first.
+type precedence int
+
+// prefixRule parses an expression that starts with a token, e.g. a literal, a keyword expression or a prefix operator.
+type prefixRule struct {
+	prec  precedence // the rule only applies where an operand of this or a lower precedence is expected
+	parse func(p *Parser, res *ParseResult) Node
+}
+
+// infixRule parses an operator between two operands.
+type infixRule struct {
+	prec  precedence // precedence of the operator
+	right precedence // precedence of the right operand, prec+1 for left and prec for right associative operators
+	parse func(p *Parser, res *ParseResult, left Node, rule infixRule) Node
 }
 
+// postfixRule parses a call, member access or index that follows an operand. Postfix forms bind tighter than any
+// operator, optional is set once a link of the chain is optional.
+type postfixRule func(p *Parser, res *ParseResult, left Node, optional *bool) Node
+
 // Node represents a generic node.
 type Node interface {
 	String() string
@@ -102,16 +197,22 @@ type Node interface {
 }
 
 type Parser struct {
-	Tokens  []*Token
-	TokIdx  int
-	Current *Token
+	Tokens       []*Token // tokens read so far
+	TokIdx       int
+	lexer        *Lexer   // produces the tokens after Tokens, nil if all tokens were given
+	LexError     *Error   // error of the lexer, it ends the tokens
+	Errors       []*Error // syntax errors in the order they were found, parsing continues after each statement with an error
+	Current      *Token
+	loopLabels   []string // labels of the enclosing loops, empty for loops without a label
+	pendingLabel *Token   // label parsed in front of the next loop
+	brackets     int      // open '(' and '[' around the current expression, newlines inside of them are insignificant
+	blocks       int      // nesting of the statements that are parsed, 1 at the top level
 }
 
 type ParseResult struct {
-	AdvanceCount   int
-	ToReverseCount int
-	Error          *Error
-	Node           Node
+	AdvanceCount int
+	Error        *Error
+	Node         Node
 }
 
 type Error struct {
@@ -123,11 +224,53 @@ type Error struct {
 
 type Interpreter struct{}
 
+// Opcode identifies an instruction of the bytecode virtual machine.
+type Opcode byte
+
+// Instruction is a single bytecode instruction, Node is the AST node it was compiled from and supplies its data and error positions.
+type Instruction struct {
+	Op      Opcode
+	Operand int // jump target or number of values taken from the stack
+	Node    Node
+}
+
+// Chunk is the bytecode of a program or a function body.
+type Chunk struct {
+	Code []Instruction
+}
+
+// Compiler translates an AST into a Chunk.
+type Compiler struct {
+	chunk *Chunk
+}
+
+// VM is a stack based virtual machine that runs a Chunk.
+type VM struct {
+	interpreter Interpreter // evaluates the nodes that are not compiled to bytecode
+	stack       []*Value
+	loops       []*loopFrame
+}
+
+// loopFrame is the state of a running for or while loop.
+type loopFrame struct {
+	label      string
+	elements   []*Value
+	stackDepth int // height of the stack when the loop started, break and continue reset it
+	breakTo    int
+	continueTo int
+	iVal       int
+	end        int
+	step       int
+}
+
 type Context struct {
 	DisplayName    string
 	Parent         *Context
 	ParentEntryPos *Position
 	SymbolTable    *SymbolTable
+	Deferred       *[]DeferredCall // calls to run when the function exits, nil outside of functions
+	Frame          *Frame          // local variables of the running function, nil at the top level
+	Depth          int             // number of enclosing contexts, limits the depth of calls
 }
 
 type RuntimeError struct {
@@ -140,6 +283,16 @@ type InvalidSyntaxError struct {
 	Error
 }
 
+// TypeError represents a type annotation mismatch found before the program runs.
+type TypeError struct {
+	Error
+}
+
+// NameError represents an unknown or wrongly declared variable found before the program runs.
+type NameError struct {
+	Error
+}
+
 // ExpectedCharError represents an error for an expected character.
 type ExpectedCharError struct {
 	Error
@@ -152,6 +305,21 @@ type RTResult struct {
 	FuncReturnValue    *Value
 	LoopShouldContinue bool
 	LoopShouldBreak    bool
+	LoopLabel          string    // label of the loop targeted by break or continue, empty for the innermost loop
+	TailCall           *TailCall // call that the returning function is replaced with
+}
+
+// TailCall is a call in tail position, it runs after the function that made it has returned.
+type TailCall struct {
+	Callee *Value
+	Args   []*Value
+}
+
+// DeferredCall is a call whose function and arguments were evaluated by a defer statement, it runs when the
+// function exits.
+type DeferredCall struct {
+	Callee *Value
+	Args   []*Value
 }
 
 // SymbolTable represents a symbol table in the interpreter.
@@ -161,6 +329,7 @@ type SymbolTable struct {
 	buildIn   map[string]*Value
 	packages  map[string]*Package
 	parent    *SymbolTable
+	mu        *sync.RWMutex // shared with every table that shares the symbols map
 }
 
 type IfCaseNode struct {
@@ -182,58 +351,79 @@ type IfNode struct {
 }
 
 type WhileNode struct {
+	LabelTok      *Token
 	ConditionNode Node
 	BodyNode      Node
 	PositionStart *Position
 	PositionEnd   *Position
-	Flag          bool
+	Flag          bool // the loop is a statement, it evaluates to nothing and does not collect the values of its body
 }
 
 type ForNode struct {
+	LabelTok       *Token
 	VarNameTok     *Token
+	VarBinding     *Binding
 	StartValueNode Node
 	EndValueNode   Node
 	StepValueNode  Node
 	BodyNode       Node
 	PositionStart  *Position
 	PositionEnd    *Position
-	Flag           bool
+	Flag           bool // the loop is a statement, it evaluates to nothing and does not collect the values of its body
 }
 
 type FuncDefNode struct {
 	VarNameTok    *Token
 	ArgNameToks   []*Token
+	ArgTypes      []*TypeAnnotation
+	ReturnType    *TypeAnnotation
 	BodyNode      Node
 	PositionStart *Position
 	PositionEnd   *Position
 	Flag          bool
+	Decorators    []Node   // expressions written as '@decorator' before the declaration, outermost first
+	Binding       *Binding // slot of a named function declared inside of a function
+	Locals        []string // names of the parameters and local variables by slot, set by the Resolver
+	Compiled      *CompiledBody
+}
+
+// CompiledBody is the bytecode of a function body. It is compiled on the first call and shared by the functions
+// declared by one FuncDefNode, so it lives as long as the node and its functions.
+type CompiledBody struct {
+	once  sync.Once
+	chunk *Chunk
 }
 
 type CallNode struct {
 	NodeToCall    Node
 	ArgNodes      []Node
+	Optional      bool
 	PositionStart *Position
 	PositionEnd   *Position
 }
 
 type ArrayNode struct {
 	ElementNodes  []Node
+	Frozen        bool
 	PositionStart *Position
 	PositionEnd   *Position
 }
 
 type ReturnNode struct {
 	NodeToReturn  Node
+	TailCall      bool // NodeToReturn is a call that replaces the call of the function, set by the resolver
 	PositionStart *Position
 	PositionEnd   *Position
 }
 
 type BreakNode struct {
+	LabelTok      *Token
 	PositionStart *Position
 	PositionEnd   *Position
 }
 
 type ContinueNode struct {
+	LabelTok      *Token
 	PositionStart *Position
 	PositionEnd   *Position
 }
@@ -244,6 +434,34 @@ type ImportNode struct {
 	PositionStart, PositionEnd *Position
 }
 
+type ComprehensionNode struct {
+	ElementNode   Node
+	Clauses       []*ComprehensionClause
+	PositionStart *Position
+	PositionEnd   *Position
+	Locals        []string // names of the loop variables by slot, set by the Resolver
+}
+
+// ComprehensionClause is one 'for x in iterable if condition' part of a comprehension, ConditionNode may be nil.
+type ComprehensionClause struct {
+	VarNameTok    *Token
+	IterableNode  Node
+	ConditionNode Node
+	VarBinding    *Binding
+}
+
+type DeferNode struct {
+	Expr          Node
+	PositionStart *Position
+	PositionEnd   *Position
+}
+
+type SpawnNode struct {
+	CallNode      *CallNode
+	PositionStart *Position
+	PositionEnd   *Position
+}
+
 type ReferenceNode struct {
 	Target        Node
 	PositionStart *Position
@@ -262,6 +480,9 @@ type Function struct {
 	ArgNames []string
 	Base     *BaseFunction
 	Flag     bool
+	Locals   []string // slot layout of the frame of a call
+	Closure  *Frame   // frame the function was declared in
+	Compiled *CompiledBody
 }
 
 type BuildInFunction struct {
@@ -278,65 +499,111 @@ type StdLibFunction struct {
 type Method struct {
 	ArgsNames []string
 	Fn        func(ctx *Context) *RTResult
+	Optional  int // number of trailing arguments that may be left out, they are null then
 }
 
 type BaseFunction struct {
 	Name                       string
 	PositionStart, PositionEnd *Position
 	Context                    *Context
+	ArgTypes                   []*TypeAnnotation
+	ReturnType                 *TypeAnnotation
+	Definition                 *Position // where a function is defined in the source, calls move PositionStart to the call
+}
+
+// TypeAnnotation is a type written in the source, e.g. 'Number' or 'Array<String>'. Element is only set for arrays.
+type TypeAnnotation struct {
+	Name          string
+	Element       *TypeAnnotation
+	PositionStart *Position
+	PositionEnd   *Position
+}
+
+// TypeChecker walks the AST before it runs and collects values that do not fit their type annotations.
+type TypeChecker struct {
+	Errors      []*TypeError
+	scopes      []*checkerScope
+	returnTypes []*TypeAnnotation
+}
+
+type checkerScope struct {
+	vars  map[string]*TypeAnnotation
+	funcs map[string]*FuncDefNode
 }
 
-// Number represents a numeric value.
+// Number represents a numeric value. Ints and floats are stored unboxed, big integers and decimals in Exact.
 type Number struct {
-	ValueField                 interface{}
-	PositionStart, PositionEnd *Position
-	Context                    *Context
+	Int   int
+	Float float64
+	Exact interface{}
+	form  numberForm
+}
+
+// numberForm tells which field of a Number holds its value.
+type numberForm byte
+
+// Decimal is an exact decimal number, its value is Unscaled * 10^-Scale.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+// RoundingMode decides how a decimal is rounded when digits have to be dropped.
+type RoundingMode string
+
+// DecimalContext holds the number of decimal places of quotients and the rounding mode, it is shared by all tasks.
+type DecimalContext struct {
+	scale    int
+	rounding RoundingMode
+	mu       sync.RWMutex
 }
 
 // String represents a String value.
 type String struct {
-	ValueField                 string
-	PositionStart, PositionEnd *Position
-	Context                    *Context
-	Type                       string
+	ValueField string
 }
 
 type Array struct {
-	Elements                   []*Value
-	PositionStart, PositionEnd *Position
-	Context                    *Context
+	Elements []*Value
+	Frozen   bool
+	refs     *int32       // number of arrays sharing Elements until one of them is changed
+	mu       sync.RWMutex // guards Elements and refs against tasks that change the array at the same time
 }
 
-type Null struct {
-	PositionStart, PositionEnd *Position
-	Context                    *Context
-}
+type Null struct{}
 
 type Boolean struct {
-	PositionStart, PositionEnd *Position
-	Context                    *Context
-	Binary                     Binary
+	Binary Binary
 }
 
+// ValueKind tells which kind of value a Value holds.
+type ValueKind byte
+
+// Value is a value of a running program. Numbers are stored unboxed in num, all other kinds of values in ref.
+// Plain data has no position, errors are located at the node that is evaluated.
 type Value struct {
-	Number          *Number
-	Function        *Function
-	BuildInFunction *BuildInFunction
-	StdLibFunction  *StdLibFunction
-	String          *String
-	Array           *Array
-	Null            *Null
-	Boolean         *Boolean
-	Pointer         *Pointer
-	Dereference     *Dereference
-	ByteArray       *ByteArray
-	VariadicArray   *VariadicArray
+	Kind ValueKind
+	num  Number
+	ref  interface{}
 }
 
 type Package struct {
 	Methods map[string]*Value
 }
 
+// MethodCallNode is a 'target.name' or 'target.name(args)' selector, which is a package lookup when target names an imported package
+// and a call of the function name with target as the first argument otherwise.
+type MethodCallNode struct {
+	TargetNode    Node
+	MethodTok     *Token
+	ArgNodes      []Node
+	IsCall        bool
+	Optional      bool
+	PositionStart *Position
+	PositionEnd   *Position
+	MethodBinding *Binding // slot of a local function called as a method
+}
+
 type PackageMethod struct {
 	PositionStart, PositionEnd *Position
 	PackageName                string
@@ -364,8 +631,34 @@ type ByteArray struct {
 }
 
 type VariadicArray struct {
-	Array         []*Value
-	PositionStart *Position
-	PositionEnd   *Position
-	Context       *Context
+	Array []*Value
+}
+
+// Task represents a function call running on its own goroutine.
+type Task struct {
+	Name                       string
+	state                      *taskState
+	PositionStart, PositionEnd *Position
+	Context                    *Context
+}
+
+// taskState is shared by every copy of a task.
+type taskState struct {
+	done   chan struct{}
+	result *Value
+	err    *RuntimeError
+}
+
+// Channel represents a channel used to communicate between tasks.
+type Channel struct {
+	state                      *channelState
+	PositionStart, PositionEnd *Position
+	Context                    *Context
+}
+
+// channelState is shared by every copy of a channel.
+type channelState struct {
+	ch     chan *Value
+	closed bool
+	mu     sync.Mutex
 }
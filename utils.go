@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math/big"
 	"os"
 	"reflect"
 	"strconv"
@@ -18,210 +19,298 @@ func ConvertBoolToInt(expr bool) Binary {
 	}
 }
 
-// SetContext sets the context
+const (
+	KIND_EMPTY ValueKind = iota
+	KIND_NUMBER
+	KIND_STRING
+	KIND_BOOLEAN
+	KIND_NULL
+	KIND_ARRAY
+	KIND_FUNCTION
+	KIND_BUILD_IN_FUNCTION
+	KIND_STD_LIB_FUNCTION
+	KIND_BYTE_ARRAY
+	KIND_VARIADIC_ARRAY
+	KIND_POINTER
+	KIND_DEREFERENCE
+	KIND_TASK
+	KIND_CHANNEL
+)
+
+// kindNames are the type names of the kinds of values as they are shown to the user.
+var kindNames = [...]string{
+	KIND_EMPTY:             "",
+	KIND_NUMBER:            "Number",
+	KIND_STRING:            "String",
+	KIND_BOOLEAN:           "Boolean",
+	KIND_NULL:              "Null",
+	KIND_ARRAY:             "Array",
+	KIND_FUNCTION:          "Function",
+	KIND_BUILD_IN_FUNCTION: "BuildInFunction",
+	KIND_STD_LIB_FUNCTION:  "StdLibFunction",
+	KIND_BYTE_ARRAY:        "ByteArray",
+	KIND_VARIADIC_ARRAY:    "VariadicArray",
+	KIND_POINTER:           "Pointer",
+	KIND_DEREFERENCE:       "Dereference",
+	KIND_TASK:              "Task",
+	KIND_CHANNEL:           "Channel",
+}
+
+// Number returns the number held by the value, or nil if the value is of another kind. The same goes for the
+// other accessors of the kinds of values.
+func (v *Value) Number() *Number {
+	if v.Kind != KIND_NUMBER {
+		return nil
+	}
+	return &v.num
+}
+
+func (v *Value) String() *String {
+	s, _ := v.ref.(*String)
+	return s
+}
+
+func (v *Value) Boolean() *Boolean {
+	b, _ := v.ref.(*Boolean)
+	return b
+}
+
+func (v *Value) Null() *Null {
+	n, _ := v.ref.(*Null)
+	return n
+}
+
+func (v *Value) Array() *Array {
+	a, _ := v.ref.(*Array)
+	return a
+}
+
+func (v *Value) Function() *Function {
+	f, _ := v.ref.(*Function)
+	return f
+}
+
+func (v *Value) BuildInFunction() *BuildInFunction {
+	b, _ := v.ref.(*BuildInFunction)
+	return b
+}
+
+func (v *Value) StdLibFunction() *StdLibFunction {
+	s, _ := v.ref.(*StdLibFunction)
+	return s
+}
+
+func (v *Value) ByteArray() *ByteArray {
+	b, _ := v.ref.(*ByteArray)
+	return b
+}
+
+func (v *Value) VariadicArray() *VariadicArray {
+	a, _ := v.ref.(*VariadicArray)
+	return a
+}
+
+func (v *Value) Pointer() *Pointer {
+	p, _ := v.ref.(*Pointer)
+	return p
+}
+
+func (v *Value) Dereference() *Dereference {
+	d, _ := v.ref.(*Dereference)
+	return d
+}
+
+func (v *Value) Task() *Task {
+	t, _ := v.ref.(*Task)
+	return t
+}
+
+func (v *Value) Channel() *Channel {
+	c, _ := v.ref.(*Channel)
+	return c
+}
+
+// base returns the function part of a callable value, or nil for other kinds of values.
+func (v *Value) base() *BaseFunction {
+	switch v.Kind {
+	case KIND_FUNCTION:
+		return v.Function().Base
+	case KIND_BUILD_IN_FUNCTION:
+		return v.BuildInFunction().Base
+	case KIND_STD_LIB_FUNCTION:
+		return v.StdLibFunction().Base
+	}
+	return nil
+}
+
+// SetContext sets the context of values that keep one, i.e. functions, byte arrays, tasks and channels.
 func (v *Value) SetContext(context *Context) *Value {
-	if v.Number != nil {
-		v.Number.Context = context
-	} else if v.String != nil {
-		v.String.Context = context
-	} else if v.Function != nil {
-		v.Function.Base.Context = context
-	} else if v.BuildInFunction != nil {
-		v.BuildInFunction.Base.Context = context
-	} else if v.Array != nil {
-		v.Array.Context = context
-	} else if v.Null != nil {
-		v.Null.Context = context
-	} else if v.Boolean != nil {
-		v.Boolean.Context = context
+	switch v.Kind {
+	case KIND_FUNCTION, KIND_BUILD_IN_FUNCTION, KIND_STD_LIB_FUNCTION:
+		v.base().Context = context
+	case KIND_BYTE_ARRAY:
+		v.ByteArray().Context = context
+	case KIND_TASK:
+		v.Task().Context = context
+	case KIND_CHANNEL:
+		v.Channel().Context = context
 	}
 	return v
 }
 
-// SetPos sets the position
+// SetPos sets the position of values that keep one, for functions it is the position of the call.
 func (v *Value) SetPos(posStart *Position, posEnd *Position) *Value {
-	if v.Number != nil {
-		v.Number.PositionStart = posStart
-		v.Number.PositionEnd = posEnd
-	} else if v.String != nil {
-		v.String.PositionStart = posStart
-		v.String.PositionEnd = posEnd
-	} else if v.Function != nil {
-		v.Function.Base.PositionStart = posStart
-		v.Function.Base.PositionEnd = posEnd
-	} else if v.Array != nil {
-		v.Array.PositionStart = posStart
-		v.Array.PositionEnd = posEnd
-	} else if v.BuildInFunction != nil {
-		v.BuildInFunction.Base.PositionStart = posStart
-		v.BuildInFunction.Base.PositionEnd = posEnd
-	} else if v.StdLibFunction != nil {
-		v.StdLibFunction.Base.PositionStart = posStart
-		v.StdLibFunction.Base.PositionEnd = posEnd
-	} else if v.Null != nil {
-		v.Null.PositionStart = posStart
-		v.Null.PositionEnd = posEnd
-	} else if v.Boolean != nil {
-		v.Boolean.PositionStart = posStart
-		v.Boolean.PositionEnd = posEnd
+	switch v.Kind {
+	case KIND_FUNCTION, KIND_BUILD_IN_FUNCTION, KIND_STD_LIB_FUNCTION:
+		base := v.base()
+		base.PositionStart, base.PositionEnd = posStart, posEnd
+	case KIND_BYTE_ARRAY:
+		v.ByteArray().PositionStart, v.ByteArray().PositionEnd = posStart, posEnd
+	case KIND_TASK:
+		v.Task().PositionStart, v.Task().PositionEnd = posStart, posEnd
+	case KIND_CHANNEL:
+		v.Channel().PositionStart, v.Channel().PositionEnd = posStart, posEnd
 	}
 	return v
 }
 
 // Value retrieves the Value of the type if available
 func (v *Value) Value() interface{} {
-	if v != nil {
-		if v.Number != nil {
-			return v.Number.Value()
-		} else if v.String != nil {
-			return v.String.Value()
-		} else if v.Function != nil {
-			return v.Function.String()
-		} else if v.Array != nil {
-			return v.Array.String()
-		} else if v.BuildInFunction != nil {
-			return v.BuildInFunction.String()
-		} else if v.Null != nil {
-			return v.Null.String()
-		} else if v.Boolean != nil {
-			return v.Boolean.String()
-		} else if v.StdLibFunction != nil {
-			return v.StdLibFunction.String()
-		} else if v.ByteArray != nil {
-			return v.ByteArray.ValueField
-		} else if v.VariadicArray != nil {
-			return v.VariadicArray.Array
-		} else if v.Pointer != nil {
-			return v.Pointer.Addr
-		} else if v.Dereference != nil {
-			return v.Dereference.Value.Value()
-		}
+	if v == nil {
+		return v
+	}
+	switch v.Kind {
+	case KIND_NUMBER:
+		return v.num.Value()
+	case KIND_STRING:
+		return v.String().Value()
+	case KIND_FUNCTION:
+		return v.Function().String()
+	case KIND_ARRAY:
+		return v.Array().String()
+	case KIND_BUILD_IN_FUNCTION:
+		return v.BuildInFunction().String()
+	case KIND_NULL:
+		return v.Null().String()
+	case KIND_BOOLEAN:
+		return v.Boolean().String()
+	case KIND_STD_LIB_FUNCTION:
+		return v.StdLibFunction().String()
+	case KIND_BYTE_ARRAY:
+		return v.ByteArray().ValueField
+	case KIND_VARIADIC_ARRAY:
+		return v.VariadicArray().Array
+	case KIND_POINTER:
+		return v.Pointer().Addr
+	case KIND_DEREFERENCE:
+		return v.Dereference().Value.Value()
+	case KIND_TASK:
+		return v.Task().String()
+	case KIND_CHANNEL:
+		return v.Channel().String()
 	}
 	return v
 }
 
+// Copy returns a copy of the value. Numbers, strings, booleans and null can not be changed and have no position, so
+// they are returned as they are. Functions are copied to be positioned at a call. Arrays are copied on write, byte
+// arrays are copied right away. Tasks, channels and pointers are references, their copies refer to the same task,
+// channel or memory address.
 func (v *Value) Copy() *Value {
-	if v.Number != nil {
-		return v.Number.Copy()
-	} else if v.String != nil {
-		return v.String.Copy()
-	} else if v.Function != nil {
-		return v.Function.Copy()
-	} else if v.StdLibFunction != nil {
-		v.StdLibFunction.Copy()
-	} else if v.Array != nil {
-		return v.Array.Copy()
-	} else if v.BuildInFunction != nil {
-		return v.BuildInFunction.Copy()
+	switch v.Kind {
+	case KIND_NUMBER, KIND_STRING, KIND_BOOLEAN, KIND_NULL:
+		return v
+	case KIND_FUNCTION:
+		return v.Function().Copy()
+	case KIND_STD_LIB_FUNCTION:
+		return v.StdLibFunction().Copy()
+	case KIND_ARRAY:
+		return v.Array().Copy()
+	case KIND_BUILD_IN_FUNCTION:
+		return v.BuildInFunction().Copy()
+	case KIND_TASK:
+		return v.Task().Copy()
+	case KIND_CHANNEL:
+		return v.Channel().Copy()
+	case KIND_BYTE_ARRAY:
+		return v.ByteArray().Copy()
 	}
-	return v
+	copied := *v
+	return &copied
 }
 
-// GetPosStart searches for the non-nil field in the Value struct and returns its StartPos if available.
-func (v *Value) GetPosStart() *Position {
-	if v.Number != nil {
-		return v.Number.PosStart()
-	} else if v.Function != nil {
-		return v.Function.PosStart()
-	} else if v.BuildInFunction != nil {
-		return v.BuildInFunction.Base.PosStart()
-	} else if v.String != nil {
-		return v.String.PosStart()
-	} else if v.Array != nil {
-		return v.Array.PosStart()
-	} else if v.Boolean != nil {
-		return v.Boolean.PosStart()
-	} else if v.Null != nil {
-		return v.Null.PosStart()
+// DeepCopy returns a copy of the value that shares no arrays with the original, not even lazily.
+func (v *Value) DeepCopy() *Value {
+	if v.Kind == KIND_ARRAY {
+		return v.Array().DeepCopy()
 	}
-	return nil
+	return v.Copy()
 }
 
-// GetPosEnd searches for the non-nil field in the Value struct and returns its EndPos if available.
-func (v *Value) GetPosEnd() *Position {
-	if v.Number != nil {
-		return v.Number.PosEnd()
-	} else if v.Function != nil {
-		return v.Function.PosEnd()
-	} else if v.BuildInFunction != nil {
-		return v.BuildInFunction.Base.PosEnd()
-	} else if v.String != nil {
-		return v.String.PosEnd()
-	} else if v.Array != nil {
-		return v.Array.PosEnd()
-	} else if v.Boolean != nil {
-		return v.Boolean.PosEnd()
-	} else if v.Null != nil {
-		return v.Null.PosEnd()
+// Share returns the value as it is stored in another variable, array or argument. Only arrays are mutable values,
+// they are copied on write so that changes do not show through the other places.
+func (v *Value) Share() *Value {
+	if v != nil && v.Kind == KIND_ARRAY {
+		return v.Array().Copy()
 	}
-	return nil
+	return v
 }
 
-// SetContext gets the context of the value
-func (v *Value) GetContext() *Context {
-	if v.Number != nil {
-		return v.Number.Context
-	} else if v.String != nil {
-		return v.String.Context
-	} else if v.Function != nil {
-		return v.Function.Base.Context
-	} else if v.BuildInFunction != nil {
-		return v.BuildInFunction.Base.Context
-	} else if v.Array != nil {
-		return v.Array.Context
-	} else if v.Null != nil {
-		return v.Null.Context
-	} else if v.Boolean != nil {
-		return v.Boolean.Context
+// Freeze returns a deeply frozen copy of the value. Frozen arrays and immutable values are returned as they are.
+func (v *Value) Freeze() *Value {
+	array := v.Array()
+	if array == nil || array.Frozen {
+		return v
 	}
-	return nil
+
+	elements := make([]*Value, len(array.Elements))
+	for idx, element := range array.Elements {
+		elements[idx] = element.Freeze()
+	}
+	frozen := NewArray(elements)
+	frozen.Array().Frozen = true
+	return frozen
+}
+
+// IsFrozen reports whether the value can not be changed, only arrays can be mutated.
+func (v *Value) IsFrozen() bool {
+	return v.Kind != KIND_ARRAY || v.Array().Frozen
 }
 
 func (v *Value) Type() string {
-	if v.Number != nil {
-		return "Number"
-	} else if v.Function != nil {
-		return "Function"
-	} else if v.BuildInFunction != nil {
-		return "BuildInFunction"
-	} else if v.String != nil {
-		return "String"
-	} else if v.Array != nil {
-		return "Array"
-	} else if v.Boolean != nil {
-		return "Boolean"
-	} else if v.Null != nil {
-		return "Null"
-	} else if v.ByteArray != nil {
-		return "ByteArray"
-	} else if v.Pointer != nil {
-		return "Pointer"
-	} else if v.Dereference != nil {
-		return "Dereference"
+	return kindNames[v.Kind]
+}
+
+// FunctionName returns the name of a callable value.
+func (v *Value) FunctionName() string {
+	if base := v.base(); base != nil {
+		return base.Name
 	}
-	return ""
+	return "<anonymous>"
 }
 
 func (v *Value) Length() *Value {
-	if v.ByteArray != nil {
-		return v.ByteArray.Length()
-	} else if v.Array != nil {
-		return v.Array.Length()
-	} else if v.String != nil {
-		return v.String.Length()
+	switch v.Kind {
+	case KIND_BYTE_ARRAY:
+		return v.ByteArray().Length()
+	case KIND_ARRAY:
+		return v.Array().Length()
+	case KIND_STRING:
+		return v.String().Length()
 	}
 	return nil
 }
 
+// IsEmpty reports whether the value is the result of a statement without a value, e.g. a declaration.
 func (v *Value) IsEmpty() bool {
-	if v != nil {
-		return v.Number == nil &&
-			v.String == nil &&
-			v.Boolean == nil &&
-			v.Array == nil &&
-			v.Null == nil && v.BuildInFunction == nil && v.Function == nil
+	if v == nil {
+		return false
+	}
+	switch v.Kind {
+	case KIND_NUMBER, KIND_STRING, KIND_BOOLEAN, KIND_ARRAY, KIND_NULL, KIND_BUILD_IN_FUNCTION, KIND_FUNCTION, KIND_TASK, KIND_CHANNEL:
+		return false
 	}
-	return false
+	return true
 }
 
 func interfaceToBytes(data interface{}) []byte {
@@ -236,6 +325,10 @@ func interfaceToBytes(data interface{}) []byte {
 		return []byte(strconv.Itoa(int(v)))
 	case float64:
 		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case *big.Int:
+		return []byte(v.String())
+	case *Decimal:
+		return []byte(v.String())
 	case []string:
 		return []byte(strings.Join(v, ""))
 	case []*Value:
@@ -274,6 +367,10 @@ func valueToBytes(value *Value) []byte {
 		return []byte(strconv.Itoa(int(v)))
 	case float64:
 		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case *big.Int:
+		return []byte(v.String())
+	case *Decimal:
+		return []byte(v.String())
 	case []*Value:
 		var result []byte
 		for _, innerValue := range v {
@@ -282,14 +379,16 @@ func valueToBytes(value *Value) []byte {
 			}
 		}
 		return result
+	case []byte:
+		return interfaceToBytes(v)
 	default:
 		return nil // Handle other types or nil values
 	}
 }
 
 func isString(data []byte) bool {
-	for _, b := range data {
-		if !isLetter(b) {
+	for _, char := range string(data) {
+		if !isLetter(char) {
 			return false
 		}
 	}
@@ -323,17 +422,6 @@ func isFloat(data []byte) bool {
 	}
 }
 
-func toInt(val interface{}) int {
-	switch v := val.(type) {
-	case int:
-		return v
-	case float64:
-		return int(v)
-	default:
-		panic("Unsupported type")
-	}
-}
-
 func LoadPackage(moduleName string) (string, error) {
 	filename := moduleName + ".ecp"
 	content, err := os.ReadFile(filename)
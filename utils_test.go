@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestValueKinds(t *testing.T) {
+	tests := []struct {
+		value    *Value
+		wantType string
+	}{
+		{NewInt(1), "Number"},
+		{NewString("a"), "String"},
+		{NewBoolean(1), "Boolean"},
+		{NewNull(), "Null"},
+		{NewArray([]*Value{NewInt(1)}), "Array"},
+	}
+	for _, test := range tests {
+		if got := test.value.Type(); got != test.wantType {
+			t.Errorf("Type() = %q, want %q", got, test.wantType)
+		}
+		// only the accessor of the kind of the value returns something
+		accessors := map[string]bool{
+			"Number":  test.value.Number() != nil,
+			"String":  test.value.String() != nil,
+			"Boolean": test.value.Boolean() != nil,
+			"Null":    test.value.Null() != nil,
+			"Array":   test.value.Array() != nil,
+		}
+		for kind, found := range accessors {
+			if found != (kind == test.wantType) {
+				t.Errorf("%s value: %s() returned %v", test.wantType, kind, found)
+			}
+		}
+	}
+}
+
+func TestNumberForms(t *testing.T) {
+	tests := []struct {
+		value  *Value
+		want   interface{}
+		isInt  bool
+		format string
+	}{
+		{NewNumber(3), 3, true, "3"},
+		{NewNumber(1.5), 1.5, false, "1.5"},
+		{NewInt(-7), -7, true, "-7"},
+		{NewFloat(2), 2.0, false, "2"},
+		{NewNumber(new(big.Int).Lsh(big.NewInt(1), 70)), nil, false, "1180591620717411303424"},
+	}
+	for _, test := range tests {
+		number := test.value.Number()
+		if number.IsInt() != test.isInt {
+			t.Errorf("%s: IsInt() = %v, want %v", test.format, number.IsInt(), test.isInt)
+		}
+		if test.want != nil && number.Value() != test.want {
+			t.Errorf("%s: Value() = %#v, want %#v", test.format, number.Value(), test.want)
+		}
+		if got := string(interfaceToBytes(test.value.Value())); got != test.format {
+			t.Errorf("formatted as %q, want %q", got, test.format)
+		}
+	}
+}
+
+func TestNumbersAreNotBoxed(t *testing.T) {
+	if allocs := testing.AllocsPerRun(100, func() { NewInt(42) }); allocs > 1 {
+		t.Errorf("NewInt allocates %v times, want 1", allocs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { NewFloat(4.2) }); allocs > 1 {
+		t.Errorf("NewFloat allocates %v times, want 1", allocs)
+	}
+}
+
+func TestSharedArraysAreCopiedOnWrite(t *testing.T) {
+	runProgramTests(t, []programTest{
+		{
+			name:   "assigning an array copies it",
+			source: "var a = [1]\nvar b = a\nappend(b, 2)\nvar out = [a, b]",
+			want:   "[[1], [1, 2]]",
+		},
+		{
+			name:   "arguments are copies",
+			source: "func grow(array) {\n\tappend(array, 0)\n\treturn len(array)\n}\nvar a = [1]\nvar out = [grow(a), len(a)]",
+			want:   "[2, 1]",
+		},
+	})
+}
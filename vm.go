@@ -0,0 +1,287 @@
+package main
+
+// NewVM creates a new VM instance.
+func NewVM() *VM {
+	return &VM{interpreter: NewInterpreter()}
+}
+
+func (vm *VM) push(value *Value) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() *Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek() *Value {
+	return vm.stack[len(vm.stack)-1]
+}
+
+// popArgs removes the top count values from the stack and returns them in order.
+func (vm *VM) popArgs(count int) []*Value {
+	var args []*Value
+	if count > 0 {
+		args = make([]*Value, count)
+		copy(args, vm.stack[len(vm.stack)-count:])
+		vm.stack = vm.stack[:len(vm.stack)-count]
+	}
+	return args
+}
+
+// controlLoop moves to the loop targeted by a break or continue and returns the instruction to continue at.
+// It returns false if no loop of this chunk is targeted, the result is then passed on like in the tree walker.
+func (vm *VM) controlLoop(result *RTResult) (int, bool) {
+	for idx := len(vm.loops) - 1; idx >= 0; idx-- {
+		loop := vm.loops[idx]
+		if !result.ControlsLoop(loop.label) {
+			continue
+		}
+		vm.loops = vm.loops[:idx+1]
+		vm.stack = vm.stack[:loop.stackDepth]
+		if result.LoopShouldBreak {
+			return loop.breakTo, true
+		}
+		return loop.continueTo, true
+	}
+	return 0, false
+}
+
+// Run executes chunk in context and returns the value of the compiled node.
+func (vm *VM) Run(chunk *Chunk, context *Context) *RTResult {
+	res := NewRTResult()
+	code := chunk.Code
+
+	for pc := 0; pc < len(code); {
+		instruction := &code[pc]
+		pc++
+
+		switch instruction.Op {
+		case OP_NUMBER:
+			node := instruction.Node.(*NumberNode)
+			vm.push(NewNumber(node.Value))
+		case OP_STRING:
+			node := instruction.Node.(*StringNode)
+			text, ok := node.Value.(string)
+			if !ok {
+				return vm.interpreter.visitStringNode(*node, context)
+			}
+			vm.push(NewString(text))
+		case OP_NULL:
+			vm.push(NewNull())
+		case OP_POP:
+			vm.pop()
+		case OP_GET:
+			value, err := lookupVariable(instruction.Node.(*VarAccessNode), context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			vm.push(value)
+		case OP_CHECK_ASSIGN:
+			if err := checkAssignment(instruction.Node.(*VarAssignNode), context); err != nil {
+				return res.Failure(err)
+			}
+		case OP_ASSIGN:
+			if err := assignVariable(instruction.Node.(*VarAssignNode), vm.pop(), context); err != nil {
+				return res.Failure(err)
+			}
+			vm.push(NewEmptyValue())
+		case OP_BINARY:
+			right := vm.pop()
+			left := vm.pop()
+			result, err := binaryOperation(instruction.Node.(*BinOpNode), left, right, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			vm.push(result)
+		case OP_UNARY:
+			result, err := unaryOperation(instruction.Node.(*UnaryOpNode), vm.pop(), context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			vm.push(result)
+		case OP_ARRAY:
+			node := instruction.Node.(*ArrayNode)
+			var elements []*Value
+			for _, value := range vm.popArgs(instruction.Operand) {
+				if !value.IsEmpty() {
+					elements = append(elements, value.Share())
+				}
+			}
+			newArray := NewArray(elements)
+			if node.Frozen {
+				newArray = newArray.Freeze()
+			}
+			vm.push(newArray)
+		case OP_INDEX:
+			index := vm.pop()
+			target := vm.pop()
+			value, err := indexValue(instruction.Node.(*IndexNode), target, index, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			vm.push(value)
+		case OP_FUNCTION:
+			node := instruction.Node.(*FuncDefNode)
+			if node.VarNameTok == nil {
+				vm.push(makeFunction(node, nil, context))
+				break
+			}
+			funcName := node.VarNameTok.Value.(string)
+			setVariable(funcName, node.Binding, makeFunction(node, &funcName, context), context)
+			vm.push(NewEmptyValue())
+		case OP_CALL:
+			node := instruction.Node
+			args := vm.popArgs(instruction.Operand)
+			valueToCall, err := positionCall(vm.pop(), node, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			result := vm.interpreter.callValue(valueToCall, args)
+			if result.ShouldReturn() {
+				if target, ok := vm.controlLoop(result); ok {
+					pc = target
+					break
+				}
+				return result
+			}
+			vm.push(result.Value.Copy().SetPos(node.PosStart(), node.PosEnd()).SetContext(context))
+		case OP_PACKAGE:
+			packageMethod := packageSelector(instruction.Node.(*MethodCallNode), context)
+			if packageMethod == nil {
+				break
+			}
+			result := vm.interpreter.visitPackageMethodNode(*packageMethod, context)
+			if result.ShouldReturn() {
+				return result
+			}
+			vm.push(result.Value)
+			pc = instruction.Operand
+		case OP_METHOD:
+			method, err := lookupMethod(instruction.Node.(*MethodCallNode), vm.peek(), context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			vm.push(method)
+		case OP_CALL_METHOD:
+			node := instruction.Node
+			args := vm.popArgs(instruction.Operand)
+			method := vm.pop()
+			args = append([]*Value{vm.pop()}, args...)
+			valueToCall, err := positionCall(method, node, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			result := vm.interpreter.callValue(valueToCall, args)
+			if result.ShouldReturn() {
+				if target, ok := vm.controlLoop(result); ok {
+					pc = target
+					break
+				}
+				return result
+			}
+			vm.push(result.Value.Copy().SetPos(node.PosStart(), node.PosEnd()).SetContext(context))
+		case OP_JUMP:
+			pc = instruction.Operand
+		case OP_JUMP_IF_FALSE:
+			condition, err := isTrue(vm.pop(), instruction.Node, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			if !condition {
+				pc = instruction.Operand
+			}
+		case OP_JUMP_IF_NULL:
+			if vm.peek().Null() != nil {
+				pc = instruction.Operand
+			}
+		case OP_JUMP_IF_NOT_NULL:
+			if vm.peek().Null() == nil {
+				pc = instruction.Operand
+			}
+		case OP_LOOP:
+			loop := &loopFrame{breakTo: instruction.Operand, continueTo: pc}
+			switch node := instruction.Node.(type) {
+			case *WhileNode:
+				loop.label = labelName(node.LabelTok)
+			case *ForNode:
+				loop.label = labelName(node.LabelTok)
+				var step *Value
+				if node.StepValueNode != nil {
+					step = vm.pop()
+				}
+				end := vm.pop()
+				start := vm.pop()
+				iVal, endValue, stepValue, err := forRange(node, start, end, step, context)
+				if err != nil {
+					return res.Failure(err)
+				}
+				loop.iVal, loop.end, loop.step = iVal, endValue, stepValue
+			}
+			loop.stackDepth = len(vm.stack)
+			vm.loops = append(vm.loops, loop)
+		case OP_FOR_NEXT:
+			loop := vm.loops[len(vm.loops)-1]
+			if (loop.step >= 0 && loop.iVal >= loop.end) || (loop.step < 0 && loop.iVal <= loop.end) {
+				pc = instruction.Operand
+				break
+			}
+			node := instruction.Node.(*ForNode)
+			setVariable(node.VarNameTok.Value.(string), node.VarBinding, NewInt(loop.iVal), context)
+			loop.iVal += loop.step
+		case OP_LOOP_APPEND:
+			loop := vm.loops[len(vm.loops)-1]
+			loop.elements = append(loop.elements, vm.pop())
+		case OP_LOOP_END:
+			loop := vm.loops[len(vm.loops)-1]
+			vm.loops = vm.loops[:len(vm.loops)-1]
+			node := instruction.Node
+			flag := false
+			switch n := node.(type) {
+			case *WhileNode:
+				flag = n.Flag
+			case *ForNode:
+				flag = n.Flag
+			}
+			if flag {
+				vm.push(NewEmptyValue())
+			} else {
+				vm.push(NewArray(loop.elements))
+			}
+		case OP_BREAK, OP_CONTINUE:
+			signal := NewRTResult()
+			if instruction.Op == OP_BREAK {
+				signal.SuccessBreak(labelName(instruction.Node.(*BreakNode).LabelTok))
+			} else {
+				signal.SuccessContinue(labelName(instruction.Node.(*ContinueNode).LabelTok))
+			}
+			target, ok := vm.controlLoop(signal)
+			if !ok {
+				return signal
+			}
+			pc = target
+		case OP_RETURN:
+			return res.SuccessReturn(vm.pop())
+		case OP_TAIL_CALL:
+			args := vm.popArgs(instruction.Operand)
+			valueToCall, err := positionCall(vm.pop(), instruction.Node, context)
+			if err != nil {
+				return res.Failure(err)
+			}
+			return returnCall(valueToCall, args, instruction.Node, context)
+		case OP_NODE:
+			result := vm.interpreter.visit(instruction.Node, context)
+			if result.ShouldReturn() {
+				if target, ok := vm.controlLoop(result); ok {
+					pc = target
+					break
+				}
+				return result
+			}
+			vm.push(result.Value)
+		}
+	}
+
+	return res.Success(vm.pop())
+}